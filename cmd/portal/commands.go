@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/database"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// runCommand executes a command-line subcommand instead of starting the server
+func runCommand(cfg *config.Config, db *sql.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		if err := database.RunMigrations(db); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+		fmt.Println("Migrations applied")
+		return nil
+
+	case "audit":
+		if len(args) < 2 || args[1] != "verify" {
+			return fmt.Errorf("usage: portal audit verify")
+		}
+		return runAuditVerify(cfg, db)
+
+	case "retention":
+		if len(args) < 2 || args[1] != "run" {
+			return fmt.Errorf("usage: portal retention run")
+		}
+		return runRetention(cfg, db)
+
+	case "sync":
+		return runSync(cfg, db, args[1:])
+
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runAuditVerify walks the audit chain and exits non-zero if it is broken
+func runAuditVerify(cfg *config.Config, db *sql.DB) error {
+	auditService := service.NewAuditService(db, cfg.Audit.CheckpointSecret, cfg.Audit.CheckpointInterval)
+
+	result, err := auditService.VerifyChain()
+	if err != nil {
+		return fmt.Errorf("failed to verify audit chain: %w", err)
+	}
+
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+
+	if !result.Valid {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// runRetention applies every retention policy once and prints the summaries
+func runRetention(cfg *config.Config, db *sql.DB) error {
+	retentionService := service.NewRetentionService(db, cfg.Retention)
+
+	runs := retentionService.RunAll()
+
+	out, _ := json.MarshalIndent(runs, "", "  ")
+	fmt.Println(string(out))
+
+	for _, run := range runs {
+		if run.Error != "" {
+			return fmt.Errorf("retention failed for %s: %s", run.Table, run.Error)
+		}
+	}
+
+	return nil
+}
@@ -3,13 +3,24 @@ package main
 import (
 	"fmt"
 	"log"
-	
+	"os"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/buildinfo"
+	"github.com/kanyaarss/kanyaars-portal/internal/cache"
 	"github.com/kanyaarss/kanyaars-portal/internal/config"
 	"github.com/kanyaarss/kanyaars-portal/internal/database"
 	"github.com/kanyaarss/kanyaars-portal/internal/http"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	// "version" needs neither configuration nor database
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		fmt.Println(buildinfo.String())
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -23,19 +34,86 @@ func main() {
 	}
 	defer db.Close()
 
+	// Run a subcommand (e.g. "migrate", "audit verify", "sync") instead of serving
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, db, os.Args[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
 	// Run migrations
 	if err := database.RunMigrations(db); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Redis backs the shared rate limit and cache stores
+	var redisClient *redis.Client
+	if cfg.Redis.Enabled {
+		redisClient, err = database.NewRedis(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+	}
+
+	// Cache for portal config, active projects and rendered pages
+	var loader *cache.Loader
+	if cfg.Cache.Enabled {
+		loader = cache.NewLoader(newCache(cfg, redisClient), cfg.Cache.TTL)
+		loader.Listen(database.DSN(cfg.Database))
+	}
+
+	// Services are shared by the HTTP server and the background workers
+	services := service.NewServices(cfg, db, loader)
+
+	// Start background retention jobs
+	if cfg.Retention.Enabled {
+		go services.Retention.Start(make(chan struct{}))
+	}
+
+	// Start the webhook delivery worker
+	if cfg.Webhooks.Enabled {
+		go services.Webhooks.Start(make(chan struct{}))
+	}
+
+	// Start the uptime prober
+	if cfg.Uptime.Enabled {
+		go services.Uptime.Start(make(chan struct{}))
+	}
+
+	// Start the maintenance window scheduler, which always runs so that
+	// project status follows the windows
+	go services.Maintenance.Start(make(chan struct{}))
+
+	// Start deleting expired idempotency keys
+	go services.Idempotency.Start(make(chan struct{}))
+
 	// Setup HTTP server
-	router := http.NewRouter(cfg, db)
+	router := http.NewRouter(cfg, db, redisClient, services)
 
 	// Start server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	log.Printf("Starting server on %s", addr)
+	log.Printf("Starting portal %s on %s", buildinfo.String(), addr)
 
 	if err := router.Run(addr); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// newCache returns the configured cache store
+func newCache(cfg *config.Config, redisClient *redis.Client) cache.Cache {
+	switch cfg.Cache.Store {
+	case cache.StoreMemory:
+		return cache.NewLRU(cfg.Cache.Size)
+
+	case cache.StoreRedis:
+		if redisClient == nil {
+			log.Fatalf("Cache store %q requires redis.enabled", cfg.Cache.Store)
+		}
+		return cache.NewRedis(redisClient)
+
+	default:
+		log.Fatalf("Unknown cache store %q", cfg.Cache.Store)
+		return nil
+	}
+}
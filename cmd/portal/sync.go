@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/projectfile"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// syncActor identifies sync changes in the audit log
+var syncActor = domain.Actor{UserAgent: "portal sync"}
+
+// runSync brings the projects table in line with a manifest file, printing
+// the plan first. With -watch it keeps polling the file and syncs again
+// whenever its contents change and two polls in a row agree on them, so
+// that a file caught halfway through being rewritten is never synced.
+func runSync(cfg *config.Config, db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	file := flags.String("f", "projects.yaml", "manifest file (YAML, JSON or CSV by extension)")
+	dryRun := flags.Bool("dry-run", false, "print the plan without applying it")
+	watch := flags.Bool("watch", false, "keep syncing whenever the file changes")
+	interval := flags.Duration("interval", 5*time.Second, "how often -watch checks the file")
+	allowEmpty := flags.Bool("allow-empty", false, "accept a manifest without projects, deleting every managed project")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("usage: portal sync -f projects.yaml [-dry-run] [-watch] [-interval 5s] [-allow-empty]")
+	}
+
+	auditService := service.NewAuditService(db, cfg.Audit.CheckpointSecret, cfg.Audit.CheckpointInterval)
+	webhookService := service.NewWebhookService(db, auditService, cfg.Webhooks)
+	projectService := service.NewProjectService(db, auditService, webhookService, nil, cfg.Sync)
+
+	if !*watch {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		return syncManifest(projectService, *file, data, *dryRun, *allowEmpty)
+	}
+
+	log.Printf("Watching %s every %s", *file, *interval)
+
+	var last, pending [sha256.Size]byte
+	for ; ; time.Sleep(*interval) {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			log.Printf("Failed to read %s: %v", *file, err)
+			continue
+		}
+
+		// Editors and shell redirections truncate the file before writing it
+		if len(data) == 0 {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if sum == last {
+			continue
+		}
+
+		// Wait for the next poll to see the same contents
+		if sum != pending {
+			pending = sum
+			continue
+		}
+
+		// A failed sync is retried only once the file changes again
+		last = sum
+		if err := syncManifest(projectService, *file, data, *dryRun, *allowEmpty); err != nil {
+			log.Printf("Sync failed: %v", err)
+		}
+	}
+}
+
+// syncManifest plans or applies the manifest in data and prints the plan
+func syncManifest(projectService *service.ProjectService, file string, data []byte, dryRun, allowEmpty bool) error {
+	rows, err := projectfile.Read(bytes.NewReader(data), manifestFormat(file))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	var plan *domain.ProjectSyncPlan
+	if dryRun {
+		plan, err = projectService.PlanSync(rows, allowEmpty)
+	} else {
+		plan, err = projectService.ApplySync(syncActor, rows, allowEmpty)
+	}
+
+	var manifestErr *service.ManifestError
+	if errors.As(err, &manifestErr) {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if errors.Is(err, service.ErrSyncEmptyManifest) {
+		return fmt.Errorf("%s: %w; pass -allow-empty to delete every managed project", file, err)
+	}
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+
+	printPlan(plan)
+	return nil
+}
+
+// manifestFormat picks the file format from its extension
+func manifestFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return projectfile.FormatJSON
+	case ".csv":
+		return projectfile.FormatCSV
+	}
+	return projectfile.FormatYAML
+}
+
+// printPlan writes the plan as a diff, one line per project prefixed with
+// + (create), ~ (update), ^ (reorder) or - (delete), followed by the
+// changed fields and a summary line
+func printPlan(plan *domain.ProjectSyncPlan) {
+	symbols := map[string]string{
+		domain.SyncActionCreate:  "+",
+		domain.SyncActionUpdate:  "~",
+		domain.SyncActionReorder: "^",
+		domain.SyncActionDelete:  "-",
+	}
+
+	for _, change := range plan.Changes {
+		fmt.Printf("%s %s\n", symbols[change.Action], change.Slug)
+
+		fields := make([]string, 0, len(change.Changes))
+		for field := range change.Changes {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			c := change.Changes[field]
+			if change.Action == domain.SyncActionCreate {
+				fmt.Printf("    %s: %s\n", field, planValue(c.To))
+			} else {
+				fmt.Printf("    %s: %s -> %s\n", field, planValue(c.From), planValue(c.To))
+			}
+		}
+	}
+
+	verb := "Plan"
+	if plan.Applied {
+		verb = "Applied"
+	}
+	fmt.Printf("%s: %d create, %d update, %d reorder, %d delete, %d unchanged\n",
+		verb,
+		plan.Count(domain.SyncActionCreate),
+		plan.Count(domain.SyncActionUpdate),
+		plan.Count(domain.SyncActionReorder),
+		plan.Count(domain.SyncActionDelete),
+		plan.Unchanged,
+	)
+}
+
+// planValue formats a field value of the plan as JSON
+func planValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -4,22 +4,31 @@ go 1.21
 
 require (
     github.com/gin-gonic/gin v1.9.1
+    github.com/graphql-go/graphql v0.8.1
     github.com/lib/pq v1.10.9
+    github.com/redis/go-redis/v9 v9.0.5
     github.com/golang-jwt/jwt/v5 v5.0.0
     github.com/joho/godotenv v1.5.1
     gopkg.in/yaml.v3 v3.0.1
     golang.org/x/crypto v0.14.0
+    golang.org/x/text v0.13.0
+    google.golang.org/genproto/googleapis/rpc v0.0.0-20230822172742-b8732ec3820d
+    google.golang.org/grpc v1.59.0
+    google.golang.org/protobuf v1.31.0
+    github.com/go-playground/validator/v10 v10.14.0
 )
 
 require (
     github.com/bytedance/sonic v1.14.1 // indirect
+    github.com/cespare/xxhash/v2 v2.2.0 // indirect
     github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
+    github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
     github.com/gabriel-vasile/mimetype v1.4.2 // indirect
     github.com/gin-contrib/sse v1.1.0 // indirect
     github.com/go-playground/locales v0.14.1 // indirect
     github.com/go-playground/universal-translator v0.18.1 // indirect
-    github.com/go-playground/validator/v10 v10.14.0 // indirect
     github.com/goccy/go-json v0.10.2 // indirect
+    github.com/golang/protobuf v1.5.3 // indirect
     github.com/json-iterator/go v1.1.12 // indirect
     github.com/klauspost/cpuid/v2 v2.2.4 // indirect
     github.com/leodido/go-urn v1.2.4 // indirect
@@ -30,9 +39,7 @@ require (
     github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
     github.com/ugorji/go/codec v1.2.11 // indirect
     golang.org/x/arch v0.4.0 // indirect
-    golang.org/x/net v0.10.0 // indirect
-    golang.org/x/sys v0.12.0 // indirect
-    golang.org/x/text v0.13.0 // indirect
-    google.golang.org/protobuf v1.30.0 // indirect
+    golang.org/x/net v0.17.0 // indirect
+    golang.org/x/sys v0.13.0 // indirect
     gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -0,0 +1,65 @@
+// Package buildinfo holds the version details stamped into the binary at
+// build time:
+//
+//	go build -ldflags "-X github.com/kanyaarss/kanyaars-portal/internal/buildinfo.Version=v1.2.0 \
+//	  -X github.com/kanyaarss/kanyaars-portal/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/kanyaarss/kanyaars-portal/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/portal
+package buildinfo
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Build details, set with -ldflags "-X"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// started is when the process started serving
+var started = time.Now()
+
+func init() {
+	// Builds from a git checkout carry the commit even without ldflags
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if BuildTime == "" {
+				BuildTime = setting.Value
+			}
+		}
+	}
+}
+
+// Uptime returns how long the process has been running
+func Uptime() time.Duration {
+	return time.Since(started)
+}
+
+// String describes the build in one line, e.g. "v1.2.0 (3f2a9c1, 2024-05-01T10:00:00Z)"
+func String() string {
+	s := Version
+	if Commit != "" {
+		commit := Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		s += " (" + commit
+		if BuildTime != "" {
+			s += ", " + BuildTime
+		}
+		s += ")"
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+// Package cache caches query results and rendered pages in process memory
+// or Redis. Writers invalidate keys through a Postgres NOTIFY channel so
+// that every replica drops them once the write commits.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Stores the cache can be kept in
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Keys of cached values
+const (
+	KeyPortal         = "portal"
+	KeyActiveProjects = "projects:active"
+	KeyProjectsPage   = "page:projects"
+)
+
+// ProjectPageKey returns the key of the rendered detail page of slug
+func ProjectPageKey(slug string) string {
+	return "page:project:" + slug
+}
+
+// Cache stores byte values by key. Implementations treat backend failures
+// as misses, since the database remains the source of truth.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+	// Purge drops every entry
+	Purge(ctx context.Context)
+}
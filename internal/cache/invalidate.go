@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// InvalidationChannel is the Postgres NOTIFY channel carrying the
+// space-separated keys to drop
+const InvalidationChannel = "cache_invalidations"
+
+// Invalidate drops keys from the local cache right away and notifies every
+// replica, including processes without a cache such as the CLI, to drop
+// them once tx commits. It works on a nil Loader.
+func (l *Loader) Invalidate(tx *sql.Tx, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	l.forget(keys...)
+
+	if _, err := tx.Exec("SELECT pg_notify($1, $2)", InvalidationChannel, strings.Join(keys, " ")); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// Listen drops the keys notified on InvalidationChannel from l until the
+// process exits. Everything is dropped after a reconnect, as notifications
+// may have been missed meanwhile.
+func (l *Loader) Listen(dsn string) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Cache listener: %v", err)
+		}
+	})
+
+	if err := listener.Listen(InvalidationChannel); err != nil {
+		log.Printf("Failed to listen on %s: %v", InvalidationChannel, err)
+	}
+
+	go func() {
+		ping := time.NewTicker(90 * time.Second)
+		defer ping.Stop()
+
+		for {
+			select {
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				if n == nil {
+					l.purge()
+					continue
+				}
+				l.forget(strings.Fields(n.Extra)...)
+
+			case <-ping.C:
+				go listener.Ping()
+			}
+		}
+	}()
+}
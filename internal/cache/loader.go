@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Loader reads JSON values through a Cache. Concurrent misses for one key
+// share a single load. A nil Loader caches nothing.
+type Loader struct {
+	cache Cache
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call is a load in flight
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+	// stale is set when the key is invalidated during the load, whose
+	// result is then not stored
+	stale bool
+}
+
+// NewLoader creates a loader keeping values in c for ttl
+func NewLoader(c Cache, ttl time.Duration) *Loader {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &Loader{cache: c, ttl: ttl, calls: map[string]*call{}}
+}
+
+// Fetch fills dst with the cached value of key. On a miss, load fills dst
+// from the source and the result is stored; callers missing the same key
+// meanwhile wait for it instead of loading again. Errors are not cached.
+func (l *Loader) Fetch(key string, dst interface{}, load func() error) error {
+	if l == nil {
+		return load()
+	}
+
+	ctx := context.Background()
+	if value, ok := l.cache.Get(ctx, key); ok && json.Unmarshal(value, dst) == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if c, ok := l.calls[key]; ok {
+		l.mu.Unlock()
+		<-c.done
+		if c.err != nil {
+			return c.err
+		}
+		return json.Unmarshal(c.value, dst)
+	}
+
+	c := &call{done: make(chan struct{})}
+	l.calls[key] = c
+	l.mu.Unlock()
+
+	c.err = load()
+	if c.err == nil {
+		c.value, c.err = json.Marshal(dst)
+	}
+
+	l.mu.Lock()
+	delete(l.calls, key)
+	if c.err == nil && !c.stale {
+		l.cache.Set(ctx, key, c.value, l.ttl)
+	}
+	l.mu.Unlock()
+	close(c.done)
+
+	return c.err
+}
+
+// forget drops keys from the cache and keeps loads in flight from storing
+// what they read before the invalidation
+func (l *Loader) forget(keys ...string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	for _, key := range keys {
+		if c, ok := l.calls[key]; ok {
+			c.stale = true
+		}
+	}
+	l.cache.Delete(context.Background(), keys...)
+	l.mu.Unlock()
+}
+
+// purge drops every cached value
+func (l *Loader) purge() {
+	l.mu.Lock()
+	for _, c := range l.calls {
+		c.stale = true
+	}
+	l.cache.Purge(context.Background())
+	l.mu.Unlock()
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoaderCoalescesMisses(t *testing.T) {
+	l := NewLoader(NewLRU(10), time.Minute)
+
+	var loads int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	load := func(dst *string) func() error {
+		return func() error {
+			if atomic.AddInt32(&loads, 1) == 1 {
+				close(started)
+			}
+			<-release
+			*dst = "value"
+			return nil
+		}
+	}
+
+	const callers = 10
+	results := make([]string, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	fetch := func(i int) {
+		defer wg.Done()
+		errs[i] = l.Fetch("key", &results[i], load(&results[i]))
+	}
+
+	wg.Add(callers)
+	go fetch(0)
+	<-started
+	for i := 1; i < callers; i++ {
+		go fetch(i)
+	}
+	time.Sleep(20 * time.Millisecond) // let the others wait for the load
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+	for i := range results {
+		if errs[i] != nil || results[i] != "value" {
+			t.Errorf("caller %d = %q, %v", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestLoaderFetch(t *testing.T) {
+	errLoad := errors.New("source down")
+
+	tests := []struct {
+		name string
+		// between runs after the first fetch
+		between func(l *Loader)
+		// during runs inside the first load
+		during    func(l *Loader)
+		firstErr  error
+		wantLoads int
+	}{
+		{name: "second fetch hits the cache", wantLoads: 1},
+		{name: "errors are not cached", firstErr: errLoad, wantLoads: 2},
+		{name: "forgotten key is loaded again", between: func(l *Loader) { l.forget("key") }, wantLoads: 2},
+		{name: "purge drops every key", between: func(l *Loader) { l.purge() }, wantLoads: 2},
+		{name: "invalidation during a load is not stored", during: func(l *Loader) { l.forget("key") }, wantLoads: 2},
+		{name: "purge during a load is not stored", during: func(l *Loader) { l.purge() }, wantLoads: 2},
+		{name: "other keys do not affect the load", during: func(l *Loader) { l.forget("other") }, wantLoads: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader(NewLRU(10), time.Minute)
+
+			loads := 0
+			load := func(dst *int) func() error {
+				return func() error {
+					loads++
+					if loads == 1 {
+						if tt.during != nil {
+							tt.during(l)
+						}
+						if tt.firstErr != nil {
+							return tt.firstErr
+						}
+					}
+					*dst = 42
+					return nil
+				}
+			}
+
+			var first int
+			if err := l.Fetch("key", &first, load(&first)); err != tt.firstErr {
+				t.Fatalf("first Fetch error = %v, want %v", err, tt.firstErr)
+			}
+			if tt.between != nil {
+				tt.between(l)
+			}
+
+			var second int
+			if err := l.Fetch("key", &second, load(&second)); err != nil {
+				t.Fatal(err)
+			}
+			if second != 42 {
+				t.Errorf("second Fetch = %d, want 42", second)
+			}
+			if loads != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestNilLoaderAlwaysLoads(t *testing.T) {
+	var l *Loader
+
+	loads := 0
+	for i := 0; i < 2; i++ {
+		var v int
+		if err := l.Fetch("key", &v, func() error { loads++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces cache entries in Redis
+const keyPrefix = "cache:"
+
+// Redis keeps entries in Redis, shared by every replica
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a cache on client
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// Get implements Cache
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Cache get %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set implements Cache
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if err := c.client.Set(ctx, keyPrefix+key, value, ttl).Err(); err != nil {
+		log.Printf("Cache set %s: %v", key, err)
+	}
+}
+
+// Delete implements Cache
+func (c *Redis) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = keyPrefix + key
+	}
+
+	if err := c.client.Del(ctx, prefixed...).Err(); err != nil {
+		log.Printf("Cache delete %v: %v", keys, err)
+	}
+}
+
+// Purge implements Cache
+func (c *Redis) Purge(ctx context.Context) {
+	iter := c.client.Scan(ctx, 0, keyPrefix+"*", 100).Iterator()
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		log.Printf("Cache purge: %v", err)
+		return
+	}
+
+	if len(keys) > 0 {
+		if err := c.client.Del(ctx, keys...).Err(); err != nil {
+			log.Printf("Cache purge: %v", err)
+		}
+	}
+}
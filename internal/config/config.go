@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -10,13 +11,29 @@ import (
 )
 
 type Config struct {
-	App      AppConfig      `yaml:"app"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Server   ServerConfig   `yaml:"server"`
-	CORS     CORSConfig     `yaml:"cors"`
-	Redis    RedisConfig    `yaml:"redis"`
-	Logging  LoggingConfig  `yaml:"logging"`
+	App         AppConfig         `yaml:"app"`
+	Database    DatabaseConfig    `yaml:"database"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Server      ServerConfig      `yaml:"server"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Redis       RedisConfig       `yaml:"redis"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	Audit       AuditConfig       `yaml:"audit"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Search      SearchConfig      `yaml:"search"`
+	GraphQL     GraphQLConfig     `yaml:"graphql"`
+	HTTPCache   HTTPCacheConfig   `yaml:"http_cache"`
+	Webhooks    WebhookConfig     `yaml:"webhooks"`
+	Events      EventsConfig      `yaml:"events"`
+	Sync        SyncConfig        `yaml:"sync"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Health      HealthConfig      `yaml:"health"`
+	Uptime      UptimeConfig      `yaml:"uptime"`
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	Deployments DeploymentsConfig `yaml:"deployments"`
 }
 
 type AppConfig struct {
@@ -49,6 +66,9 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// Proxies whose X-Forwarded-For is trusted for the client IP; all
+	// when empty
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type CORSConfig struct {
@@ -71,6 +91,125 @@ type LoggingConfig struct {
 	Output string `yaml:"output"`
 }
 
+type AuditConfig struct {
+	CheckpointSecret   string `yaml:"checkpoint_secret"`
+	CheckpointInterval int    `yaml:"checkpoint_interval"`
+}
+
+type RetentionConfig struct {
+	Enabled    bool              `yaml:"enabled"`
+	Interval   time.Duration     `yaml:"interval"`
+	BatchSize  int               `yaml:"batch_size"`
+	ArchiveDir string            `yaml:"archive_dir"`
+	Policies   []RetentionPolicy `yaml:"policies"`
+}
+
+type RetentionPolicy struct {
+	Table   string        `yaml:"table"`
+	Column  string        `yaml:"column"`
+	MaxAge  time.Duration `yaml:"max_age"`
+	Archive bool          `yaml:"archive"`
+}
+
+type SearchConfig struct {
+	Backend string `yaml:"backend"` // postgres, basic
+}
+
+type HTTPCacheConfig struct {
+	API   CachePolicy `yaml:"api"`   // /api/v1
+	Pages CachePolicy `yaml:"pages"` // server-rendered pages
+}
+
+type CachePolicy struct {
+	MaxAge               time.Duration `yaml:"max_age"`
+	StaleWhileRevalidate time.Duration `yaml:"stale_while_revalidate"`
+	Private              bool          `yaml:"private"`
+	NoStore              bool          `yaml:"no_store"`
+}
+
+type WebhookConfig struct {
+	Enabled      bool          `yaml:"enabled"` // run the delivery worker
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Timeout      time.Duration `yaml:"timeout"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	BackoffBase  time.Duration `yaml:"backoff_base"`
+	BackoffMax   time.Duration `yaml:"backoff_max"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+type EventsConfig struct {
+	BufferSize int           `yaml:"buffer_size"` // events kept for Last-Event-ID resume
+	Heartbeat  time.Duration `yaml:"heartbeat"`
+}
+
+type SyncConfig struct {
+	ManagedPolicy string `yaml:"managed_policy"` // warn or block admin edits of managed projects
+}
+
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl"`            // how long keys are remembered
+	SweepInterval time.Duration `yaml:"sweep_interval"` // how often expired keys are deleted
+}
+
+type UptimeConfig struct {
+	Enabled      bool          `yaml:"enabled"`       // run the prober
+	PollInterval time.Duration `yaml:"poll_interval"` // how often due checks are looked for
+	Concurrency  int           `yaml:"concurrency"`   // checks run at once per replica
+	Interval     time.Duration `yaml:"interval"`      // default time between checks of a project
+	Timeout      time.Duration `yaml:"timeout"`       // default time a project has to respond
+}
+
+type MaintenanceConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // how often windows are started and ended
+}
+
+type DeploymentsConfig struct {
+	Secret    string        `yaml:"secret"`    // HMAC key CI signs events with; empty disables the endpoint
+	Tolerance time.Duration `yaml:"tolerance"` // maximum age of a signed event
+}
+
+type HealthConfig struct {
+	Timeout time.Duration `yaml:"timeout"` // per dependency check of /readyz
+}
+
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Store   string        `yaml:"store"` // memory or redis
+	Size    int           `yaml:"size"`  // entries kept by the memory store
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool            `yaml:"enabled"`
+	Store   string          `yaml:"store"` // memory or redis
+	API     RateLimitPolicy `yaml:"api"`   // /api/v1
+	Login   RateLimitPolicy `yaml:"login"` // POST /api/v1/auth/login, on top of api
+	GraphQL RateLimitPolicy `yaml:"graphql"`
+	Admin   RateLimitPolicy `yaml:"admin"`
+	// APIKeys are the hex SHA-256 digests of the X-API-Key values issued to
+	// clients; policies by api_key count any other key by IP
+	APIKeys []string `yaml:"api_keys"`
+}
+
+// RateLimitPolicy is a token bucket of Burst tokens (default Requests)
+// refilled with Requests tokens every Period; Requests 0 disables it
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+	By       string        `yaml:"by"` // ip, user or api_key
+}
+
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"` // 0 shares the HTTP port over h2c
+}
+
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth"`
+	MaxComplexity int `yaml:"max_complexity"`
+}
+
 // Load loads configuration from config.yaml and environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists
@@ -93,6 +232,39 @@ func Load() (*Config, error) {
 	// Override with environment variables
 	cfg.overrideFromEnv()
 
+	// Sign audit checkpoints with the JWT secret unless a dedicated key is set
+	if cfg.Audit.CheckpointSecret == "" {
+		cfg.Audit.CheckpointSecret = cfg.JWT.Secret
+	}
+
+	// Let shared caches hold public responses briefly unless configured
+	if cfg.HTTPCache.API == (CachePolicy{}) {
+		cfg.HTTPCache.API = CachePolicy{MaxAge: 10 * time.Second, StaleWhileRevalidate: 60 * time.Second}
+	}
+	if cfg.HTTPCache.Pages == (CachePolicy{}) {
+		cfg.HTTPCache.Pages = CachePolicy{MaxAge: 60 * time.Second, StaleWhileRevalidate: 5 * time.Minute}
+	}
+
+	// Throttle by client IP, and admins by user, unless configured
+	if cfg.RateLimit.Store == "" {
+		cfg.RateLimit.Store = "memory"
+	}
+	if cfg.Cache.Store == "" {
+		cfg.Cache.Store = "memory"
+	}
+	if cfg.RateLimit.API == (RateLimitPolicy{}) {
+		cfg.RateLimit.API = RateLimitPolicy{Requests: 120, Period: time.Minute, Burst: 30, By: "ip"}
+	}
+	if cfg.RateLimit.Login == (RateLimitPolicy{}) {
+		cfg.RateLimit.Login = RateLimitPolicy{Requests: 10, Period: time.Minute, By: "ip"}
+	}
+	if cfg.RateLimit.GraphQL == (RateLimitPolicy{}) {
+		cfg.RateLimit.GraphQL = RateLimitPolicy{Requests: 60, Period: time.Minute, Burst: 20, By: "ip"}
+	}
+	if cfg.RateLimit.Admin == (RateLimitPolicy{}) {
+		cfg.RateLimit.Admin = RateLimitPolicy{Requests: 600, Period: time.Minute, Burst: 100, By: "user"}
+	}
+
 	return cfg, nil
 }
 
@@ -143,6 +315,78 @@ func (c *Config) overrideFromEnv() {
 	if env := os.Getenv("SERVER_PORT"); env != "" {
 		fmt.Sscanf(env, "%d", &c.Server.Port)
 	}
+
+	if env := os.Getenv("AUDIT_CHECKPOINT_SECRET"); env != "" {
+		c.Audit.CheckpointSecret = env
+	}
+	if env := os.Getenv("AUDIT_CHECKPOINT_INTERVAL"); env != "" {
+		fmt.Sscanf(env, "%d", &c.Audit.CheckpointInterval)
+	}
+
+	if env := os.Getenv("RATE_LIMIT_API_KEYS"); env != "" {
+		c.RateLimit.APIKeys = strings.Split(env, ",")
+	}
+
+	if env := os.Getenv("SEARCH_BACKEND"); env != "" {
+		c.Search.Backend = env
+	}
+
+	if env := os.Getenv("GRAPHQL_MAX_DEPTH"); env != "" {
+		fmt.Sscanf(env, "%d", &c.GraphQL.MaxDepth)
+	}
+	if env := os.Getenv("GRAPHQL_MAX_COMPLEXITY"); env != "" {
+		fmt.Sscanf(env, "%d", &c.GraphQL.MaxComplexity)
+	}
+
+	if env := os.Getenv("GRPC_ENABLED"); env != "" {
+		c.GRPC.Enabled = env == "true"
+	}
+	if env := os.Getenv("GRPC_PORT"); env != "" {
+		fmt.Sscanf(env, "%d", &c.GRPC.Port)
+	}
+
+	if env := os.Getenv("RATE_LIMIT_ENABLED"); env != "" {
+		c.RateLimit.Enabled = env == "true"
+	}
+	if env := os.Getenv("RATE_LIMIT_STORE"); env != "" {
+		c.RateLimit.Store = env
+	}
+
+	if env := os.Getenv("CACHE_ENABLED"); env != "" {
+		c.Cache.Enabled = env == "true"
+	}
+	if env := os.Getenv("CACHE_STORE"); env != "" {
+		c.Cache.Store = env
+	}
+
+	if env := os.Getenv("REDIS_HOST"); env != "" {
+		c.Redis.Host = env
+	}
+	if env := os.Getenv("REDIS_PORT"); env != "" {
+		fmt.Sscanf(env, "%d", &c.Redis.Port)
+	}
+	if env := os.Getenv("REDIS_PASSWORD"); env != "" {
+		c.Redis.Password = env
+	}
+
+	if env := os.Getenv("WEBHOOKS_ENABLED"); env != "" {
+		c.Webhooks.Enabled = env == "true"
+	}
+
+	if env := os.Getenv("UPTIME_ENABLED"); env != "" {
+		c.Uptime.Enabled = env == "true"
+	}
+
+	if env := os.Getenv("DEPLOYMENTS_SECRET"); env != "" {
+		c.Deployments.Secret = env
+	}
+
+	if env := os.Getenv("RETENTION_ENABLED"); env != "" {
+		c.Retention.Enabled = env == "true"
+	}
+	if env := os.Getenv("RETENTION_ARCHIVE_DIR"); env != "" {
+		c.Retention.ArchiveDir = env
+	}
 }
 
 // IsDevelopment returns true if the app is in development mode
@@ -1,28 +1,107 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/health"
+	"github.com/lib/pq"
 )
 
-// RunMigrations runs all database migrations
-func RunMigrations(db *sql.DB) error {
-	migrations := []string{
-		createUsersTable,
-		createProjectsTable,
-		createPortalConfigTable,
-		createAuditLogsTable,
-	}
+// migrations are applied in order on every start, so each must be
+// idempotent. The schema version is the number of migrations applied.
+var migrations = []string{
+	createUsersTable,
+	createProjectsTable,
+	createPortalConfigTable,
+	createAuditLogsTable,
+	addAuditLogsHashChain,
+	createRetentionRunsTable,
+	addProjectsTags,
+	addProjectsSearchVector,
+	createProjectSlugHistoryTable,
+	createWebhookTables,
+	addProjectsManaged,
+	createIdempotencyKeysTable,
+	createUptimeTables,
+	createIncidentTables,
+	createMaintenanceTables,
+	createDeploymentsTable,
+	createProjectChangesTable,
+	addWebhookEventsPosition,
+	addIdempotencyKeysHeartbeat,
+	convertMaintenanceWindowsTimestamps,
+	convertDeploymentsTimestamps,
+}
 
+// SchemaVersion is the schema version this build migrates to
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// RunMigrations runs all database migrations and records the schema version
+func RunMigrations(db *sql.DB) error {
 	for i, migration := range migrations {
 		if _, err := db.Exec(migration); err != nil {
 			return fmt.Errorf("migration %d failed: %w", i+1, err)
 		}
 	}
 
+	if _, err := db.Exec(createSchemaVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	// A newer build may already have migrated further; never go back
+	_, err := db.Exec(
+		"INSERT INTO schema_version (version) VALUES ($1) ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version), applied_at = CURRENT_TIMESTAMP",
+		SchemaVersion(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
 	return nil
 }
 
+// CheckSchema compares the recorded schema version with SchemaVersion. An
+// older or missing schema is an error; a newer one, left by a newer build,
+// only degrades this one.
+func CheckSchema(ctx context.Context, db *sql.DB) error {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT version FROM schema_version").Scan(&version)
+	if err == sql.ErrNoRows || isUndefinedTable(err) {
+		return fmt.Errorf("migrations have not been applied")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version < SchemaVersion():
+		return fmt.Errorf("schema version %d is behind this build (%d)", version, SchemaVersion())
+	case version > SchemaVersion():
+		return health.Degraded(fmt.Errorf("schema version %d is ahead of this build (%d)", version, SchemaVersion()))
+	}
+
+	return nil
+}
+
+// isUndefinedTable reports whether err is Postgres' undefined_table error
+func isUndefinedTable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42P01"
+}
+
+const createSchemaVersionTable = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+	version INTEGER NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
 const createUsersTable = `
 CREATE TABLE IF NOT EXISTS users (
 	id SERIAL PRIMARY KEY,
@@ -87,4 +166,342 @@ CREATE TABLE IF NOT EXISTS audit_logs (
 
 CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
 CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource ON audit_logs(resource, resource_id);
+`
+
+const addAuditLogsHashChain = `
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+	id SERIAL PRIMARY KEY,
+	last_log_id INTEGER NOT NULL,
+	last_hash VARCHAR(64) NOT NULL,
+	signature VARCHAR(64) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_last_log_id ON audit_checkpoints(last_log_id);
+`
+
+const createRetentionRunsTable = `
+CREATE TABLE IF NOT EXISTS retention_runs (
+	id SERIAL PRIMARY KEY,
+	table_name VARCHAR(255) NOT NULL,
+	cutoff TIMESTAMP NOT NULL,
+	rows_deleted INTEGER DEFAULT 0,
+	rows_archived INTEGER DEFAULT 0,
+	archive_file TEXT,
+	error TEXT,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_started_at ON retention_runs(started_at);
+`
+
+const addProjectsTags = `
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS tags TEXT[] DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_projects_tags ON projects USING GIN(tags);
+`
+
+const addProjectsSearchVector = `
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION projects_search_vector_update() RETURNS trigger AS $$
+BEGIN
+	NEW.search_vector :=
+		setweight(to_tsvector('simple', COALESCE(NEW.name, '')), 'A') ||
+		setweight(to_tsvector('simple', array_to_string(COALESCE(NEW.tags, '{}'), ' ')), 'B') ||
+		setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'C');
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS projects_search_vector_trigger ON projects;
+CREATE TRIGGER projects_search_vector_trigger
+	BEFORE INSERT OR UPDATE OF name, description, tags ON projects
+	FOR EACH ROW EXECUTE FUNCTION projects_search_vector_update();
+
+UPDATE projects SET name = name WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_projects_search_vector ON projects USING GIN(search_vector);
+`
+
+const createProjectSlugHistoryTable = `
+CREATE TABLE IF NOT EXISTS project_slug_history (
+	id SERIAL PRIMARY KEY,
+	project_id INTEGER NOT NULL,
+	slug VARCHAR(255) UNIQUE NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_slug_history_project_id ON project_slug_history(project_id);
+`
+
+const createWebhookTables = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id SERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	description VARCHAR(255),
+	events TEXT[] NOT NULL DEFAULT '{}',
+	secret VARCHAR(255) NOT NULL,
+	active BOOLEAN DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+	id SERIAL PRIMARY KEY,
+	type VARCHAR(100) NOT NULL,
+	payload TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id SERIAL PRIMARY KEY,
+	webhook_id INTEGER NOT NULL,
+	event_id INTEGER NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER DEFAULT 0,
+	response_status INTEGER,
+	response_body TEXT,
+	error TEXT,
+	next_attempt_at TIMESTAMP,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+	FOREIGN KEY (event_id) REFERENCES webhook_events(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_created_at ON webhook_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+`
+
+const addProjectsManaged = `
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS managed BOOLEAN NOT NULL DEFAULT FALSE;
+`
+
+const createIdempotencyKeysTable = `
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id INTEGER NOT NULL,
+	key VARCHAR(255) NOT NULL,
+	request_hash CHAR(64) NOT NULL,
+	status INTEGER,
+	content_type VARCHAR(255),
+	response_body BYTEA,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+`
+
+const createUptimeTables = `
+CREATE TABLE IF NOT EXISTS project_monitors (
+	project_id INTEGER PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+	enabled BOOLEAN NOT NULL DEFAULT true,
+	method VARCHAR(10) NOT NULL DEFAULT 'GET',
+	expected_status INTEGER NOT NULL DEFAULT 0,
+	timeout_seconds INTEGER,
+	interval_seconds INTEGER,
+	next_check_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_monitors_next_check_at ON project_monitors(next_check_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS project_checks (
+	id BIGSERIAL PRIMARY KEY,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	up BOOLEAN NOT NULL,
+	status_code INTEGER,
+	latency_ms INTEGER NOT NULL,
+	error TEXT,
+	checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_checks_project_checked_at ON project_checks(project_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_project_checks_checked_at ON project_checks(checked_at);
+`
+
+const createIncidentTables = `
+CREATE TABLE IF NOT EXISTS incidents (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	severity VARCHAR(20) NOT NULL,
+	state VARCHAR(20) NOT NULL,
+	started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	resolved_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_started_at ON incidents(started_at DESC);
+
+CREATE TABLE IF NOT EXISTS incident_projects (
+	incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	PRIMARY KEY (incident_id, project_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_projects_project_id ON incident_projects(project_id);
+
+CREATE TABLE IF NOT EXISTS incident_updates (
+	id SERIAL PRIMARY KEY,
+	incident_id INTEGER NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+	state VARCHAR(20) NOT NULL,
+	message TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_updates_incident_id ON incident_updates(incident_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_incident_updates_created_at ON incident_updates(created_at DESC);
+`
+
+const createMaintenanceTables = `
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+	id SERIAL PRIMARY KEY,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	starts_at TIMESTAMPTZ NOT NULL,
+	ends_at TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+	message TEXT NOT NULL,
+	previous_status VARCHAR(20),
+	started_at TIMESTAMPTZ,
+	ended_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_project_id ON maintenance_windows(project_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_pending ON maintenance_windows(starts_at, ends_at) WHERE ended_at IS NULL;
+`
+
+const createDeploymentsTable = `
+CREATE TABLE IF NOT EXISTS deployments (
+	id SERIAL PRIMARY KEY,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	version VARCHAR(100) NOT NULL,
+	commit_sha VARCHAR(64),
+	environment VARCHAR(50) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	run_id VARCHAR(255),
+	url VARCHAR(500),
+	finished_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_project_id ON deployments(project_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deployments_run_id ON deployments(project_id, run_id) WHERE run_id IS NOT NULL;
+`
+
+// createProjectChangesTable keeps a single version row that every write to
+// projects bumps, including deletes, so list validators never depend on
+// rows retention may prune. changed_at advances by at least a second per
+// change because HTTP dates have second precision.
+const createProjectChangesTable = `
+CREATE TABLE IF NOT EXISTS project_changes (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	version BIGINT NOT NULL DEFAULT 0,
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO project_changes (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+CREATE OR REPLACE FUNCTION projects_changed() RETURNS trigger AS $$
+BEGIN
+	UPDATE project_changes
+	SET version = version + 1,
+		changed_at = GREATEST(clock_timestamp(), date_trunc('second', changed_at) + INTERVAL '1 second')
+	WHERE id = 1;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS projects_changed_trigger ON projects;
+CREATE TRIGGER projects_changed_trigger
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON projects
+	FOR EACH STATEMENT EXECUTE FUNCTION projects_changed();
+`
+
+// addWebhookEventsPosition orders outbox events by commit for streaming.
+// Positions are assigned by the event broker under advisory lock 7310458,
+// which existing events are numbered under too.
+const addWebhookEventsPosition = `
+CREATE SEQUENCE IF NOT EXISTS webhook_events_position_seq;
+
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS position BIGINT;
+
+SELECT pg_advisory_xact_lock(7310458);
+
+UPDATE webhook_events e SET position = p.position
+FROM (
+	SELECT id, nextval('webhook_events_position_seq') AS position FROM (
+		SELECT id FROM webhook_events WHERE position IS NULL ORDER BY id
+	) pending
+) p
+WHERE e.id = p.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_events_position ON webhook_events(position);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_unpositioned ON webhook_events(id) WHERE position IS NULL;
+`
+
+// addIdempotencyKeysHeartbeat lets requests hold their key for as long as
+// they run instead of a fixed time after the claim. The claim token tells
+// a request whether its claim was taken over.
+const addIdempotencyKeysHeartbeat = `
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS claim_token CHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_heartbeat_at ON idempotency_keys(heartbeat_at) WHERE status IS NULL;
+`
+
+// convertMaintenanceWindowsTimestamps stores maintenance windows as instants,
+// so that they compare correctly with CURRENT_TIMESTAMP whatever the session
+// time zone. Tables created before then held window bounds written as UTC
+// and other columns set from CURRENT_TIMESTAMP in the session time zone.
+// The check keeps the conversion from running twice.
+const convertMaintenanceWindowsTimestamps = `
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'maintenance_windows' AND column_name = 'starts_at' AND data_type = 'timestamp without time zone'
+	) THEN
+		ALTER TABLE maintenance_windows
+			ALTER COLUMN starts_at TYPE TIMESTAMPTZ USING starts_at AT TIME ZONE 'UTC',
+			ALTER COLUMN ends_at TYPE TIMESTAMPTZ USING ends_at AT TIME ZONE 'UTC',
+			ALTER COLUMN started_at TYPE TIMESTAMPTZ,
+			ALTER COLUMN ended_at TYPE TIMESTAMPTZ,
+			ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+			ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+	END IF;
+END
+$$;
+`
+
+// convertDeploymentsTimestamps stores deployment times as instants. Tables
+// created before then held finished_at written as UTC and the other columns
+// set from CURRENT_TIMESTAMP in the session time zone. The check keeps the
+// conversion from running twice.
+const convertDeploymentsTimestamps = `
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'deployments' AND column_name = 'finished_at' AND data_type = 'timestamp without time zone'
+	) THEN
+		ALTER TABLE deployments
+			ALTER COLUMN finished_at TYPE TIMESTAMPTZ USING finished_at AT TIME ZONE 'UTC',
+			ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+			ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+	END IF;
+END
+$$;
 `
@@ -8,9 +8,9 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// NewPostgres creates a new PostgreSQL database connection
-func NewPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
+// DSN returns the connection string for cfg
+func DSN(cfg config.DatabaseConfig) string {
+	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host,
 		cfg.Port,
@@ -19,8 +19,11 @@ func NewPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
 		cfg.Name,
 		cfg.SSLMode,
 	)
+}
 
-	db, err := sql.Open("postgres", dsn)
+// NewPostgres creates a new PostgreSQL database connection
+func NewPostgres(cfg config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", DSN(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedis creates a new Redis client and checks that the server responds
+func NewRedis(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,107 @@
+package domain
+
+import "time"
+
+// Audit actions recorded in audit_logs
+const (
+	AuditActionProjectCreate     = "project.create"
+	AuditActionProjectUpdate     = "project.update"
+	AuditActionProjectDelete     = "project.delete"
+	AuditActionMonitorUpdate     = "project.monitor_update"
+	AuditActionPortalUpdate      = "portal.update"
+	AuditActionWebhookCreate     = "webhook.create"
+	AuditActionWebhookUpdate     = "webhook.update"
+	AuditActionWebhookDelete     = "webhook.delete"
+	AuditActionWebhookRedeliver  = "webhook.redeliver"
+	AuditActionIncidentCreate    = "incident.create"
+	AuditActionIncidentUpdate    = "incident.update"
+	AuditActionIncidentPost      = "incident.post_update"
+	AuditActionIncidentDelete    = "incident.delete"
+	AuditActionMaintenanceCreate = "maintenance.create"
+	AuditActionMaintenanceUpdate = "maintenance.update"
+	AuditActionMaintenanceDelete = "maintenance.delete"
+	AuditActionLoginSuccess      = "auth.login_success"
+	AuditActionLoginFailure      = "auth.login_failure"
+	AuditActionUserCreate        = "user.create"
+	AuditActionUserPassword      = "user.password_change"
+)
+
+// Audit resources recorded in audit_logs
+const (
+	AuditResourceProject     = "project"
+	AuditResourcePortal      = "portal"
+	AuditResourceWebhook     = "webhook"
+	AuditResourceIncident    = "incident"
+	AuditResourceMaintenance = "maintenance_window"
+	AuditResourceUser        = "user"
+)
+
+// AuditLog represents an entry in the audit log
+type AuditLog struct {
+	ID         int       `json:"id"`
+	UserID     *int      `json:"user_id"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	ResourceID *int      `json:"resource_id"`
+	Details    string    `json:"details"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	PrevHash   string    `json:"prev_hash,omitempty"`
+	Hash       string    `json:"hash,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditCheckpoint represents a signed snapshot of the audit chain head
+type AuditCheckpoint struct {
+	ID        int       `json:"id"`
+	LastLogID int       `json:"last_log_id"`
+	LastHash  string    `json:"last_hash"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditVerifyResult represents the outcome of walking the audit chain
+type AuditVerifyResult struct {
+	Valid              bool   `json:"valid"`
+	EntriesChecked     int    `json:"entries_checked"`
+	LegacyEntries      int    `json:"legacy_entries"`
+	CheckpointsChecked int    `json:"checkpoints_checked"`
+	BrokenEntryID      int    `json:"broken_entry_id,omitempty"`
+	BrokenCheckpointID int    `json:"broken_checkpoint_id,omitempty"`
+	Reason             string `json:"reason,omitempty"`
+}
+
+// AuditDetails represents the JSON payload stored in AuditLog.Details
+type AuditDetails struct {
+	Before  interface{}            `json:"before,omitempty"`
+	After   interface{}            `json:"after,omitempty"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+	Email   string                 `json:"email,omitempty"`
+	Reason  string                 `json:"reason,omitempty"`
+}
+
+// AuditChange represents a single field change between two snapshots
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Actor identifies who performed an audited action
+type Actor struct {
+	UserID    int
+	IPAddress string
+	UserAgent string
+}
+
+// AuditLogFilter represents audit log query parameters
+type AuditLogFilter struct {
+	UserID     int       `form:"user_id"`
+	Action     string    `form:"action"`
+	Resource   string    `form:"resource"`
+	ResourceID int       `form:"resource_id"`
+	IPAddress  string    `form:"ip"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor     string    `form:"cursor"`
+	PageSize   int       `form:"page_size" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Deployment states reported by CI; succeeded and failed are final
+const (
+	DeploymentPending   = "pending"
+	DeploymentRunning   = "running"
+	DeploymentSucceeded = "succeeded"
+	DeploymentFailed    = "failed"
+)
+
+// Deployment is a release of a project version to an environment, as
+// reported by CI. Events with the same run ID update one deployment.
+type Deployment struct {
+	ID          int        `json:"id"`
+	ProjectID   int        `json:"project_id"`
+	Version     string     `json:"version"`
+	Commit      string     `json:"commit,omitempty"`
+	Environment string     `json:"environment"`
+	Status      string     `json:"status"` // pending, running, succeeded, failed
+	RunID       string     `json:"run_id,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// DeploymentEvent is the signed body CI posts to report a deployment. The
+// project is addressed by slug; RunID identifies the pipeline run so that
+// later events of the same run update its deployment. It is required so
+// that an event replayed within the signature tolerance cannot add another.
+type DeploymentEvent struct {
+	Project     string `json:"project" binding:"required,max=255"`
+	Version     string `json:"version" binding:"required,max=100"`
+	Commit      string `json:"commit" binding:"omitempty,max=64"`
+	Environment string `json:"environment" binding:"required,max=50"`
+	Status      string `json:"status" binding:"required,oneof=pending running succeeded failed"`
+	RunID       string `json:"run_id" binding:"required,max=255"`
+	URL         string `json:"url" binding:"omitempty,url,max=500"`
+}
+
+// DeploymentFilter represents deployment list query parameters;
+// deployments are returned newest first
+type DeploymentFilter struct {
+	Environment string `form:"environment" binding:"omitempty,max=50"`
+	Status      string `form:"status" binding:"omitempty,oneof=pending running succeeded failed"`
+	Page        int    `form:"page" binding:"omitempty,min=1"`
+	PageSize    int    `form:"page_size" binding:"omitempty,min=1"`
+}
@@ -0,0 +1,9 @@
+package domain
+
+// IdempotentResponse is the stored response of a request sent with an
+// Idempotency-Key, replayed when the request is retried
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
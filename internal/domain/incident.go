@@ -0,0 +1,119 @@
+package domain
+
+import "time"
+
+// Incident severities, from least to most severe
+const (
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+// Incident states; every state but resolved keeps an incident active
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentResolved      = "resolved"
+)
+
+// Project states shown on the status page, from best to worst
+const (
+	StatusOperational   = "operational"
+	StatusMaintenance   = "maintenance"
+	StatusDegraded      = "degraded_performance"
+	StatusPartialOutage = "partial_outage"
+	StatusMajorOutage   = "major_outage"
+)
+
+// Incident is an admin-authored report of a problem affecting one or more
+// projects. Updates are listed newest first.
+type Incident struct {
+	ID         int               `json:"id"`
+	Title      string            `json:"title"`
+	Severity   string            `json:"severity"` // minor, major, critical
+	State      string            `json:"state"`    // investigating, identified, monitoring, resolved
+	Projects   []IncidentProject `json:"projects"`
+	Updates    []IncidentUpdate  `json:"updates,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	ResolvedAt *time.Time        `json:"resolved_at"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// IncidentProject is a project affected by an incident
+type IncidentProject struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// IncidentUpdate is a timestamped message posted to an incident, together
+// with the state it moved the incident to
+type IncidentUpdate struct {
+	ID         int       `json:"id"`
+	IncidentID int       `json:"incident_id"`
+	State      string    `json:"state"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IncidentFeedItem is an incident update as listed in the status feed
+type IncidentFeedItem struct {
+	IncidentUpdate
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+}
+
+// CreateIncidentRequest represents create incident request; message is the
+// first update
+type CreateIncidentRequest struct {
+	Title      string     `json:"title" binding:"required,max=255"`
+	Severity   string     `json:"severity" binding:"required,oneof=minor major critical"`
+	State      string     `json:"state" binding:"omitempty,oneof=investigating identified monitoring resolved"`
+	Message    string     `json:"message" binding:"required,max=5000"`
+	ProjectIDs []int      `json:"project_ids" binding:"required,min=1,dive,min=1"`
+	StartedAt  *time.Time `json:"started_at"`
+}
+
+// UpdateIncidentRequest represents update incident request; empty fields are
+// left unchanged. State only changes by posting an update.
+type UpdateIncidentRequest struct {
+	Title      string `json:"title" binding:"omitempty,max=255"`
+	Severity   string `json:"severity" binding:"omitempty,oneof=minor major critical"`
+	ProjectIDs []int  `json:"project_ids" binding:"omitempty,min=1,dive,min=1"`
+}
+
+// CreateIncidentUpdateRequest represents a new update moving an incident to
+// the given state
+type CreateIncidentUpdateRequest struct {
+	State   string `json:"state" binding:"required,oneof=investigating identified monitoring resolved"`
+	Message string `json:"message" binding:"required,max=5000"`
+}
+
+// IncidentFilter represents admin incident list query parameters
+type IncidentFilter struct {
+	State string `form:"state" binding:"omitempty,oneof=investigating identified monitoring resolved"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// ProjectStatus is the current state of a project on the status page.
+// Health is the result of its latest uptime check.
+type ProjectStatus struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	URL    string `json:"url"`
+	Status string `json:"status"` // operational, maintenance, degraded_performance, partial_outage, major_outage
+	Health string `json:"health"` // up, down, unknown
+}
+
+// StatusPage is the public status of the portal's projects: the overall
+// status is that of the worst project. Incidents lists active incidents
+// followed by those resolved recently.
+type StatusPage struct {
+	Status    string          `json:"status"`
+	Projects  []ProjectStatus `json:"projects"`
+	Incidents []Incident      `json:"incidents"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// Maintenance window states, derived from the current time
+const (
+	MaintenanceScheduled  = "scheduled"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceCompleted  = "completed"
+)
+
+// MaintenanceWindow is a planned period during which a project is switched
+// to maintenance status. The switch happens when the window starts and the
+// previous status is restored when it ends.
+type MaintenanceWindow struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	ProjectSlug string    `json:"project_slug"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Message     string    `json:"message"`
+	State       string    `json:"state"` // scheduled, in_progress, completed
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateMaintenanceRequest represents create maintenance window request
+type CreateMaintenanceRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Message  string    `json:"message" binding:"required,max=1000"`
+}
+
+// UpdateMaintenanceRequest represents update maintenance window request;
+// empty fields are left unchanged
+type UpdateMaintenanceRequest struct {
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Message  string     `json:"message" binding:"max=1000"`
+}
+
+// MaintenanceFilter represents maintenance window list query parameters.
+// Completed windows are only listed when requested.
+type MaintenanceFilter struct {
+	IncludeCompleted bool `form:"include_completed"`
+	Limit            int  `form:"limit" binding:"omitempty,min=1,max=500"`
+}
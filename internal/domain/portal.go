@@ -16,13 +16,27 @@ type Portal struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// UpdatePortalRequest represents update portal request
+// UpdatePortalRequest represents update portal request; empty fields are
+// left unchanged
 type UpdatePortalRequest struct {
-	Name        string `json:"name" binding:"min=3"`
+	Name        string `json:"name" binding:"omitempty,min=3"`
 	Description string `json:"description"`
-	LogoURL     string `json:"logo_url" binding:"url"`
-	Website     string `json:"website" binding:"url"`
-	Email       string `json:"email" binding:"email"`
+	LogoURL     string `json:"logo_url" binding:"omitempty,url"`
+	Website     string `json:"website" binding:"omitempty,url"`
+	Email       string `json:"email" binding:"omitempty,email"`
 	Phone       string `json:"phone"`
 	Address     string `json:"address"`
 }
+
+// PortalDocument is the editable portal configuration that PATCH requests
+// apply to. Every field but Name is cleared by null; only the fields a
+// patch changes are validated.
+type PortalDocument struct {
+	Name        string `json:"name" binding:"required,min=3"`
+	Description string `json:"description"`
+	LogoURL     string `json:"logo_url" binding:"omitempty,url"`
+	Website     string `json:"website" binding:"omitempty,url"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	Phone       string `json:"phone" binding:"max=20"`
+	Address     string `json:"address"`
+}
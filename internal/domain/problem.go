@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ErrorCode is a stable, machine-readable error identifier clients can
+// branch on instead of the human-readable title
+type ErrorCode string
+
+// Error codes
+const (
+	ErrCodeValidationFailed    ErrorCode = "validation.failed"
+	ErrCodeMalformedRequest    ErrorCode = "request.malformed"
+	ErrCodeInvalidID           ErrorCode = "request.invalid_id"
+	ErrCodeInvalidCursor       ErrorCode = "request.invalid_cursor"
+	ErrCodeInvalidSort         ErrorCode = "request.invalid_sort"
+	ErrCodeInvalidFormat       ErrorCode = "request.invalid_format"
+	ErrCodeUnsupportedMedia    ErrorCode = "request.unsupported_media_type"
+	ErrCodePatchConflict       ErrorCode = "request.patch_conflict"
+	ErrCodeProjectNotFound     ErrorCode = "project.not_found"
+	ErrCodeProjectSlugTaken    ErrorCode = "project.slug_taken"
+	ErrCodeProjectSlugInvalid  ErrorCode = "project.slug_invalid"
+	ErrCodeProjectSlugReserved ErrorCode = "project.slug_reserved"
+	ErrCodeProjectManaged      ErrorCode = "project.managed"
+	ErrCodePortalNotConfigured ErrorCode = "portal.not_configured"
+	ErrCodeImportInvalid       ErrorCode = "project.import_invalid"
+	ErrCodeWebhookNotFound     ErrorCode = "webhook.not_found"
+	ErrCodeDeliveryNotFound    ErrorCode = "webhook.delivery_not_found"
+	ErrCodeIncidentNotFound    ErrorCode = "incident.not_found"
+	ErrCodeIncidentProject     ErrorCode = "incident.project_not_found"
+	ErrCodeMaintenanceNotFound ErrorCode = "maintenance.not_found"
+	ErrCodeMaintenanceInvalid  ErrorCode = "maintenance.invalid_window"
+	ErrCodeMaintenanceDone     ErrorCode = "maintenance.completed"
+	ErrCodeDeploymentsDisabled ErrorCode = "deployment.disabled"
+	ErrCodeDeploymentSignature ErrorCode = "deployment.signature_invalid"
+	ErrCodeDeploymentProject   ErrorCode = "deployment.project_not_found"
+	ErrCodeAuthTokenMissing    ErrorCode = "auth.token_missing"
+	ErrCodeAuthTokenMalformed  ErrorCode = "auth.token_malformed"
+	ErrCodeAuthTokenInvalid    ErrorCode = "auth.token_invalid"
+	ErrCodeAuthTokenExpired    ErrorCode = "auth.token_expired"
+	ErrCodeAuthInvalidLogin    ErrorCode = "auth.invalid_credentials"
+	ErrCodeQueryTooDeep        ErrorCode = "graphql.too_deep"
+	ErrCodeQueryTooComplex     ErrorCode = "graphql.too_complex"
+	ErrCodeMutationNotAllowed  ErrorCode = "graphql.mutation_not_allowed"
+	ErrCodeRateLimited         ErrorCode = "rate_limit.exceeded"
+	ErrCodeIdempotencyInvalid  ErrorCode = "idempotency.key_invalid"
+	ErrCodeIdempotencyReused   ErrorCode = "idempotency.key_reused"
+	ErrCodeIdempotencyPending  ErrorCode = "idempotency.in_progress"
+	ErrCodeInternal            ErrorCode = "internal.error"
+)
+
+// ErrorDefinition is the catalog entry of an error code
+type ErrorDefinition struct {
+	Code   ErrorCode `json:"code"`
+	Status int       `json:"status"`
+	Title  string    `json:"title"`
+}
+
+// ErrorCatalog lists every error code the API can return
+var ErrorCatalog = []ErrorDefinition{
+	{ErrCodeValidationFailed, http.StatusBadRequest, "Request validation failed"},
+	{ErrCodeMalformedRequest, http.StatusBadRequest, "Malformed request"},
+	{ErrCodeInvalidID, http.StatusBadRequest, "Invalid ID"},
+	{ErrCodeInvalidCursor, http.StatusBadRequest, "Invalid cursor"},
+	{ErrCodeInvalidSort, http.StatusBadRequest, "Invalid sort"},
+	{ErrCodeInvalidFormat, http.StatusBadRequest, "Invalid format"},
+	{ErrCodeUnsupportedMedia, http.StatusUnsupportedMediaType, "Unsupported media type"},
+	{ErrCodePatchConflict, http.StatusConflict, "Patch cannot be applied to the resource"},
+	{ErrCodeProjectNotFound, http.StatusNotFound, "Project not found"},
+	{ErrCodeProjectSlugTaken, http.StatusConflict, "Project slug already taken"},
+	{ErrCodeProjectSlugInvalid, http.StatusBadRequest, "Invalid project slug"},
+	{ErrCodeProjectSlugReserved, http.StatusBadRequest, "Project slug is reserved"},
+	{ErrCodeProjectManaged, http.StatusConflict, "Project is managed by sync"},
+	{ErrCodePortalNotConfigured, http.StatusNotFound, "Portal not configured"},
+	{ErrCodeImportInvalid, http.StatusUnprocessableEntity, "Project import contains invalid rows"},
+	{ErrCodeWebhookNotFound, http.StatusNotFound, "Webhook not found"},
+	{ErrCodeDeliveryNotFound, http.StatusNotFound, "Webhook delivery not found"},
+	{ErrCodeIncidentNotFound, http.StatusNotFound, "Incident not found"},
+	{ErrCodeIncidentProject, http.StatusUnprocessableEntity, "Incident refers to an unknown project"},
+	{ErrCodeMaintenanceNotFound, http.StatusNotFound, "Maintenance window not found"},
+	{ErrCodeMaintenanceInvalid, http.StatusBadRequest, "Maintenance window must end after it starts and in the future"},
+	{ErrCodeMaintenanceDone, http.StatusConflict, "Maintenance window has already ended"},
+	{ErrCodeDeploymentsDisabled, http.StatusNotFound, "Deployment tracking not configured"},
+	{ErrCodeDeploymentSignature, http.StatusUnauthorized, "Invalid or expired deployment signature"},
+	{ErrCodeDeploymentProject, http.StatusUnprocessableEntity, "Deployment refers to an unknown project"},
+	{ErrCodeAuthTokenMissing, http.StatusUnauthorized, "Missing authorization header"},
+	{ErrCodeAuthTokenMalformed, http.StatusUnauthorized, "Invalid authorization header format"},
+	{ErrCodeAuthTokenInvalid, http.StatusUnauthorized, "Invalid token"},
+	{ErrCodeAuthTokenExpired, http.StatusUnauthorized, "Token expired"},
+	{ErrCodeAuthInvalidLogin, http.StatusUnauthorized, "Invalid email or password"},
+	{ErrCodeQueryTooDeep, http.StatusBadRequest, "GraphQL query too deep"},
+	{ErrCodeQueryTooComplex, http.StatusBadRequest, "GraphQL query too complex"},
+	{ErrCodeMutationNotAllowed, http.StatusMethodNotAllowed, "Mutations require POST"},
+	{ErrCodeRateLimited, http.StatusTooManyRequests, "Rate limit exceeded"},
+	{ErrCodeIdempotencyInvalid, http.StatusBadRequest, "Invalid idempotency key"},
+	{ErrCodeIdempotencyReused, http.StatusUnprocessableEntity, "Idempotency key reused with a different request"},
+	{ErrCodeIdempotencyPending, http.StatusConflict, "Request with this idempotency key is still in progress"},
+	{ErrCodeInternal, http.StatusInternalServerError, "Internal server error"},
+}
+
+// ErrorTypeBase prefixes error codes to form the problem type URI; the
+// catalog is served at this path
+const ErrorTypeBase = "/api/v1/errors#"
+
+// Problem is an RFC 9457 problem details response
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      ErrorCode    `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single failed validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports fields a service found invalid in a request that
+// passed binding, e.g. because the rules depend on stored state
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+" "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// LookupError returns the catalog entry of code
+func LookupError(code ErrorCode) (ErrorDefinition, bool) {
+	for _, def := range ErrorCatalog {
+		if def.Code == code {
+			return def, true
+		}
+	}
+	return ErrorDefinition{}, false
+}
+
+// NewProblem creates a problem for a catalogued error code. Unknown codes
+// are reported as internal errors.
+func NewProblem(code ErrorCode, detail string) *Problem {
+	def, ok := LookupError(code)
+	if !ok {
+		def, _ = LookupError(ErrCodeInternal)
+	}
+
+	return &Problem{
+		Type:   ErrorTypeBase + string(def.Code),
+		Title:  def.Title,
+		Status: def.Status,
+		Detail: detail,
+		Code:   def.Code,
+	}
+}
@@ -2,6 +2,13 @@ package domain
 
 import "time"
 
+// Project statuses
+const (
+	ProjectActive      = "active"
+	ProjectInactive    = "inactive"
+	ProjectMaintenance = "maintenance"
+)
+
 // Project represents a project in the portal
 type Project struct {
 	ID          int       `json:"id"`
@@ -12,26 +19,87 @@ type Project struct {
 	IconURL     string    `json:"icon_url"`
 	Status      string    `json:"status"` // active, inactive, maintenance
 	Order       int       `json:"order"`
+	Tags        []string  `json:"tags"`
+	Managed     bool      `json:"managed"` // owned by a sync manifest
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // CreateProjectRequest represents create project request
 type CreateProjectRequest struct {
-	Name        string `json:"name" binding:"required,min=3"`
-	Slug        string `json:"slug" binding:"required,min=3"`
-	Description string `json:"description" binding:"required"`
-	URL         string `json:"url" binding:"required,url"`
-	IconURL     string `json:"icon_url" binding:"url"`
-	Status      string `json:"status" binding:"required,oneof=active inactive maintenance"`
+	Name        string   `json:"name" yaml:"name" binding:"required,min=3"`
+	Slug        string   `json:"slug" yaml:"slug,omitempty" binding:"omitempty,min=3"` // generated from Name when empty
+	Description string   `json:"description" yaml:"description" binding:"required"`
+	URL         string   `json:"url" yaml:"url" binding:"required,url"`
+	IconURL     string   `json:"icon_url" yaml:"icon_url,omitempty" binding:"omitempty,url"`
+	Status      string   `json:"status" yaml:"status" binding:"required,oneof=active inactive maintenance"`
+	Tags        []string `json:"tags" yaml:"tags,omitempty" binding:"omitempty,dive,min=1,max=50"`
 }
 
-// UpdateProjectRequest represents update project request
+// UpdateProjectRequest represents update project request; empty fields are
+// left unchanged
 type UpdateProjectRequest struct {
-	Name        string `json:"name" binding:"min=3"`
-	Slug        string `json:"slug" binding:"min=3"`
-	Description string `json:"description"`
-	URL         string `json:"url" binding:"url"`
-	IconURL     string `json:"icon_url" binding:"url"`
-	Status      string `json:"status" binding:"oneof=active inactive maintenance"`
+	Name        string   `json:"name" binding:"omitempty,min=3"`
+	Slug        string   `json:"slug" binding:"omitempty,min=3"`
+	Description string   `json:"description"`
+	URL         string   `json:"url" binding:"omitempty,url"`
+	IconURL     string   `json:"icon_url" binding:"omitempty,url"`
+	Status      string   `json:"status" binding:"omitempty,oneof=active inactive maintenance"`
+	Tags        []string `json:"tags" binding:"omitempty,dive,min=1,max=50"`
+}
+
+// ProjectDocument is the editable part of a project that PATCH requests
+// apply to. Description and IconURL are cleared by null; only the fields a
+// patch changes are validated.
+type ProjectDocument struct {
+	Name        string   `json:"name" binding:"required,min=3"`
+	Slug        string   `json:"slug" binding:"required,min=3"`
+	Description string   `json:"description"`
+	URL         string   `json:"url" binding:"required,url"`
+	IconURL     string   `json:"icon_url" binding:"omitempty,url"`
+	Status      string   `json:"status" binding:"required,oneof=active inactive maintenance"`
+	Tags        []string `json:"tags" binding:"omitempty,dive,min=1,max=50"`
+}
+
+// ProjectFilter represents project list query parameters. Pages are
+// addressed either by Page or by the opaque Cursor from a previous page.
+type ProjectFilter struct {
+	Status   string   `form:"status" binding:"omitempty,oneof=active inactive maintenance"`
+	Tags     []string `form:"tag"`
+	Query    string   `form:"q"`
+	Sort     string   `form:"sort"`
+	Page     int      `form:"page" binding:"omitempty,min=1"`
+	PageSize int      `form:"page_size" binding:"omitempty,min=1"`
+	Cursor   string   `form:"cursor"`
+}
+
+// SearchRequest represents project search query parameters
+type SearchRequest struct {
+	Query string `form:"q" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1"`
+}
+
+// ProjectSearchResult represents a ranked project search hit. The
+// highlight fields are HTML-escaped with matches wrapped in <mark>.
+type ProjectSearchResult struct {
+	Project       Project `json:"project"`
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+// TagCount is a project tag with the number of projects carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ProjectStatusChange is a status transition recorded in the audit log.
+// From is empty when the project was created and To when it was deleted.
+type ProjectStatusChange struct {
+	ProjectID int       `json:"project_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	UserID    *int      `json:"user_id"`
+	ChangedAt time.Time `json:"changed_at"`
 }
@@ -0,0 +1,46 @@
+package domain
+
+// Project import modes
+const (
+	ImportModeCreate = "create" // rows whose slug exists are rejected
+	ImportModeUpsert = "upsert" // rows whose slug exists update that project
+)
+
+// Import row actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionInvalid   = "invalid"
+)
+
+// ProjectImportOptions represents project import query parameters. Format
+// defaults to the request Content-Type.
+type ProjectImportOptions struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv json yaml"`
+	Mode   string `form:"mode" binding:"omitempty,oneof=create upsert"`
+	DryRun bool   `form:"dry_run"`
+}
+
+// ProjectImportRow is one record of an import file and its planned outcome
+type ProjectImportRow struct {
+	Row       int                  `json:"row"` // 1-based, excluding any header
+	Slug      string               `json:"slug"`
+	Action    string               `json:"action"`               // create, update, unchanged, invalid
+	ProjectID int                  `json:"project_id,omitempty"` // the project updated
+	Errors    []FieldError         `json:"errors,omitempty"`
+	Project   CreateProjectRequest `json:"-"`
+}
+
+// ProjectImportResult summarizes an import. Nothing is applied unless
+// every row is valid.
+type ProjectImportResult struct {
+	Mode      string             `json:"mode"`
+	DryRun    bool               `json:"dry_run"`
+	Applied   bool               `json:"applied"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Invalid   int                `json:"invalid"`
+	Rows      []ProjectImportRow `json:"rows"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+// Project sync actions
+const (
+	SyncActionCreate  = "create"
+	SyncActionUpdate  = "update"
+	SyncActionReorder = "reorder" // only the position changed
+	SyncActionDelete  = "delete"
+)
+
+// Managed project policies
+const (
+	ManagedPolicyWarn  = "warn"  // admins may edit managed projects
+	ManagedPolicyBlock = "block" // managed projects can only change through sync
+)
+
+// ProjectSyncChange is one planned change of a sync
+type ProjectSyncChange struct {
+	Action    string                 `json:"action"` // create, update, reorder, delete
+	Slug      string                 `json:"slug"`
+	ProjectID int                    `json:"project_id,omitempty"`
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+}
+
+// ProjectSyncPlan lists the changes that bring the projects table in line
+// with a manifest. Projects not listed in the manifest are only deleted if
+// an earlier sync created or adopted them.
+type ProjectSyncPlan struct {
+	Changes   []ProjectSyncChange `json:"changes"`
+	Unchanged int                 `json:"unchanged"`
+	Applied   bool                `json:"applied"`
+}
+
+// Count returns how many changes of action the plan holds
+func (p *ProjectSyncPlan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
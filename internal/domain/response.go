@@ -5,24 +5,45 @@ type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
 }
 
-// PaginatedResponse represents a paginated API response
+// PaginatedResponse represents a paginated API response. Page is 0 when the
+// page was addressed by cursor; NextCursor is set when more rows follow.
 type PaginatedResponse struct {
 	Data       interface{} `json:"data"`
 	Total      int         `json:"total"`
 	Page       int         `json:"page"`
 	PageSize   int         `json:"page_size"`
 	TotalPages int         `json:"total_pages"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
-// HealthCheckResponse represents health check response
+// Health statuses, from best to worst
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+// HealthCheckResponse represents health check response. Checks lists the
+// dependency checks behind Status and is omitted by the liveness probe.
 type HealthCheckResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
-	Version   string `json:"version"`
-	Uptime    int64  `json:"uptime"`
+	Status    string                      `json:"status"` // ok, degraded, down
+	Timestamp string                      `json:"timestamp"`
+	Version   string                      `json:"version"`
+	Commit    string                      `json:"commit,omitempty"`
+	BuildTime string                      `json:"build_time,omitempty"`
+	Uptime    int64                       `json:"uptime"` // seconds since the process started
+	Checks    map[string]DependencyHealth `json:"checks,omitempty"`
+}
+
+// DependencyHealth is the outcome of one dependency check. A failing
+// critical dependency takes the service down; any other only degrades it.
+type DependencyHealth struct {
+	Status    string `json:"status"` // ok, degraded, down
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
 }
 
 // NewAPIResponse creates a new API response
@@ -34,11 +55,19 @@ func NewAPIResponse(success bool, message string, data interface{}) *APIResponse
 	}
 }
 
-// NewErrorResponse creates a new error response
-func NewErrorResponse(message string, err string) *APIResponse {
-	return &APIResponse{
-		Success: false,
-		Message: message,
-		Error:   err,
+// NewPaginatedResponse creates a paginated response, deriving the page count
+// from the total and page size
+func NewPaginatedResponse(data interface{}, total, page, pageSize int) *PaginatedResponse {
+	totalPages := 0
+	if pageSize > 0 {
+		totalPages = (total + pageSize - 1) / pageSize
+	}
+
+	return &PaginatedResponse{
+		Data:       data,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
 	}
 }
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// RetentionRun represents the summary of applying one retention policy
+type RetentionRun struct {
+	ID           int       `json:"id"`
+	Table        string    `json:"table"`
+	Cutoff       time.Time `json:"cutoff"`
+	RowsDeleted  int       `json:"rows_deleted"`
+	RowsArchived int       `json:"rows_archived"`
+	ArchiveFile  string    `json:"archive_file,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// Project health states derived from the latest uptime check
+const (
+	HealthUp      = "up"
+	HealthUnknown = "unknown" // not checked yet
+)
+
+// ProjectMonitor is the uptime check configuration of a project. Every
+// project has one, enabled with the configured defaults; inactive projects
+// are not checked.
+type ProjectMonitor struct {
+	ProjectID       int        `json:"project_id"`
+	Enabled         bool       `json:"enabled"`
+	Method          string     `json:"method"`          // GET or HEAD
+	ExpectedStatus  int        `json:"expected_status"` // 0 accepts any 2xx
+	TimeoutSeconds  int        `json:"timeout_seconds"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	NextCheckAt     *time.Time `json:"next_check_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// UpdateProjectMonitorRequest represents update monitor request; omitted
+// fields are left unchanged and a timeout or interval of 0 restores the
+// default
+type UpdateProjectMonitorRequest struct {
+	Enabled         *bool  `json:"enabled"`
+	Method          string `json:"method" binding:"omitempty,oneof=GET HEAD"`
+	ExpectedStatus  *int   `json:"expected_status" binding:"omitempty,min=0,max=599"`
+	TimeoutSeconds  *int   `json:"timeout_seconds" binding:"omitempty,min=0,max=60"`
+	IntervalSeconds *int   `json:"interval_seconds" binding:"omitempty,min=0,max=86400"`
+}
+
+// ProjectCheck is the result of one uptime check. StatusCode is null when
+// no response was received.
+type ProjectCheck struct {
+	ID         int64     `json:"id"`
+	ProjectID  int       `json:"project_id"`
+	Up         bool      `json:"up"`
+	StatusCode *int      `json:"status_code"`
+	LatencyMS  int       `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// ProjectHealth is the current uptime state of a project: up, down or
+// unknown, with availability over the last 24 hours
+type ProjectHealth struct {
+	ProjectID     int           `json:"project_id"`
+	Status        string        `json:"status"` // up, down, unknown
+	LastCheck     *ProjectCheck `json:"last_check"`
+	Uptime24h     *float64      `json:"uptime_24h"` // percentage of checks up; null without checks
+	AvgLatency24h *int          `json:"avg_latency_ms_24h"`
+	Checks24h     int           `json:"checks_24h"`
+}
+
+// ProjectCheckFilter represents check history query parameters; checks are
+// returned newest first
+type ProjectCheckFilter struct {
+	From  time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To    time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types published to webhook subscribers
+const (
+	EventProjectCreated       = "project.created"
+	EventProjectUpdated       = "project.updated"
+	EventProjectDeleted       = "project.deleted"
+	EventProjectStatusChanged = "project.status_changed"
+	EventPortalUpdated        = "portal.updated"
+)
+
+// EventAll subscribes a webhook to every event type
+const EventAll = "*"
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook represents an admin-managed webhook subscription. The signing
+// secret is only returned when it is set.
+type Webhook struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest represents create webhook request. A secret is
+// generated when none is given.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=* project.created project.updated project.deleted project.status_changed portal.updated"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=255"`
+	Active      *bool    `json:"active"`
+}
+
+// UpdateWebhookRequest represents update webhook request; empty fields are
+// left unchanged
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url" binding:"omitempty,url"`
+	Description string   `json:"description" binding:"max=255"`
+	Events      []string `json:"events" binding:"omitempty,min=1,dive,oneof=* project.created project.updated project.deleted project.status_changed portal.updated"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=255"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookSecret is returned once when a webhook is created so that the
+// receiver can verify signatures
+type WebhookSecret struct {
+	ID     int    `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery represents one event queued for one webhook, together
+// with the outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	EventID        int        `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"` // pending, succeeded, failed
+	Attempts       int        `json:"attempts"`
+	ResponseStatus *int       `json:"response_status"`
+	ResponseBody   string     `json:"response_body,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Event is an outbox event as POSTed to webhook subscribers and streamed
+// to /api/v1/events clients. Position orders streamed events by commit and
+// is what streaming clients resume from.
+type Event struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+	Position  int             `json:"-"`
+}
+
+// ProjectEventData is the data of project events; Changes lists the fields
+// that differ from the previous state
+type ProjectEventData struct {
+	Project *Project               `json:"project"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+}
+
+// PortalEventData is the data of portal events
+type PortalEventData struct {
+	Portal  *Portal                `json:"portal"`
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+}
@@ -0,0 +1,129 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// defaultTimeout bounds each check when none is configured
+const defaultTimeout = 2 * time.Second
+
+// Check probes one dependency. It returns nil when the dependency is
+// healthy and an error wrapped by Degraded when it works but needs
+// attention.
+type Check func(ctx context.Context) error
+
+// degradedError marks a check failure that only degrades the service
+type degradedError struct {
+	err error
+}
+
+func (e degradedError) Error() string { return e.err.Error() }
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degraded marks err as a warning rather than an outage
+func Degraded(err error) error {
+	return degradedError{err: err}
+}
+
+type registered struct {
+	name     string
+	critical bool
+	check    Check
+}
+
+// Checker runs the registered dependency checks
+type Checker struct {
+	timeout time.Duration
+	checks  []registered
+}
+
+// NewChecker creates a checker giving each check timeout to finish
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check. A failing critical check takes the service down;
+// a failing non-critical one only degrades it. Register must not be called
+// once the checker is in use.
+func (c *Checker) Register(name string, critical bool, check Check) {
+	c.checks = append(c.checks, registered{name: name, critical: critical, check: check})
+}
+
+// Run runs every check concurrently and returns the overall status with the
+// result of each check
+func (c *Checker) Run(ctx context.Context) (string, map[string]domain.DependencyHealth) {
+	results := make(map[string]domain.DependencyHealth, len(c.checks))
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, r := range c.checks {
+		wg.Add(1)
+		go func(r registered) {
+			defer wg.Done()
+			result := c.run(ctx, r)
+
+			mu.Lock()
+			results[r.name] = result
+			mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+
+	status := domain.HealthOK
+	for _, result := range results {
+		status = worst(status, result.Status)
+	}
+
+	return status, results
+}
+
+func (c *Checker) run(ctx context.Context, r registered) domain.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := r.check(ctx)
+	result := domain.DependencyHealth{
+		Status:    domain.HealthOK,
+		Critical:  r.critical,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+
+	if err == nil {
+		return result
+	}
+
+	result.Error = err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Error = "timed out after " + c.timeout.String()
+	}
+
+	var degraded degradedError
+	if r.critical && !errors.As(err, &degraded) {
+		result.Status = domain.HealthDown
+	} else {
+		result.Status = domain.HealthDegraded
+	}
+
+	return result
+}
+
+// worst returns the worse of two statuses
+func worst(a, b string) string {
+	rank := map[string]int{domain.HealthOK: 0, domain.HealthDegraded: 1, domain.HealthDown: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,23 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+type contextKey struct{}
+
+// request is the per-request state resolvers read from the context
+type request struct {
+	actor         domain.Actor
+	authenticated bool
+	requestID     string
+
+	statusHistory *loader
+}
+
+// requestFrom returns the request state stored by Server.Execute
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(contextKey{}).(*request)
+}
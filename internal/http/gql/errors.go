@@ -0,0 +1,65 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// Error is a GraphQL error carrying a catalogued error code in its
+// extensions, mirroring the REST problem responses
+type Error struct {
+	Code    domain.ErrorCode
+	Message string
+	Fields  []domain.FieldError
+}
+
+func newError(code domain.ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		ext["errors"] = e.Fields
+	}
+	return ext
+}
+
+// resolveError maps a service error onto a coded GraphQL error. Unexpected
+// errors are logged with the request ID and not revealed to the client.
+func resolveError(ctx context.Context, err error) error {
+	if fields, ok := problem.FieldErrors(err); ok {
+		e := newError(domain.ErrCodeValidationFailed, "One or more fields are invalid")
+		e.Fields = fields
+		return e
+	}
+
+	codes := map[error]domain.ErrorCode{
+		service.ErrProjectNotFound: domain.ErrCodeProjectNotFound,
+		service.ErrSlugTaken:       domain.ErrCodeProjectSlugTaken,
+		service.ErrSlugInvalid:     domain.ErrCodeProjectSlugInvalid,
+		service.ErrSlugReserved:    domain.ErrCodeProjectSlugReserved,
+		service.ErrProjectManaged:  domain.ErrCodeProjectManaged,
+		service.ErrInvalidCursor:   domain.ErrCodeInvalidCursor,
+		service.ErrInvalidSort:     domain.ErrCodeInvalidSort,
+	}
+	for target, code := range codes {
+		if errors.Is(err, target) {
+			def, _ := domain.LookupError(code)
+			return newError(code, def.Title)
+		}
+	}
+
+	log.Printf("[%s] graphql: %v", requestFrom(ctx).requestID, err)
+	return newError(domain.ErrCodeInternal, "An unexpected error occurred; quote the request ID when reporting it")
+}
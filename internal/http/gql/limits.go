@@ -0,0 +1,146 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Default query limits
+const (
+	defaultMaxDepth      = 8
+	defaultMaxComplexity = 1000
+)
+
+// listSizes is the assumed result size of list fields without a "first"
+// argument when estimating complexity
+var listSizes = map[string]int{
+	"projects":      20,
+	"tags":          50,
+	"statusHistory": 5,
+}
+
+// analysis is the static shape of the operation about to be executed
+type analysis struct {
+	operation  string
+	depth      int
+	complexity int
+}
+
+// analyze parses query and measures the selected operation. Depth counts
+// nested fields; complexity counts every field, multiplying the cost of a
+// list field's selections by its page size. Introspection fields are free.
+func analyze(query, operationName string, variables map[string]interface{}) (*analysis, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, err
+	}
+
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			operations = append(operations, d)
+		}
+	}
+
+	var op *ast.OperationDefinition
+	for _, candidate := range operations {
+		if operationName == "" || (candidate.Name != nil && candidate.Name.Value == operationName) {
+			op = candidate
+			break
+		}
+	}
+	if op == nil {
+		return nil, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	w := &walker{fragments: fragments, variables: variables, visiting: map[string]bool{}}
+	complexity, depth := w.selectionSet(op.SelectionSet)
+
+	return &analysis{operation: op.Operation, depth: depth, complexity: complexity}, nil
+}
+
+// walker computes cost and depth over selection sets, expanding fragments
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func (w *walker) selectionSet(set *ast.SelectionSet) (cost, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var c, d int
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			childCost, childDepth := w.selectionSet(s.SelectionSet)
+			c = 1 + childCost*w.listSize(s)
+			d = 1 + childDepth
+
+		case *ast.InlineFragment:
+			c, d = w.selectionSet(s.SelectionSet)
+
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := w.fragments[name]
+			if !ok || w.visiting[name] {
+				continue
+			}
+			w.visiting[name] = true
+			c, d = w.selectionSet(fragment.SelectionSet)
+			w.visiting[name] = false
+		}
+
+		cost += c
+		if d > depth {
+			depth = d
+		}
+	}
+
+	return cost, depth
+}
+
+// listSize returns the multiplier applied to the selections of field
+func (w *walker) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := w.variables[v.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+
+	if size, ok := listSizes[field.Name.Value]; ok {
+		return size
+	}
+	return 1
+}
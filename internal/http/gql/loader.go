@@ -0,0 +1,69 @@
+package gql
+
+import "sync"
+
+// batchFunc loads the values of many keys at once. Keys missing from the
+// result resolve to nil.
+type batchFunc func(keys []int) (map[int]interface{}, error)
+
+// loader batches the keys requested while a query level is resolved into a
+// single fetch. Load returns a thunk; the executor only calls thunks once
+// every field of the level has been resolved, so all keys are known by the
+// time the first thunk runs. A loader lives for a single request.
+type loader struct {
+	fetch batchFunc
+
+	mu      sync.Mutex
+	pending []int
+	results map[int]interface{}
+	errs    map[int]error
+}
+
+func newLoader(fetch batchFunc) *loader {
+	return &loader{
+		fetch:   fetch,
+		results: map[int]interface{}{},
+		errs:    map[int]error{},
+	}
+}
+
+// Load queues key and returns a thunk resolving to its value
+func (l *loader) Load(key int) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, loaded := l.results[key]; !loaded {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			l.dispatch()
+		}
+
+		return l.results[key], l.errs[key]
+	}
+}
+
+// dispatch fetches every pending key; callers hold l.mu
+func (l *loader) dispatch() {
+	seen := map[int]bool{}
+	keys := make([]int, 0, len(l.pending))
+	for _, key := range l.pending {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	l.pending = nil
+
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		l.results[key] = values[key]
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// resolver implements the root fields on top of the service layer
+type resolver struct {
+	projectService *service.ProjectService
+	portalService  *service.PortalService
+}
+
+func (r *resolver) portal(p graphql.ResolveParams) (interface{}, error) {
+	portal, err := r.portalService.GetPortal()
+	if errors.Is(err, service.ErrPortalNotConfigured) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return portal, nil
+}
+
+func (r *resolver) projects(p graphql.ResolveParams) (interface{}, error) {
+	filter := domain.ProjectFilter{}
+	filter.Status, _ = p.Args["status"].(string)
+	filter.Query, _ = p.Args["q"].(string)
+	filter.Sort, _ = p.Args["sort"].(string)
+	filter.PageSize, _ = p.Args["first"].(int)
+	filter.Cursor, _ = p.Args["after"].(string)
+	if tags, ok := p.Args["tags"].([]interface{}); ok {
+		for _, tag := range tags {
+			filter.Tags = append(filter.Tags, tag.(string))
+		}
+	}
+
+	if err := binding.Validator.ValidateStruct(&filter); err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	page, err := r.projectService.ListProjects(&filter, !requestFrom(p.Context).authenticated)
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return page, nil
+}
+
+func (r *resolver) project(p graphql.ResolveParams) (interface{}, error) {
+	var (
+		project *domain.Project
+		err     error
+	)
+
+	if id, ok := p.Args["id"].(int); ok {
+		project, err = r.projectService.GetProjectByID(id)
+	} else if slug, ok := p.Args["slug"].(string); ok {
+		project, err = r.projectService.GetProjectBySlug(slug)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			// Old slugs resolve to the renamed project
+			var current string
+			if current, err = r.projectService.CurrentSlug(slug); err == nil {
+				project, err = r.projectService.GetProjectBySlug(current)
+			}
+		}
+	} else {
+		return nil, newError(domain.ErrCodeValidationFailed, "id or slug is required")
+	}
+
+	if errors.Is(err, service.ErrProjectNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return project, nil
+}
+
+func (r *resolver) tags(p graphql.ResolveParams) (interface{}, error) {
+	tags, err := r.projectService.ListTags(!requestFrom(p.Context).authenticated)
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return tags, nil
+}
+
+func (r *resolver) createProject(p graphql.ResolveParams) (interface{}, error) {
+	req := &domain.CreateProjectRequest{}
+	if err := authorizeInput(p, req); err != nil {
+		return nil, err
+	}
+
+	id, err := r.projectService.CreateProject(requestFrom(p.Context).actor, req)
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return r.reloadProject(p, id)
+}
+
+func (r *resolver) updateProject(p graphql.ResolveParams) (interface{}, error) {
+	req := &domain.UpdateProjectRequest{}
+	if err := authorizeInput(p, req); err != nil {
+		return nil, err
+	}
+
+	id := p.Args["id"].(int)
+	if err := r.projectService.UpdateProject(requestFrom(p.Context).actor, id, req); err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return r.reloadProject(p, id)
+}
+
+func (r *resolver) deleteProject(p graphql.ResolveParams) (interface{}, error) {
+	if err := authorize(p); err != nil {
+		return nil, err
+	}
+
+	if err := r.projectService.DeleteProject(requestFrom(p.Context).actor, p.Args["id"].(int)); err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return true, nil
+}
+
+func (r *resolver) updatePortal(p graphql.ResolveParams) (interface{}, error) {
+	req := &domain.UpdatePortalRequest{}
+	if err := authorizeInput(p, req); err != nil {
+		return nil, err
+	}
+
+	if err := r.portalService.UpdatePortal(requestFrom(p.Context).actor, req); err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	portal, err := r.portalService.GetPortal()
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return portal, nil
+}
+
+// reloadProject returns the stored state of a project after a mutation
+func (r *resolver) reloadProject(p graphql.ResolveParams, id int) (interface{}, error) {
+	project, err := r.projectService.GetProjectByID(id)
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+
+	return project, nil
+}
+
+// authorize applies the /admin rule to mutations: a valid JWT is required
+func authorize(p graphql.ResolveParams) error {
+	if !requestFrom(p.Context).authenticated {
+		return newError(domain.ErrCodeAuthTokenMissing, "Mutations require a bearer token")
+	}
+	return nil
+}
+
+// authorizeInput checks authorization, then decodes the input argument into
+// req and validates it with the same binding rules as the REST endpoints
+func authorizeInput(p graphql.ResolveParams, req interface{}) error {
+	if err := authorize(p); err != nil {
+		return err
+	}
+
+	input, _ := p.Args["input"].(map[string]interface{})
+	fields := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		fields[snakeCase(key)] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return resolveError(p.Context, err)
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		return resolveError(p.Context, err)
+	}
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return resolveError(p.Context, err)
+	}
+
+	return nil
+}
+
+// snakeCase converts an input field name such as iconUrl to the JSON name
+// of the request struct (icon_url)
+func snakeCase(s string) string {
+	var out []rune
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
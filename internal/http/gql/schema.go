@@ -0,0 +1,221 @@
+package gql
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// Object types mirror the domain structs; the default resolver maps the
+// camelCase field names onto the Go fields
+var (
+	portalType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Portal",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.String},
+			"logoUrl":     &graphql.Field{Type: graphql.String},
+			"website":     &graphql.Field{Type: graphql.String},
+			"email":       &graphql.Field{Type: graphql.String},
+			"phone":       &graphql.Field{Type: graphql.String},
+			"address":     &graphql.Field{Type: graphql.String},
+			"createdAt":   &graphql.Field{Type: graphql.DateTime},
+			"updatedAt":   &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	statusChangeType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "StatusChange",
+		Description: "A project status transition; from is empty on creation and to on deletion",
+		Fields: graphql.Fields{
+			"from":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"to":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"changedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"userId": &graphql.Field{
+				Type:        graphql.Int,
+				Description: "Admin who made the change; only visible to authenticated clients",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					change := p.Source.(domain.ProjectStatusChange)
+					if !requestFrom(p.Context).authenticated || change.UserID == nil {
+						return nil, nil
+					}
+					return *change.UserID, nil
+				},
+			},
+		},
+	})
+
+	tagCountType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "TagCount",
+		Fields: graphql.Fields{
+			"tag":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	projectType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Project",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"slug":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.String},
+			"url":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"iconUrl":     &graphql.Field{Type: graphql.String},
+			"status":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"order":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"tags":        &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"managed":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Defined in the sync manifest"},
+			"createdAt":   &graphql.Field{Type: graphql.DateTime},
+			"updatedAt":   &graphql.Field{Type: graphql.DateTime},
+			"statusHistory": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statusChangeType))),
+				Description: "Status transitions, oldest first; batched across all projects in a query",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return requestFrom(p.Context).statusHistory.Load(asProject(p.Source).ID), nil
+				},
+			},
+		},
+	})
+
+	projectPageType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProjectPage",
+		Fields: graphql.Fields{
+			"items":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(projectType))), Resolve: resolvePageItems},
+			"total":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"nextCursor": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	createProjectInput = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateProjectInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"slug":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"url":         &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"iconUrl":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"status":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	updateProjectInput = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateProjectInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"slug":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"url":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"iconUrl":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"status":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tags":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	updatePortalInput = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdatePortalInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"logoUrl":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"website":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"email":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"phone":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"address":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+)
+
+// newSchema builds the schema with resolvers bound to r
+func newSchema(r *resolver) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"portal": &graphql.Field{
+				Type:    portalType,
+				Resolve: r.portal,
+			},
+			"projects": &graphql.Field{
+				Type:        graphql.NewNonNull(projectPageType),
+				Description: "Projects page; anonymous clients only see active projects",
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: graphql.String},
+					"tags":   &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"q":      &graphql.ArgumentConfig{Type: graphql.String},
+					"sort":   &graphql.ArgumentConfig{Type: graphql.String},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: r.projects,
+			},
+			"project": &graphql.Field{
+				Type:        projectType,
+				Description: "Project by id or slug; old slugs of renamed projects also match",
+				Args: graphql.FieldConfigArgument{
+					"id":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"slug": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: r.project,
+			},
+			"tags": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(tagCountType))),
+				Description: "Tags with their project counts, most used first",
+				Resolve:     r.tags,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createProject": &graphql.Field{
+				Type: graphql.NewNonNull(projectType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createProjectInput)},
+				},
+				Resolve: r.createProject,
+			},
+			"updateProject": &graphql.Field{
+				Type: graphql.NewNonNull(projectType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateProjectInput)},
+				},
+				Resolve: r.updateProject,
+			},
+			"deleteProject": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: r.deleteProject,
+			},
+			"updatePortal": &graphql.Field{
+				Type: graphql.NewNonNull(portalType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updatePortalInput)},
+				},
+				Resolve: r.updatePortal,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// resolvePageItems returns the projects of a page
+func resolvePageItems(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*domain.PaginatedResponse).Data, nil
+}
+
+// asProject returns the project a Project field is resolved on
+func asProject(source interface{}) *domain.Project {
+	if p, ok := source.(domain.Project); ok {
+		return &p
+	}
+	return source.(*domain.Project)
+}
@@ -0,0 +1,120 @@
+// Package gql serves the GraphQL API for portal and project data on top of
+// the service layer.
+package gql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Caller identifies who sent a request
+type Caller struct {
+	Actor         domain.Actor
+	Authenticated bool
+	RequestID     string
+}
+
+// Server executes GraphQL requests against the portal schema
+type Server struct {
+	schema         graphql.Schema
+	projectService *service.ProjectService
+	maxDepth       int
+	maxComplexity  int
+}
+
+// NewServer builds the schema and applies the configured query limits
+func NewServer(projectService *service.ProjectService, portalService *service.PortalService, cfg config.GraphQLConfig) (*Server, error) {
+	schema, err := newSchema(&resolver{projectService: projectService, portalService: portalService})
+	if err != nil {
+		return nil, fmt.Errorf("graphql schema: %w", err)
+	}
+
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = defaultMaxDepth
+	}
+	if cfg.MaxComplexity <= 0 {
+		cfg.MaxComplexity = defaultMaxComplexity
+	}
+
+	return &Server{
+		schema:         schema,
+		projectService: projectService,
+		maxDepth:       cfg.MaxDepth,
+		maxComplexity:  cfg.MaxComplexity,
+	}, nil
+}
+
+// Execute checks req against the query limits and runs it. Mutations are
+// rejected unless allowMutations is set, so that GET requests stay safe.
+func (s *Server) Execute(ctx context.Context, req *Request, caller Caller, allowMutations bool) *graphql.Result {
+	// Syntax errors are left for graphql.Do, which reports their location
+	if a, err := analyze(req.Query, req.OperationName, req.Variables); err == nil {
+		switch {
+		case a.operation == "mutation" && !allowMutations:
+			return errorResult(newError(domain.ErrCodeMutationNotAllowed, "Mutations must be sent with POST"))
+		case a.depth > s.maxDepth:
+			return errorResult(newError(domain.ErrCodeQueryTooDeep, fmt.Sprintf("Query depth %d exceeds the limit of %d", a.depth, s.maxDepth)))
+		case a.complexity > s.maxComplexity:
+			return errorResult(newError(domain.ErrCodeQueryTooComplex, fmt.Sprintf("Query complexity %d exceeds the limit of %d", a.complexity, s.maxComplexity)))
+		}
+	}
+
+	r := &request{
+		actor:         caller.Actor,
+		authenticated: caller.Authenticated,
+		requestID:     caller.RequestID,
+	}
+	ctx = context.WithValue(ctx, contextKey{}, r)
+	r.statusHistory = newLoader(s.statusHistoryBatch(ctx))
+
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+}
+
+// statusHistoryBatch loads the status history of many projects in one query
+func (s *Server) statusHistoryBatch(ctx context.Context) batchFunc {
+	return func(ids []int) (map[int]interface{}, error) {
+		history, err := s.projectService.StatusHistory(ids)
+		if err != nil {
+			return nil, resolveError(ctx, err)
+		}
+
+		values := make(map[int]interface{}, len(ids))
+		for _, id := range ids {
+			changes := history[id]
+			if changes == nil {
+				changes = []domain.ProjectStatusChange{}
+			}
+			values[id] = changes
+		}
+		return values, nil
+	}
+}
+
+// errorResult wraps an error rejected before execution in a result
+func errorResult(err *Error) *graphql.Result {
+	return &graphql.Result{
+		Errors: []gqlerrors.FormattedError{{
+			Message:    err.Error(),
+			Extensions: err.Extensions(),
+		}},
+	}
+}
@@ -1,20 +1,29 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
 )
 
+// managedDetail explains why a managed project cannot be edited
+const managedDetail = "This project is defined in the sync manifest; change it there and run portal sync"
+
 type AdminHandler struct {
-	db *sql.DB
+	projectService *service.ProjectService
+	portalService  *service.PortalService
 }
 
 // NewAdminHandler creates a new admin handler
-func NewAdminHandler(db *sql.DB) *AdminHandler {
-	return &AdminHandler{db: db}
+func NewAdminHandler(projectService *service.ProjectService, portalService *service.PortalService) *AdminHandler {
+	return &AdminHandler{
+		projectService: projectService,
+		portalService:  portalService,
+	}
 }
 
 // Dashboard renders the admin dashboard
@@ -24,30 +33,9 @@ func (h *AdminHandler) Dashboard(c *gin.Context) {
 	})
 }
 
-// ListProjects returns all projects
+// ListProjects returns a page of projects
 func (h *AdminHandler) ListProjects(c *gin.Context) {
-	rows, err := h.db.Query(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at FROM projects ORDER BY \"order\" ASC",
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
-		return
-	}
-	defer rows.Close()
-
-	var projects []domain.Project
-	for rows.Next() {
-		var p domain.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, &p.CreatedAt); err != nil {
-			continue
-		}
-		projects = append(projects, p)
-	}
-
-	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Projects retrieved", projects))
+	listProjects(c, h.projectService, false)
 }
 
 // CreateProject creates a new project
@@ -55,24 +43,17 @@ func (h *AdminHandler) CreateProject(c *gin.Context) {
 	var req domain.CreateProjectRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.NewErrorResponse(
-			"Invalid request",
-			err.Error(),
-		))
+		problem.Validation(c, err)
 		return
 	}
 
-	var id int
-	err := h.db.QueryRow(
-		"INSERT INTO projects (name, slug, description, url, icon_url, status) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		req.Name, req.Slug, req.Description, req.URL, req.IconURL, req.Status,
-	).Scan(&id)
+	id, err := h.projectService.CreateProject(actorFromContext(c), &req)
+	if respondSlugError(c, err) {
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
@@ -81,27 +62,19 @@ func (h *AdminHandler) CreateProject(c *gin.Context) {
 
 // GetProject returns a single project
 func (h *AdminHandler) GetProject(c *gin.Context) {
-	id := c.Param("id")
-
-	var project domain.Project
-	err := h.db.QueryRow(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at FROM projects WHERE id = $1",
-		id,
-	).Scan(&project.ID, &project.Name, &project.Slug, &project.Description, &project.URL, &project.IconURL, &project.Status, &project.Order, &project.CreatedAt)
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
 
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, domain.NewErrorResponse(
-			"Not found",
-			"Project not found",
-		))
+	project, err := h.projectService.GetProjectByID(id)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
@@ -110,52 +83,105 @@ func (h *AdminHandler) GetProject(c *gin.Context) {
 
 // UpdateProject updates a project
 func (h *AdminHandler) UpdateProject(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
 	var req domain.UpdateProjectRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.NewErrorResponse(
-			"Invalid request",
-			err.Error(),
-		))
+		problem.Validation(c, err)
+		return
+	}
+
+	err := h.projectService.UpdateProject(actorFromContext(c), id, &req)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+		return
+	}
+
+	if errors.Is(err, service.ErrProjectManaged) {
+		problem.Respond(c, domain.ErrCodeProjectManaged, managedDetail)
 		return
 	}
 
-	_, err := h.db.Exec(
-		"UPDATE projects SET name = COALESCE(NULLIF($1, ''), name), slug = COALESCE(NULLIF($2, ''), slug), description = COALESCE(NULLIF($3, ''), description), url = COALESCE(NULLIF($4, ''), url), icon_url = COALESCE(NULLIF($5, ''), icon_url), status = COALESCE(NULLIF($6, ''), status), updated_at = CURRENT_TIMESTAMP WHERE id = $7",
-		req.Name, req.Slug, req.Description, req.URL, req.IconURL, req.Status, id,
-	)
+	if respondSlugError(c, err) {
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project updated", nil))
 }
 
+// PatchProject applies a merge patch or JSON Patch to a project and returns
+// the result. A null member clears description and icon_url.
+func (h *AdminHandler) PatchProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	p, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	err := h.projectService.PatchProject(actorFromContext(c), id, func(doc *domain.ProjectDocument) error {
+		return applyPatch(p, doc)
+	})
+	if errors.Is(err, service.ErrProjectNotFound) {
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+		return
+	}
+
+	if errors.Is(err, service.ErrProjectManaged) {
+		problem.Respond(c, domain.ErrCodeProjectManaged, managedDetail)
+		return
+	}
+
+	if respondSlugError(c, err) || respondPatchError(c, err) {
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	project, err := h.projectService.GetProjectByID(id)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project updated", project))
+}
+
 // DeleteProject deletes a project
 func (h *AdminHandler) DeleteProject(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
 
-	result, err := h.db.Exec("DELETE FROM projects WHERE id = $1", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+	err := h.projectService.DeleteProject(actorFromContext(c), id)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, domain.NewErrorResponse(
-			"Not found",
-			"Project not found",
-		))
+	if errors.Is(err, service.ErrProjectManaged) {
+		problem.Respond(c, domain.ErrCodeProjectManaged, managedDetail)
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
 		return
 	}
 
@@ -164,31 +190,14 @@ func (h *AdminHandler) DeleteProject(c *gin.Context) {
 
 // GetPortal returns portal configuration
 func (h *AdminHandler) GetPortal(c *gin.Context) {
-	var portal domain.Portal
-
-	err := h.db.QueryRow(
-		"SELECT id, name, description, logo_url, website, email, phone, address FROM portal_config LIMIT 1",
-	).Scan(
-		&portal.ID,
-		&portal.Name,
-		&portal.Description,
-		&portal.LogoURL,
-		&portal.Website,
-		&portal.Email,
-		&portal.Phone,
-		&portal.Address,
-	)
-
-	if err == sql.ErrNoRows {
+	portal, err := h.portalService.GetPortal()
+	if errors.Is(err, service.ErrPortalNotConfigured) {
 		c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Portal not configured", nil))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
@@ -200,44 +209,53 @@ func (h *AdminHandler) UpdatePortal(c *gin.Context) {
 	var req domain.UpdatePortalRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.NewErrorResponse(
-			"Invalid request",
-			err.Error(),
-		))
-		return
-	}
-
-	// Check if portal config exists
-	var id int
-	err := h.db.QueryRow("SELECT id FROM portal_config LIMIT 1").Scan(&id)
-
-	if err == sql.ErrNoRows {
-		// Create new portal config
-		_, err := h.db.Exec(
-			"INSERT INTO portal_config (name, description, logo_url, website, email, phone, address) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-			req.Name, req.Description, req.LogoURL, req.Website, req.Email, req.Phone, req.Address,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-				"Database error",
-				err.Error(),
-			))
-			return
-		}
-	} else {
-		// Update existing portal config
-		_, err := h.db.Exec(
-			"UPDATE portal_config SET name = COALESCE(NULLIF($1, ''), name), description = COALESCE(NULLIF($2, ''), description), logo_url = COALESCE(NULLIF($3, ''), logo_url), website = COALESCE(NULLIF($4, ''), website), email = COALESCE(NULLIF($5, ''), email), phone = COALESCE(NULLIF($6, ''), phone), address = COALESCE(NULLIF($7, ''), address), updated_at = CURRENT_TIMESTAMP WHERE id = $8",
-			req.Name, req.Description, req.LogoURL, req.Website, req.Email, req.Phone, req.Address, id,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-				"Database error",
-				err.Error(),
-			))
+		problem.Validation(c, err)
+		return
+	}
+
+	if err := h.portalService.UpdatePortal(actorFromContext(c), &req); err != nil {
+		if _, ok := problem.FieldErrors(err); ok {
+			problem.Validation(c, err)
 			return
 		}
+		problem.Internal(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Portal updated", nil))
 }
+
+// PatchPortal applies a merge patch or JSON Patch to the portal
+// configuration and returns the result. A null member clears any field but
+// name.
+func (h *AdminHandler) PatchPortal(c *gin.Context) {
+	p, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	err := h.portalService.PatchPortal(actorFromContext(c), func(doc *domain.PortalDocument) error {
+		return applyPatch(p, doc)
+	})
+	if errors.Is(err, service.ErrPortalNotConfigured) {
+		problem.Respond(c, domain.ErrCodePortalNotConfigured, "Create the portal configuration with PUT /admin/portal first")
+		return
+	}
+
+	if respondPatchError(c, err) {
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	portal, err := h.portalService.GetPortal()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Portal updated", portal))
+}
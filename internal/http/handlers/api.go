@@ -1,120 +1,174 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
 )
 
 type APIHandler struct {
-	db *sql.DB
+	projectService *service.ProjectService
+	portalService  *service.PortalService
+	searcher       service.ProjectSearcher
 }
 
 // NewAPIHandler creates a new API handler
-func NewAPIHandler(db *sql.DB) *APIHandler {
-	return &APIHandler{db: db}
-}
-
-// HealthCheck returns the health status of the API
-func (h *APIHandler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, domain.HealthCheckResponse{
-		Status:    "ok",
-		Timestamp: time.Now().Format(time.RFC3339),
-		Version:   "1.0.0",
-		Uptime:    int64(time.Since(time.Now()).Seconds()),
-	})
+func NewAPIHandler(projectService *service.ProjectService, portalService *service.PortalService, searcher service.ProjectSearcher) *APIHandler {
+	return &APIHandler{
+		projectService: projectService,
+		portalService:  portalService,
+		searcher:       searcher,
+	}
 }
 
 // GetPortal returns portal information
 func (h *APIHandler) GetPortal(c *gin.Context) {
-	var portal domain.Portal
-
-	err := h.db.QueryRow(
-		"SELECT id, name, description, logo_url, website, email, phone, address FROM portal_config LIMIT 1",
-	).Scan(
-		&portal.ID,
-		&portal.Name,
-		&portal.Description,
-		&portal.LogoURL,
-		&portal.Website,
-		&portal.Email,
-		&portal.Phone,
-		&portal.Address,
-	)
-
-	if err == sql.ErrNoRows {
+	portal, err := h.portalService.GetPortal()
+	if errors.Is(err, service.ErrPortalNotConfigured) {
 		c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Portal not configured", nil))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
+		return
+	}
+
+	if notModified(c, strongETag("portal", portal.ID, portal.UpdatedAt.UnixNano()), portal.UpdatedAt) {
 		return
 	}
 
 	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Portal retrieved", portal))
 }
 
-// GetProjects returns all active projects
+// GetProjects returns a page of active projects
 func (h *APIHandler) GetProjects(c *gin.Context) {
-	rows, err := h.db.Query(
-		"SELECT id, name, slug, description, url, icon_url, status, created_at FROM projects WHERE status = 'active' ORDER BY \"order\" ASC",
-	)
+	listProjects(c, h.projectService, true)
+}
+
+// GetProject returns a single project by ID
+func (h *APIHandler) GetProject(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.GetProjectByID(id)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+		return
+	}
+
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
+		return
+	}
+
+	if notModified(c, strongETag("project", project.ID, project.UpdatedAt.UnixNano()), project.UpdatedAt) {
 		return
 	}
-	defer rows.Close()
 
-	var projects []domain.Project
-	for rows.Next() {
-		var p domain.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.CreatedAt); err != nil {
-			continue
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project retrieved", project))
+}
+
+// GetProjectBySlug returns a single project by slug. Old slugs are
+// permanently redirected to the project's current one.
+func (h *APIHandler) GetProjectBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+
+	project, err := h.projectService.GetProjectBySlug(slug)
+	if errors.Is(err, service.ErrProjectNotFound) {
+		current, err := h.projectService.CurrentSlug(slug)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+			return
 		}
-		projects = append(projects, p)
+
+		if err != nil {
+			problem.Internal(c, err)
+			return
+		}
+
+		redirectSlug(c, slug, current)
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Projects retrieved", gin.H{
-		"data":  projects,
-		"total": len(projects),
+	if notModified(c, strongETag("project", project.ID, project.UpdatedAt.UnixNano()), project.UpdatedAt) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project retrieved", project))
+}
+
+// Search returns active projects ranked by relevance to the q parameter
+func (h *APIHandler) Search(c *gin.Context) {
+	var req domain.SearchRequest
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	results, err := h.searcher.Search(req.Query, req.Limit)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Search results retrieved", gin.H{
+		"data":  results,
+		"total": len(results),
 	}))
 }
 
-// GetProject returns a single project by ID
-func (h *APIHandler) GetProject(c *gin.Context) {
-	id := c.Param("id")
+// listProjects binds the project list query parameters and writes a
+// paginated response, shared by the public and admin list endpoints
+func listProjects(c *gin.Context, projectService *service.ProjectService, activeOnly bool) {
+	var filter domain.ProjectFilter
+
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	// Answer revalidations from the cheap table version before loading the
+	// page
+	version, lastModified, err := projectService.ListVersion()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
 
-	var project domain.Project
-	err := h.db.QueryRow(
-		"SELECT id, name, slug, description, url, icon_url, status, created_at FROM projects WHERE id = $1",
-		id,
-	).Scan(&project.ID, &project.Name, &project.Slug, &project.Description, &project.URL, &project.IconURL, &project.Status, &project.CreatedAt)
+	etag := strongETag("projects", activeOnly, c.Request.URL.RawQuery, version)
+	if notModified(c, etag, lastModified) {
+		return
+	}
 
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, domain.NewErrorResponse(
-			"Not found",
-			"Project not found",
-		))
+	projects, err := projectService.ListProjects(&filter, activeOnly)
+	if errors.Is(err, service.ErrInvalidSort) {
+		problem.Respond(c, domain.ErrCodeInvalidSort, "sort must be one of name, order, created_at, updated_at, optionally prefixed with -")
+		return
+	}
+
+	if errors.Is(err, service.ErrInvalidCursor) {
+		problem.Respond(c, domain.ErrCodeInvalidCursor, "")
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project retrieved", project))
+	setPaginationLinks(c, projects)
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Projects retrieved", projects))
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListAuditLogs returns a page of audit log entries
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	var filter domain.AuditLogFilter
+
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	logs, err := h.auditService.ListAuditLogs(&filter)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		problem.Respond(c, domain.ErrCodeInvalidCursor, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	setPaginationLinks(c, logs)
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Audit logs retrieved", logs))
+}
+
+// ExportAuditLogs streams audit log entries as CSV or NDJSON
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	var filter domain.AuditLogFilter
+
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		problem.Respond(c, domain.ErrCodeInvalidFormat, "format must be csv or ndjson")
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var err error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		err = h.exportCSV(c, &filter)
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		err = h.exportNDJSON(c, &filter)
+	}
+
+	// Headers are already sent once streaming starts, so failures can only
+	// be logged and the response cut short
+	if err != nil {
+		log.Printf("Audit log export failed: %v", err)
+	}
+}
+
+// VerifyAuditLogs walks the audit hash chain and reports the first broken link
+func (h *AuditHandler) VerifyAuditLogs(c *gin.Context) {
+	result, err := h.auditService.VerifyChain()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	message := "Audit chain intact"
+	if !result.Valid {
+		message = "Audit chain broken"
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, message, result))
+}
+
+// exportCSV writes the matching audit entries as CSV rows
+func (h *AuditHandler) exportCSV(c *gin.Context, filter *domain.AuditLogFilter) error {
+	w := csv.NewWriter(c.Writer)
+
+	header := []string{"id", "created_at", "user_id", "action", "resource", "resource_id", "ip_address", "user_agent", "details", "prev_hash", "hash"}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	err := h.auditService.ExportAuditLogs(filter, func(entry domain.AuditLog) error {
+		return w.Write([]string{
+			strconv.Itoa(entry.ID),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			optionalInt(entry.UserID),
+			entry.Action,
+			entry.Resource,
+			optionalInt(entry.ResourceID),
+			entry.IPAddress,
+			entry.UserAgent,
+			entry.Details,
+			entry.PrevHash,
+			entry.Hash,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// exportNDJSON writes the matching audit entries as one JSON object per line
+func (h *AuditHandler) exportNDJSON(c *gin.Context, filter *domain.AuditLogFilter) error {
+	enc := json.NewEncoder(c.Writer)
+
+	return h.auditService.ExportAuditLogs(filter, func(entry domain.AuditLog) error {
+		return enc.Encode(entry)
+	})
+}
+
+// optionalInt formats a nullable integer, using an empty string for NULL
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
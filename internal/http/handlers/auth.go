@@ -1,26 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
-	"github.com/kanyaarss/kanyaars-portal/pkg/jwt"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
 )
 
 type AuthHandler struct {
-	db        *sql.DB
-	jwtSecret string
+	authService *service.AuthService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *sql.DB, jwtSecret string) *AuthHandler {
-	return &AuthHandler{
-		db:        db,
-		jwtSecret: jwtSecret,
-	}
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+	return &AuthHandler{authService: authService}
 }
 
 // Login handles admin login
@@ -28,59 +24,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var req domain.UserLoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, domain.NewErrorResponse(
-			"Invalid request",
-			err.Error(),
-		))
-		return
-	}
-
-	// Find user by email
-	var user domain.User
-	err := h.db.QueryRow(
-		"SELECT id, email, name, password, role FROM users WHERE email = $1 AND is_active = true",
-		req.Email,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusUnauthorized, domain.NewErrorResponse(
-			"Login failed",
-			"Invalid email or password",
-		))
-		return
-	}
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Database error",
-			err.Error(),
-		))
+		problem.Validation(c, err)
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, domain.NewErrorResponse(
-			"Login failed",
-			"Invalid email or password",
-		))
+	user, token, err := h.authService.Login(actorFromContext(c), req.Email, req.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		problem.Respond(c, domain.ErrCodeAuthInvalidLogin, "")
 		return
 	}
 
-	// Generate JWT token
-	token, err := jwt.GenerateToken(user.ID, user.Email, h.jwtSecret, 86400)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-			"Token generation failed",
-			err.Error(),
-		))
+		problem.Internal(c, err)
 		return
 	}
 
 	// Return response
 	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Login successful", domain.UserLoginResponse{
 		Token:     token,
-		ExpiresIn: 86400,
+		ExpiresIn: h.authService.TokenExpiry(),
 		User: &domain.UserInfo{
 			ID:    user.ID,
 			Email: user.Email,
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// strongETag derives a strong entity tag from the values that determine a
+// representation
+func strongETag(parts ...interface{}) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x00", part)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// notModified sets the ETag and Last-Modified validators and answers 304 when
+// the request's preconditions show the client copy is current. If-None-Match
+// takes precedence over If-Modified-Since (RFC 9110 section 13.2.2).
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	fresh := false
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		fresh = etagMatches(inm, etag)
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		// HTTP dates have second precision
+		fresh = err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	if fresh {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+
+	return fresh
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. The weak
+// comparison is used, as required for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+	"github.com/kanyaarss/kanyaars-portal/pkg/webhook"
+)
+
+// maxDeploymentEventSize bounds the size of a deployment event body
+const maxDeploymentEventSize = 64 << 10
+
+type DeploymentHandler struct {
+	deploymentService *service.DeploymentService
+}
+
+// NewDeploymentHandler creates a new deployment handler
+func NewDeploymentHandler(deploymentService *service.DeploymentService) *DeploymentHandler {
+	return &DeploymentHandler{deploymentService: deploymentService}
+}
+
+// RecordEvent stores a deployment event posted by CI. The body must be
+// signed with the deployments secret in the X-Portal-Signature header.
+func (h *DeploymentHandler) RecordEvent(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxDeploymentEventSize))
+	if err != nil {
+		problem.Respond(c, domain.ErrCodeMalformedRequest, err.Error())
+		return
+	}
+
+	if respondDeploymentError(c, h.deploymentService.Verify(c.GetHeader(webhook.SignatureHeader), body)) {
+		return
+	}
+
+	var event domain.DeploymentEvent
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err := c.ShouldBindJSON(&event); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	deployment, err := h.deploymentService.Record(&event)
+	if respondDeploymentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Deployment recorded", deployment))
+}
+
+// ListDeployments returns a page of a project's deployments, newest first
+func (h *DeploymentHandler) ListDeployments(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var filter domain.DeploymentFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	deployments, err := h.deploymentService.ListDeployments(id, &filter)
+	if respondDeploymentError(c, err) {
+		return
+	}
+
+	setPaginationLinks(c, deployments)
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Deployments retrieved", deployments))
+}
+
+// respondDeploymentError writes the problem response for err and reports
+// whether there was one
+func respondDeploymentError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrDeploymentsDisabled):
+		problem.Respond(c, domain.ErrCodeDeploymentsDisabled, "")
+	case errors.Is(err, service.ErrDeploymentSignature):
+		problem.Respond(c, domain.ErrCodeDeploymentSignature, "")
+	case errors.Is(err, service.ErrDeploymentProject):
+		problem.Respond(c, domain.ErrCodeDeploymentProject, "")
+	case errors.Is(err, service.ErrProjectNotFound):
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+	default:
+		problem.Internal(c, err)
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+type DocsHandler struct {
+	document map[string]interface{}
+}
+
+// NewDocsHandler creates a docs handler serving the given OpenAPI document
+func NewDocsHandler(document map[string]interface{}) *DocsHandler {
+	return &DocsHandler{document: document}
+}
+
+// OpenAPI returns the OpenAPI document as JSON
+func (h *DocsHandler) OpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.document)
+}
+
+// Errors returns the catalog of error codes referenced by problem types
+func (h *DocsHandler) Errors(c *gin.Context) {
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Error catalog retrieved", domain.ErrorCatalog))
+}
+
+// Docs renders the bundled API documentation viewer
+func (h *DocsHandler) Docs(c *gin.Context) {
+	c.HTML(http.StatusOK, "docs.html", gin.H{
+		"title":   "API Documentation",
+		"specURL": "/api/v1/openapi.json",
+	})
+}
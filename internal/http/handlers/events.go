@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// streamedEvents are the event types sent to /api/v1/events clients
+var streamedEvents = map[string]bool{
+	domain.EventProjectCreated:       true,
+	domain.EventProjectUpdated:       true,
+	domain.EventProjectStatusChanged: true,
+	domain.EventProjectDeleted:       true,
+}
+
+// reconnectDelay is the retry interval suggested to EventSource clients
+const reconnectDelay = 3 * time.Second
+
+type EventsHandler struct {
+	broker *service.EventBroker
+}
+
+// NewEventsHandler creates a new events handler
+func NewEventsHandler(broker *service.EventBroker) *EventsHandler {
+	return &EventsHandler{broker: broker}
+}
+
+// Stream sends project events as Server-Sent Events until the client
+// disconnects. Clients resuming with Last-Event-ID first receive the events
+// they missed; a "reset" event tells them some were no longer buffered and
+// they should reload instead.
+func (h *EventsHandler) Stream(c *gin.Context) {
+	last, _ := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+
+	replay, complete, events, cancel := h.broker.Subscribe(last)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		writeEvent(w, event)
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(h.broker.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				return
+			}
+			writeEvent(w, event)
+			w.Flush()
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			w.Flush()
+		}
+	}
+}
+
+// writeEvent writes a streamed event in SSE framing, with its position as
+// the SSE ID; other types are skipped
+func writeEvent(w gin.ResponseWriter, event domain.Event) {
+	if !streamedEvents[event.Type] {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Position, event.Type, data)
+}
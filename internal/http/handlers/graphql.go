@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/gql"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+)
+
+type GraphQLHandler struct {
+	server *gql.Server
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(server *gql.Server) *GraphQLHandler {
+	return &GraphQLHandler{server: server}
+}
+
+// Query executes a GraphQL request. GET requests take the query from the
+// query string and may not run mutations.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req gql.Request
+
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			problem.Validation(c, err)
+			return
+		}
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				problem.Validation(c, err)
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	_, authenticated := c.Get("user_id")
+	result := h.server.Execute(c.Request.Context(), &req, gql.Caller{
+		Actor:         actorFromContext(c),
+		Authenticated: authenticated,
+		RequestID:     c.GetString(problem.RequestIDKey),
+	}, c.Request.Method == http.MethodPost)
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/buildinfo"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/health"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Livez reports that the process is up and serving, without touching any
+// dependency, so that a database outage does not get the process restarted
+func (h *HealthHandler) Livez(c *gin.Context) {
+	respondHealth(c, http.StatusOK, report(domain.HealthOK, nil))
+}
+
+// Readyz runs the dependency checks. It responds 503 while a critical
+// dependency is down and 200 when the service is ok or degraded.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	status, checks := h.checker.Run(c.Request.Context())
+
+	code := http.StatusOK
+	if status == domain.HealthDown {
+		code = http.StatusServiceUnavailable
+	}
+
+	respondHealth(c, code, report(status, checks))
+}
+
+// report describes this build and process with the given status
+func report(status string, checks map[string]domain.DependencyHealth) domain.HealthCheckResponse {
+	return domain.HealthCheckResponse{
+		Status:    status,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Version:   buildinfo.Version,
+		Commit:    buildinfo.Commit,
+		BuildTime: buildinfo.BuildTime,
+		Uptime:    int64(buildinfo.Uptime().Seconds()),
+		Checks:    checks,
+	}
+}
+
+func respondHealth(c *gin.Context, code int, body domain.HealthCheckResponse) {
+	// Probes must always reach the process, never a cache
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, body)
+}
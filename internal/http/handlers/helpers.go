@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// actorFromContext builds the audit actor for the current request from the
+// user stored by the auth middleware and the client connection details
+func actorFromContext(c *gin.Context) domain.Actor {
+	return domain.Actor{
+		UserID:    c.GetInt("user_id"),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// projectIDParam parses the :id route parameter, writing a problem response
+// when it is not a valid integer
+func projectIDParam(c *gin.Context) (int, bool) {
+	return idParam(c, "id")
+}
+
+// idParam parses the named integer route parameter, writing a problem
+// response when it is not a valid integer
+func idParam(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		problem.Respond(c, domain.ErrCodeInvalidID, "")
+		return 0, false
+	}
+
+	return id, true
+}
+
+// respondSlugError writes the problem response for a rejected project slug
+// and reports whether err was one
+func respondSlugError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrSlugTaken):
+		problem.Respond(c, domain.ErrCodeProjectSlugTaken, "")
+	case errors.Is(err, service.ErrSlugReserved):
+		problem.Respond(c, domain.ErrCodeProjectSlugReserved, fmt.Sprintf("Reserved slugs: %s", strings.Join(service.ReservedSlugs, ", ")))
+	case errors.Is(err, service.ErrSlugInvalid):
+		problem.Respond(c, domain.ErrCodeProjectSlugInvalid, "slug may only contain lower case letters, digits and single hyphens")
+	default:
+		return false
+	}
+	return true
+}
+
+// redirectSlug permanently redirects a request for a project's old slug to
+// the same route under its current slug, keeping the query string
+func redirectSlug(c *gin.Context, oldSlug, slug string) {
+	path := strings.TrimSuffix(c.Request.URL.Path, oldSlug) + url.PathEscape(slug)
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+
+	c.Redirect(http.StatusMovedPermanently, path)
+}
+
+// setPaginationLinks sets RFC 8288 Link headers pointing at the neighbouring
+// pages of a paginated response. Cursor pages only link to the next page.
+func setPaginationLinks(c *gin.Context, page *domain.PaginatedResponse) {
+	link := func(rel string, set map[string]string) string {
+		u := *c.Request.URL
+		q := u.Query()
+		q.Del("page")
+		q.Del("cursor")
+		for key, value := range set {
+			q.Set(key, value)
+		}
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel)
+	}
+
+	var links []string
+	if page.Page == 0 {
+		if page.NextCursor != "" {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor}))
+		}
+	} else {
+		links = append(links, link("first", map[string]string{"page": "1"}))
+		if page.Page > 1 {
+			links = append(links, link("prev", map[string]string{"page": strconv.Itoa(page.Page - 1)}))
+		}
+		if page.Page < page.TotalPages {
+			links = append(links, link("next", map[string]string{"page": strconv.Itoa(page.Page + 1)}))
+		}
+		if page.TotalPages > 0 {
+			links = append(links, link("last", map[string]string{"page": strconv.Itoa(page.TotalPages)}))
+		}
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type IncidentHandler struct {
+	incidentService *service.IncidentService
+}
+
+// NewIncidentHandler creates a new incident handler
+func NewIncidentHandler(incidentService *service.IncidentService) *IncidentHandler {
+	return &IncidentHandler{incidentService: incidentService}
+}
+
+// Status returns the current state of every project with active and
+// recently resolved incidents
+func (h *IncidentHandler) Status(c *gin.Context) {
+	status, err := h.incidentService.Status()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Status retrieved", status))
+}
+
+// ListIncidents returns incidents newest first
+func (h *IncidentHandler) ListIncidents(c *gin.Context) {
+	var filter domain.IncidentFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	incidents, err := h.incidentService.ListIncidents(filter)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Incidents retrieved", incidents))
+}
+
+// CreateIncident opens an incident
+func (h *IncidentHandler) CreateIncident(c *gin.Context) {
+	var req domain.CreateIncidentRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	incident, err := h.incidentService.CreateIncident(actorFromContext(c), &req)
+	if respondIncidentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.NewAPIResponse(true, "Incident created", incident))
+}
+
+// GetIncident returns a single incident with its updates
+func (h *IncidentHandler) GetIncident(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	incident, err := h.incidentService.GetIncident(id)
+	if respondIncidentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Incident retrieved", incident))
+}
+
+// UpdateIncident changes an incident's title, severity or projects
+func (h *IncidentHandler) UpdateIncident(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateIncidentRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	incident, err := h.incidentService.UpdateIncident(actorFromContext(c), id, &req)
+	if respondIncidentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Incident updated", incident))
+}
+
+// PostUpdate adds an update to an incident and moves it to its state
+func (h *IncidentHandler) PostUpdate(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.CreateIncidentUpdateRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	incident, err := h.incidentService.PostUpdate(actorFromContext(c), id, &req)
+	if respondIncidentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.NewAPIResponse(true, "Incident update posted", incident))
+}
+
+// DeleteIncident deletes an incident and its updates
+func (h *IncidentHandler) DeleteIncident(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := h.incidentService.DeleteIncident(actorFromContext(c), id)
+	if respondIncidentError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Incident deleted", nil))
+}
+
+// respondIncidentError writes the problem response for err and reports
+// whether there was one
+func respondIncidentError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrIncidentNotFound):
+		problem.Respond(c, domain.ErrCodeIncidentNotFound, "")
+	case errors.Is(err, service.ErrIncidentProject):
+		problem.Respond(c, domain.ErrCodeIncidentProject, "")
+	default:
+		problem.Internal(c, err)
+	}
+	return true
+}
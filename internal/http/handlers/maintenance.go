@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type MaintenanceHandler struct {
+	maintenanceService *service.MaintenanceService
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenanceService *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceService: maintenanceService}
+}
+
+// ListUpcoming returns the scheduled and running windows of all projects,
+// soonest first
+func (h *MaintenanceHandler) ListUpcoming(c *gin.Context) {
+	windows, err := h.maintenanceService.ListUpcoming(0)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Maintenance windows retrieved", windows))
+}
+
+// ListWindows returns a project's maintenance windows
+func (h *MaintenanceHandler) ListWindows(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var filter domain.MaintenanceFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	windows, err := h.maintenanceService.ListWindows(id, filter)
+	if respondMaintenanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Maintenance windows retrieved", windows))
+}
+
+// CreateWindow schedules a maintenance window for a project
+func (h *MaintenanceHandler) CreateWindow(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req domain.CreateMaintenanceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	window, err := h.maintenanceService.CreateWindow(actorFromContext(c), id, &req)
+	if respondMaintenanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.NewAPIResponse(true, "Maintenance window created", window))
+}
+
+// UpdateWindow reschedules a maintenance window or changes its message
+func (h *MaintenanceHandler) UpdateWindow(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	id, ok := idParam(c, "window_id")
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateMaintenanceRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	window, err := h.maintenanceService.UpdateWindow(actorFromContext(c), projectID, id, &req)
+	if respondMaintenanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Maintenance window updated", window))
+}
+
+// DeleteWindow deletes a maintenance window, ending it first if it runs
+func (h *MaintenanceHandler) DeleteWindow(c *gin.Context) {
+	projectID, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	id, ok := idParam(c, "window_id")
+	if !ok {
+		return
+	}
+
+	err := h.maintenanceService.DeleteWindow(actorFromContext(c), projectID, id)
+	if respondMaintenanceError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Maintenance window deleted", nil))
+}
+
+// respondMaintenanceError writes the problem response for err and reports
+// whether there was one
+func respondMaintenanceError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrProjectNotFound):
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+	case errors.Is(err, service.ErrMaintenanceNotFound):
+		problem.Respond(c, domain.ErrCodeMaintenanceNotFound, "")
+	case errors.Is(err, service.ErrMaintenanceInvalid):
+		problem.Respond(c, domain.ErrCodeMaintenanceInvalid, "")
+	case errors.Is(err, service.ErrMaintenanceDone):
+		problem.Respond(c, domain.ErrCodeMaintenanceDone, "")
+	default:
+		problem.Internal(c, err)
+	}
+	return true
+}
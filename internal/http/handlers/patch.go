@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/patch"
+)
+
+// readPatch parses the request body as a merge patch or JSON Patch by its
+// content type, writing a problem response when it is neither
+func readPatch(c *gin.Context) (patch.Patch, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem.Respond(c, domain.ErrCodeMalformedRequest, err.Error())
+		return nil, false
+	}
+
+	p, err := patch.Parse(c.GetHeader("Content-Type"), body)
+	if respondPatchError(c, err) {
+		return nil, false
+	}
+
+	return p, true
+}
+
+// applyPatch applies p to doc, a pointer to a document struct. Members the
+// document does not have are rejected and only the fields p changes are
+// validated, so stored values are never re-checked.
+func applyPatch(p patch.Patch, doc interface{}) error {
+	current, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	patched, err := p.Apply(current)
+	if err != nil {
+		return err
+	}
+
+	before := reflect.ValueOf(doc).Elem()
+	if err := checkMembers(patched, before.Type()); err != nil {
+		return err
+	}
+
+	after := reflect.New(before.Type())
+	if err := json.Unmarshal(patched, after.Interface()); err != nil {
+		return err
+	}
+
+	var changed []string
+	for i := 0; i < before.NumField(); i++ {
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Elem().Field(i).Interface()) {
+			changed = append(changed, before.Type().Field(i).Name)
+		}
+	}
+
+	if len(changed) > 0 {
+		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			if err := v.StructPartial(after.Interface(), changed...); err != nil {
+				return err
+			}
+		}
+	}
+
+	before.Set(after.Elem())
+	return nil
+}
+
+// unknownMembersError lists patched members that are not editable fields
+type unknownMembersError []string
+
+func (e unknownMembersError) Error() string {
+	return "unknown fields: " + strings.Join(e, ", ")
+}
+
+// checkMembers rejects members of the patched object that t has no json
+// field for, such as id or created_at
+func checkMembers(patched []byte, t reflect.Type) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &members); err != nil {
+		return &patch.Error{Reason: "the patched resource must be a JSON object"}
+	}
+
+	known := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		known[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+
+	var unknown unknownMembersError
+	for name := range members {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return unknown
+	}
+
+	return nil
+}
+
+// respondPatchError writes the problem response for a patch that could not
+// be read, applied or validated and reports whether err was one
+func respondPatchError(c *gin.Context, err error) bool {
+	var (
+		invalid *patch.Error
+		unknown unknownMembersError
+	)
+
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, patch.ErrUnsupportedType):
+		problem.Respond(c, domain.ErrCodeUnsupportedMedia, "Send a "+patch.MergePatchType+" or "+patch.JSONPatchType+" body")
+	case errors.Is(err, patch.ErrConflict):
+		problem.Respond(c, domain.ErrCodePatchConflict, err.Error())
+	case errors.As(err, &invalid):
+		problem.Respond(c, domain.ErrCodeMalformedRequest, invalid.Reason)
+	case errors.As(err, &unknown):
+		p := domain.NewProblem(domain.ErrCodeValidationFailed, "One or more fields are invalid")
+		for _, name := range unknown {
+			p.Errors = append(p.Errors, domain.FieldError{Field: name, Rule: "unknown", Message: "is not an editable field"})
+		}
+		problem.Write(c, p)
+	default:
+		if _, ok := problem.FieldErrors(err); !ok {
+			return false
+		}
+		problem.Validation(c, err)
+	}
+
+	return true
+}
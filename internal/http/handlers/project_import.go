@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/projectfile"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// maxImportSize bounds the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// ExportProjects downloads every project as CSV, JSON or YAML in the format
+// accepted by ImportProjects
+func (h *AdminHandler) ExportProjects(c *gin.Context) {
+	format := c.DefaultQuery("format", projectfile.FormatJSON)
+	if format != projectfile.FormatCSV && format != projectfile.FormatJSON && format != projectfile.FormatYAML {
+		problem.Respond(c, domain.ErrCodeInvalidFormat, "format must be csv, json or yaml")
+		return
+	}
+
+	projects, err := h.projectService.GetAllProjects()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("projects-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", projectfile.ContentType(format))
+	c.Status(http.StatusOK)
+
+	if err := projectfile.Write(c.Writer, format, projects); err != nil {
+		log.Printf("Project export failed: %v", err)
+	}
+}
+
+// ImportProjects creates or updates projects from an uploaded CSV, JSON or
+// YAML file. The format defaults to the request Content-Type. Nothing is
+// applied unless every row is valid; with dry_run the plan is returned
+// without applying it.
+func (h *AdminHandler) ImportProjects(c *gin.Context) {
+	var opts domain.ProjectImportOptions
+
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	if opts.Format == "" {
+		opts.Format = projectfile.FormatFromContentType(c.ContentType())
+	}
+	if opts.Format == "" {
+		problem.Respond(c, domain.ErrCodeInvalidFormat, "Set format to csv, json or yaml, or send a matching Content-Type")
+		return
+	}
+
+	rows, err := projectfile.Read(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize), opts.Format)
+	if err != nil {
+		problem.Respond(c, domain.ErrCodeMalformedRequest, err.Error())
+		return
+	}
+
+	result, err := h.projectService.ImportProjects(actorFromContext(c), rows, opts.Mode, opts.DryRun)
+	if errors.Is(err, service.ErrImportInvalid) {
+		p := domain.NewProblem(domain.ErrCodeImportInvalid, fmt.Sprintf("%d of %d rows are invalid; nothing was imported", result.Invalid, len(result.Rows)))
+		p.Errors = importErrors(result)
+		problem.Write(c, p)
+		return
+	}
+
+	if errors.Is(err, service.ErrProjectManaged) {
+		problem.Respond(c, domain.ErrCodeProjectManaged, managedDetail)
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	message := "Projects imported"
+	if opts.DryRun {
+		message = "Import checked"
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, message, result))
+}
+
+// importErrors lists the errors of every invalid row, prefixing each field
+// with its row number
+func importErrors(result *domain.ProjectImportResult) []domain.FieldError {
+	var fields []domain.FieldError
+	for _, row := range result.Rows {
+		for _, fe := range row.Errors {
+			prefix := fmt.Sprintf("rows[%d]", row.Row)
+			if fe.Field == "" {
+				fe.Field = prefix
+			} else {
+				fe.Field = prefix + "." + fe.Field
+			}
+			fields = append(fields, fe)
+		}
+	}
+	return fields
+}
@@ -1,115 +1,170 @@
 package handlers
 
 import (
-	"database/sql"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/cache"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
 )
 
+// templatePattern matches the page templates
+const templatePattern = "web/templates/*.html"
+
 type PublicHandler struct {
-	db *sql.DB
+	projectService     *service.ProjectService
+	incidentService    *service.IncidentService
+	maintenanceService *service.MaintenanceService
+	deploymentService  *service.DeploymentService
+	pages              *cache.Loader
+	templates          *template.Template
+	templateVersion    string
+}
+
+// NewPublicHandler creates a new public handler; rendered pages are cached
+// in pages unless it is nil
+func NewPublicHandler(projectService *service.ProjectService, incidentService *service.IncidentService, maintenanceService *service.MaintenanceService, deploymentService *service.DeploymentService, pages *cache.Loader) *PublicHandler {
+	return &PublicHandler{
+		projectService:     projectService,
+		incidentService:    incidentService,
+		maintenanceService: maintenanceService,
+		deploymentService:  deploymentService,
+		pages:              pages,
+		templates:          template.Must(template.ParseGlob(templatePattern)),
+		templateVersion:    templateVersion(templatePattern),
+	}
 }
 
-// NewPublicHandler creates a new public handler
-func NewPublicHandler(db *sql.DB) *PublicHandler {
-	return &PublicHandler{db: db}
+// templateVersion hashes the page templates so that page ETags change on
+// deploys that alter markup, and agree across replicas running the same build
+func templateVersion(pattern string) string {
+	files, _ := filepath.Glob(pattern)
+	h := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // Home renders the home page
 func (h *PublicHandler) Home(c *gin.Context) {
+	if notModified(c, strongETag("page:home", h.templateVersion), time.Time{}) {
+		return
+	}
+
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"title": "Kanyaars Portal",
 	})
 }
 
-// Projects renders the projects page
+// Projects renders the projects page, with a banner on projects that are
+// under or scheduled for maintenance
 func (h *PublicHandler) Projects(c *gin.Context) {
-	rows, err := h.db.Query(`
-		SELECT id, name, slug, description, url, icon_url, status
-		FROM projects
-		WHERE status = 'active'
-		ORDER BY "order" ASC
-	`)
+	var p page
+	err := h.pages.Fetch(cache.KeyProjectsPage, &p, func() error {
+		projects, err := h.projectService.GetListedProjects()
+		if err != nil {
+			return err
+		}
+
+		windows, err := h.maintenanceService.NextWindows()
+		if err != nil {
+			return err
+		}
+
+		items := make([]gin.H, 0, len(projects))
+		for _, project := range projects {
+			item := projectView(&project)
+			if w, ok := windows[project.ID]; ok {
+				item["maintenance"] = maintenanceView(&w)
+			}
+			items = append(items, item)
+		}
+
+		return h.render(&p, "projects.html", gin.H{
+			"title":    "Projects",
+			"projects": items,
+		}, time.Time{})
+	})
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Failed to fetch projects",
 		})
 		return
 	}
-	defer rows.Close()
-
-	projects := []map[string]interface{}{}
-
-	for rows.Next() {
-		var (
-			id          int
-			name        string
-			slug        string
-			description string
-			url         string
-			iconURL     string
-			status      string
-		)
-
-		if err := rows.Scan(
-			&id,
-			&name,
-			&slug,
-			&description,
-			&url,
-			&iconURL,
-			&status,
-		); err != nil {
-			continue
-		}
-
-		projects = append(projects, gin.H{
-			"id":          id,
-			"name":        name,
-			"slug":        slug,
-			"description": description,
-			"url":         url,
-			"icon_url":    iconURL,
-			"status":      status,
-		})
-	}
 
-	c.HTML(http.StatusOK, "projects.html", gin.H{
-		"title":    "Projects",
-		"projects": projects,
-	})
+	h.serve(c, &p)
 }
 
 // ProjectDetail renders the project detail page
 func (h *PublicHandler) ProjectDetail(c *gin.Context) {
 	slug := c.Param("slug")
 
-	var (
-		id          int
-		name        string
-		slugDB      string
-		description string
-		url         string
-		iconURL     string
-		status      string
-	)
-
-	err := h.db.QueryRow(`
-		SELECT id, name, slug, description, url, icon_url, status
-		FROM projects
-		WHERE slug = $1
-	`, slug).Scan(
-		&id,
-		&name,
-		&slugDB,
-		&description,
-		&url,
-		&iconURL,
-		&status,
-	)
-
-	if err == sql.ErrNoRows {
+	var p page
+	err := h.pages.Fetch(cache.ProjectPageKey(slug), &p, func() error {
+		project, err := h.projectService.GetProjectBySlug(slug)
+		if err != nil {
+			return err
+		}
+
+		view := projectView(project)
+		window, err := h.maintenanceService.NextWindow(project.ID)
+		if err != nil {
+			return err
+		}
+		if window != nil {
+			view["maintenance"] = maintenanceView(window)
+		}
+
+		deployments, err := h.deploymentService.CurrentDeployments(project.ID)
+		if err != nil {
+			return err
+		}
+
+		lastModified := project.UpdatedAt
+		current := make([]gin.H, 0, len(deployments))
+		for i := range deployments {
+			current = append(current, deploymentView(&deployments[i]))
+			if deployments[i].UpdatedAt.After(lastModified) {
+				lastModified = deployments[i].UpdatedAt
+			}
+		}
+		view["deployments"] = current
+
+		return h.render(&p, "project-detail.html", gin.H{
+			"title":   "Project Detail",
+			"project": view,
+		}, lastModified)
+	})
+
+	if errors.Is(err, service.ErrProjectNotFound) {
+		// Follow renamed projects to their current slug
+		current, err := h.projectService.CurrentSlug(slug)
+		if err == nil {
+			redirectSlug(c, slug, current)
+			return
+		}
+
+		if !errors.Is(err, service.ErrProjectNotFound) {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error": "Failed to fetch project",
+			})
+			return
+		}
+
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Project not found",
 		})
@@ -123,18 +178,82 @@ func (h *PublicHandler) ProjectDetail(c *gin.Context) {
 		return
 	}
 
-	project := gin.H{
-		"id":          id,
-		"name":        name,
-		"slug":        slugDB,
-		"description": description,
-		"url":         url,
-		"icon_url":    iconURL,
-		"status":      status,
+	h.serve(c, &p)
+}
+
+// page is a rendered page as cached
+type page struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// render executes the named template into p. The ETag is derived from the
+// markup, so it changes exactly when the page does.
+func (h *PublicHandler) render(p *page, name string, data gin.H, lastModified time.Time) error {
+	var buf bytes.Buffer
+	if err := h.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
 	}
 
-	c.HTML(http.StatusOK, "project-detail.html", gin.H{
-		"title":   "Project Detail",
-		"project": project,
-	})
-}
\ No newline at end of file
+	p.Body = buf.Bytes()
+	p.ETag = strongETag("page", p.Body)
+	p.LastModified = lastModified
+	return nil
+}
+
+// serve sends a rendered page, or 304 when the client copy is current
+func (h *PublicHandler) serve(c *gin.Context, p *page) {
+	if notModified(c, p.ETag, p.LastModified) {
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", p.Body)
+}
+
+// projectView is the project as the page templates read it
+func projectView(p *domain.Project) gin.H {
+	return gin.H{
+		"id":          p.ID,
+		"name":        p.Name,
+		"slug":        p.Slug,
+		"description": p.Description,
+		"url":         p.URL,
+		"icon_url":    p.IconURL,
+		"status":      p.Status,
+	}
+}
+
+// deploymentView is a current deployment as the project page lists it;
+// commits are shortened to their usual 7 characters
+func deploymentView(d *domain.Deployment) gin.H {
+	commit := d.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+
+	view := gin.H{
+		"environment": d.Environment,
+		"version":     d.Version,
+		"commit":      commit,
+		"url":         d.URL,
+		"deployedAt":  d.UpdatedAt.UTC().Format(statusTimeFormat),
+	}
+	if d.FinishedAt != nil {
+		view["deployedAt"] = d.FinishedAt.UTC().Format(statusTimeFormat)
+	}
+
+	return view
+}
+
+// maintenanceView is the maintenance window as the banner reads it; endsAt
+// is the ETA of a running window
+func maintenanceView(w *domain.MaintenanceWindow) gin.H {
+	return gin.H{
+		"state":      w.State,
+		"inProgress": w.State == domain.MaintenanceInProgress,
+		"message":    w.Message,
+		"startsAt":   w.StartsAt.UTC().Format(statusTimeFormat),
+		"endsAt":     w.EndsAt.UTC().Format(statusTimeFormat),
+	}
+}
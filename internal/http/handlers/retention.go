@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService *service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService}
+}
+
+// ListRuns returns the most recent retention run summaries
+func (h *RetentionHandler) ListRuns(c *gin.Context) {
+	runs, err := h.retentionService.ListRuns(100)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Retention runs retrieved", runs))
+}
+
+// RunNow applies every retention policy immediately
+func (h *RetentionHandler) RunNow(c *gin.Context) {
+	runs := h.retentionService.RunAll()
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Retention applied", runs))
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// feedLimit is the number of incident updates in the status feed
+const feedLimit = 50
+
+// statusTimeFormat is how the status page shows times
+const statusTimeFormat = "02 Jan 2006 15:04 MST"
+
+// Status renders the public status page
+func (h *PublicHandler) Status(c *gin.Context) {
+	status, err := h.incidentService.Status()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": "Failed to fetch status",
+		})
+		return
+	}
+
+	var active, past []gin.H
+	for i := range status.Incidents {
+		incident := &status.Incidents[i]
+		if incident.State == domain.IncidentResolved {
+			past = append(past, incidentView(incident))
+		} else {
+			active = append(active, incidentView(incident))
+		}
+	}
+
+	var p page
+	err = h.render(&p, "status.html", gin.H{
+		"title":     "Status",
+		"status":    status.Status,
+		"label":     statusLabel(status.Status),
+		"projects":  status.Projects,
+		"active":    active,
+		"past":      past,
+		"updatedAt": status.UpdatedAt.UTC().Format(statusTimeFormat),
+	}, time.Time{})
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": "Failed to render status",
+		})
+		return
+	}
+
+	h.serve(c, &p)
+}
+
+// StatusFeed serves incident updates as an Atom feed, newest first
+func (h *PublicHandler) StatusFeed(c *gin.Context) {
+	items, err := h.incidentService.RecentUpdates(feedLimit)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to fetch incident updates")
+		return
+	}
+
+	base := baseURL(c)
+	feed := atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		ID:      base + "/status",
+		Title:   "Kanyaars Portal Status",
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "Kanyaars Portal"},
+		Links: []atomLink{
+			{Href: base + "/status/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/status", Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for i, item := range items {
+		updated := item.CreatedAt.UTC().Format(time.RFC3339)
+		if i == 0 {
+			feed.Updated = updated
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:       fmt.Sprintf("%s/status#incident-%d-update-%d", base, item.IncidentID, item.ID),
+			Title:    fmt.Sprintf("[%s] %s", incidentStateLabel(item.State), item.Title),
+			Updated:  updated,
+			Link:     atomLink{Href: fmt.Sprintf("%s/status#incident-%d", base, item.IncidentID), Rel: "alternate", Type: "text/html"},
+			Category: atomCategory{Term: item.Severity},
+			Content:  atomContent{Type: "text", Body: item.Message},
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Failed to encode feed")
+		return
+	}
+	body = append([]byte(xml.Header), body...)
+
+	if notModified(c, strongETag("feed", body), time.Time{}) {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", body)
+}
+
+// atomFeed is an RFC 4287 Atom feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Link     atomLink     `xml:"link"`
+	Category atomCategory `xml:"category"`
+	Content  atomContent  `xml:"content"`
+}
+
+// atomAuthor is required on the feed since entries carry no author of their own
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// baseURL is the scheme and host the request was made to, as seen by the
+// client when behind a TLS-terminating proxy
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// incidentView is the incident as the status page reads it
+func incidentView(i *domain.Incident) gin.H {
+	updates := make([]gin.H, 0, len(i.Updates))
+	for _, u := range i.Updates {
+		updates = append(updates, gin.H{
+			"state":     u.State,
+			"label":     incidentStateLabel(u.State),
+			"message":   u.Message,
+			"createdAt": u.CreatedAt.UTC().Format(statusTimeFormat),
+		})
+	}
+
+	view := gin.H{
+		"id":        i.ID,
+		"title":     i.Title,
+		"severity":  i.Severity,
+		"state":     i.State,
+		"label":     incidentStateLabel(i.State),
+		"projects":  i.Projects,
+		"updates":   updates,
+		"startedAt": i.StartedAt.UTC().Format(statusTimeFormat),
+	}
+	if i.ResolvedAt != nil {
+		view["resolvedAt"] = i.ResolvedAt.UTC().Format(statusTimeFormat)
+	}
+
+	return view
+}
+
+// statusLabel is the headline shown for an overall status
+func statusLabel(status string) string {
+	switch status {
+	case domain.StatusOperational:
+		return "All systems operational"
+	case domain.StatusMaintenance:
+		return "Scheduled maintenance in progress"
+	case domain.StatusDegraded:
+		return "Degraded performance"
+	case domain.StatusPartialOutage:
+		return "Partial outage"
+	default:
+		return "Major outage"
+	}
+}
+
+// incidentStateLabel capitalises an incident state for display
+func incidentStateLabel(state string) string {
+	if state == "" {
+		return ""
+	}
+	return strings.ToUpper(state[:1]) + state[1:]
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type UptimeHandler struct {
+	uptimeService *service.UptimeService
+}
+
+// NewUptimeHandler creates a new uptime handler
+func NewUptimeHandler(uptimeService *service.UptimeService) *UptimeHandler {
+	return &UptimeHandler{uptimeService: uptimeService}
+}
+
+// GetHealth returns the current uptime state of a project
+func (h *UptimeHandler) GetHealth(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	health, err := h.uptimeService.GetHealth(id)
+	if respondUptimeError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project health retrieved", health))
+}
+
+// ListChecks returns a project's uptime check history, newest first
+func (h *UptimeHandler) ListChecks(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var filter domain.ProjectCheckFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	checks, err := h.uptimeService.ListChecks(id, filter)
+	if respondUptimeError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project checks retrieved", checks))
+}
+
+// GetMonitor returns the uptime check configuration of a project
+func (h *UptimeHandler) GetMonitor(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	monitor, err := h.uptimeService.GetMonitor(id)
+	if respondUptimeError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project monitor retrieved", monitor))
+}
+
+// UpdateMonitor changes the uptime check configuration of a project
+func (h *UptimeHandler) UpdateMonitor(c *gin.Context) {
+	id, ok := projectIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateProjectMonitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	monitor, err := h.uptimeService.UpdateMonitor(actorFromContext(c), id, &req)
+	if respondUptimeError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Project monitor updated", monitor))
+}
+
+// respondUptimeError writes the problem response for err and reports
+// whether there was one
+func respondUptimeError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrProjectNotFound):
+		problem.Respond(c, domain.ErrCodeProjectNotFound, "")
+	default:
+		problem.Internal(c, err)
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+// ListWebhooks returns all webhook subscriptions
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks()
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Webhooks retrieved", webhooks))
+}
+
+// CreateWebhook creates a webhook and returns its signing secret
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req domain.CreateWebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	created, err := h.webhookService.CreateWebhook(actorFromContext(c), &req)
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, domain.NewAPIResponse(true, "Webhook created", created))
+}
+
+// GetWebhook returns a single webhook
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.GetWebhook(id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		problem.Respond(c, domain.ErrCodeWebhookNotFound, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Webhook retrieved", webhook))
+}
+
+// UpdateWebhook updates a webhook
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateWebhookRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Validation(c, err)
+		return
+	}
+
+	err := h.webhookService.UpdateWebhook(actorFromContext(c), id, &req)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		problem.Respond(c, domain.ErrCodeWebhookNotFound, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Webhook updated", nil))
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := h.webhookService.DeleteWebhook(actorFromContext(c), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		problem.Respond(c, domain.ErrCodeWebhookNotFound, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Webhook deleted", nil))
+}
+
+// ListDeliveries returns the most recent deliveries of a webhook
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(id, 100)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		problem.Respond(c, domain.ErrCodeWebhookNotFound, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, domain.NewAPIResponse(true, "Webhook deliveries retrieved", deliveries))
+}
+
+// Redeliver queues the event of a previous delivery again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveryID, ok := idParam(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(actorFromContext(c), id, deliveryID)
+	if errors.Is(err, service.ErrDeliveryNotFound) {
+		problem.Respond(c, domain.ErrCodeDeliveryNotFound, "")
+		return
+	}
+
+	if err != nil {
+		problem.Internal(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, domain.NewAPIResponse(true, "Redelivery queued", delivery))
+}
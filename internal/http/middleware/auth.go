@@ -1,55 +1,77 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
 	"github.com/kanyaarss/kanyaars-portal/pkg/jwt"
 )
 
 // Auth returns a middleware that validates JWT tokens
 func Auth(jwtSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, domain.NewErrorResponse(
-				"Unauthorized",
-				"Missing authorization header",
-			))
-			c.Abort()
+		if c.GetHeader("Authorization") == "" {
+			problem.Respond(c, domain.ErrCodeAuthTokenMissing, "")
 			return
 		}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, domain.NewErrorResponse(
-				"Unauthorized",
-				"Invalid authorization header format",
-			))
-			c.Abort()
+		authenticate(c, jwtSecret)
+	}
+}
+
+// OptionalAuth returns a middleware that validates a JWT token when one is
+// sent and lets anonymous requests through. Handlers check for "user_id".
+func OptionalAuth(jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
 
-		token := parts[1]
+		authenticate(c, jwtSecret)
+	}
+}
 
-		// Validate token
-		claims, err := jwt.ValidateToken(token, jwtSecret)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, domain.NewErrorResponse(
-				"Unauthorized",
-				"Invalid or expired token",
-			))
-			c.Abort()
-			return
-		}
+// authenticate validates the bearer token and stores the user in the
+// context, aborting with a problem response when it is unusable
+func authenticate(c *gin.Context, jwtSecret string) {
+	claims, p := BearerClaims(c.GetHeader("Authorization"), jwtSecret)
+	if p != nil {
+		problem.Write(c, p)
+		return
+	}
 
-		// Store user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
+	// Store user info in context
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
 
-		c.Next()
+	c.Next()
+}
+
+// BearerClaims validates an "Authorization: Bearer <token>" value and
+// returns its claims, or the problem describing why it is unusable. The
+// gRPC server authenticates calls with it as well.
+func BearerClaims(header, jwtSecret string) (*jwt.Claims, *domain.Problem) {
+	// Extract token from "Bearer <token>"
+	parts := strings.Split(header, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, domain.NewProblem(domain.ErrCodeAuthTokenMalformed, "Expected \"Bearer <token>\"")
+	}
+
+	token := parts[1]
+
+	// Validate token
+	claims, err := jwt.ValidateToken(token, jwtSecret)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, domain.NewProblem(domain.ErrCodeAuthTokenExpired, "Log in again to obtain a new token")
+	}
+
+	if err != nil {
+		return nil, domain.NewProblem(domain.ErrCodeAuthTokenInvalid, "")
 	}
+
+	return claims, nil
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+)
+
+// CacheControl returns a middleware that applies a Cache-Control policy to
+// GET and HEAD responses. Handlers may still override the header, as error
+// responses do.
+func CacheControl(policy config.CachePolicy) gin.HandlerFunc {
+	value := cacheControlValue(policy)
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Header("Cache-Control", value)
+		}
+
+		c.Next()
+	}
+}
+
+// NoStore returns a middleware that forbids caching of every response
+func NoStore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Next()
+	}
+}
+
+// cacheControlValue renders a policy as a Cache-Control header value.
+// Validators are always revalidated once max-age has passed.
+func cacheControlValue(policy config.CachePolicy) string {
+	if policy.NoStore {
+		return "no-store"
+	}
+
+	directives := []string{"public"}
+	if policy.Private {
+		directives[0] = "private"
+	}
+	directives = append(directives, fmt.Sprintf("max-age=%d", int(policy.MaxAge.Seconds())))
+	if policy.StaleWhileRevalidate > 0 {
+		directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", int(policy.StaleWhileRevalidate.Seconds())))
+	}
+
+	return strings.Join(directives, ", ")
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GRPC returns a middleware that hands HTTP/2 gRPC requests to h, so that
+// the gRPC server can share the HTTP port. The engine must serve h2c.
+func GRPC(h http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ProtoMajor != 2 || !strings.HasPrefix(c.GetHeader("Content-Type"), "application/grpc") {
+			c.Next()
+			return
+		}
+
+		// gRPC paths match no route; drop the pending 404 as grpc relies on
+		// the implicit 200 of the first write
+		c.Status(http.StatusOK)
+		h.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
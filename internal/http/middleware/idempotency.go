@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// IdempotencyKeyHeader carries the client-chosen key of a retryable request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKey is the longest accepted key
+const maxIdempotencyKey = 255
+
+// maxIdempotentBody bounds the request bodies buffered for hashing, matching
+// the largest admin upload
+const maxIdempotentBody = 10 << 20
+
+// Idempotency returns a middleware that runs POST and PATCH requests sent
+// with an Idempotency-Key header at most once per user and key. Retries
+// with the same method, URL and body get the stored response with an
+// Idempotent-Replayed header; other requests reusing the key get 422.
+// Server errors are not stored, so those requests may be retried. It must
+// run after Auth.
+func Idempotency(idempotency *service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKey {
+			problem.Respond(c, domain.ErrCodeIdempotencyInvalid, fmt.Sprintf("Keys are at most %d characters", maxIdempotencyKey))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			problem.Respond(c, domain.ErrCodeMalformedRequest, err.Error())
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetInt("user_id")
+		claim, stored, err := idempotency.Begin(userID, key, requestHash(c, body))
+		switch {
+		case errors.Is(err, service.ErrIdempotencyReused):
+			problem.Respond(c, domain.ErrCodeIdempotencyReused, "Use a new key for a different request")
+			return
+		case errors.Is(err, service.ErrIdempotencyPending):
+			problem.Respond(c, domain.ErrCodeIdempotencyPending, "Retry once the original request has finished")
+			return
+		case err != nil:
+			problem.Internal(c, err)
+			return
+		case stored != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.Status, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Hold the key however long the request takes
+		stopKeepAlive := idempotency.KeepAlive(claim)
+
+		completed := false
+		defer func() {
+			stopKeepAlive()
+
+			// Panics and server errors leave the key free for a retry
+			if completed {
+				return
+			}
+			if err := idempotency.Release(claim); err != nil {
+				log.Printf("[%s] idempotency key release: %v", c.GetString(problem.RequestIDKey), err)
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		err = idempotency.Complete(claim, &domain.IdempotentResponse{
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			log.Printf("[%s] idempotency key complete: %v", c.GetString(problem.RequestIDKey), err)
+			return
+		}
+		completed = true
+	}
+}
+
+// requestHash identifies a request by method, URL, content type and body
+func requestHash(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\x00%s\x00", c.Request.Method, c.Request.URL.RequestURI(), c.ContentType())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
 )
 
 // Logger returns a middleware that logs HTTP requests
@@ -20,7 +21,8 @@ func Logger() gin.HandlerFunc {
 
 		// Log request details
 		log.Printf(
-			"[%s] %s %s - Status: %d - Duration: %v",
+			"[%s] %s %s %s - Status: %d - Duration: %v",
+			c.GetString(problem.RequestIDKey),
 			c.Request.Method,
 			c.Request.RequestURI,
 			c.ClientIP(),
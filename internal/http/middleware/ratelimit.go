@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/ratelimit"
+)
+
+// APIKeyHeader identifies clients of policies limited by API key
+const APIKeyHeader = "X-API-Key"
+
+// rateLimitRemainingKey holds the tokens left in the bucket whose
+// RateLimit-* headers were sent, when several limiters apply to a route
+const rateLimitRemainingKey = "rate_limit_remaining"
+
+// RateLimit returns a middleware that throttles the requests of each
+// identity with policy, keeping buckets in store under name; keys are the
+// API keys policies by api_key accept. It sends the
+// RateLimit-* headers and answers 429 with Retry-After when the bucket is
+// empty. When several limiters apply to a route, the headers describe the
+// bucket with the fewest tokens left. A nil store or a policy without
+// requests disables it; store errors let the request through.
+func RateLimit(store ratelimit.Store, keys ratelimit.KeySet, name string, cfg config.RateLimitPolicy) gin.HandlerFunc {
+	policy := ratelimit.NewPolicy(cfg)
+	policyHeader := fmt.Sprintf("%d;w=%d;burst=%d", policy.Requests, int(policy.Period.Seconds()), policy.Burst)
+
+	return func(c *gin.Context) {
+		if store == nil || !policy.Enabled() {
+			c.Next()
+			return
+		}
+
+		result, err := store.Take(c.Request.Context(), name+":"+identity(c, cfg.By, keys), policy)
+		if err != nil {
+			log.Printf("[%s] rate limit %s: %v", c.GetString(problem.RequestIDKey), name, err)
+			c.Next()
+			return
+		}
+
+		if remaining, ok := c.Get(rateLimitRemainingKey); !ok || !result.Allowed || result.Remaining < remaining.(int) {
+			c.Set(rateLimitRemainingKey, result.Remaining)
+			c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			c.Header("RateLimit-Policy", policyHeader)
+		}
+
+		if !result.Allowed {
+			retry := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(retry))
+			problem.Respond(c, domain.ErrCodeRateLimited, fmt.Sprintf("Too many requests; retry in %d seconds", retry))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// identity returns the bucket key of the client. Policies by user or API
+// key count anonymous requests and requests without an issued key by IP.
+func identity(c *gin.Context, by string, keys ratelimit.KeySet) string {
+	switch by {
+	case ratelimit.ByUser:
+		if userID, ok := c.Get("user_id"); ok {
+			return fmt.Sprintf("user:%v", userID)
+		}
+	case ratelimit.ByAPIKey:
+		if id, ok := keys.Identify(c.GetHeader(APIKeyHeader)); ok {
+			return id
+		}
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds rounds d up to whole seconds, the unit of the headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/ratelimit"
+)
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sum := sha256.Sum256([]byte("issued-key"))
+	keys, err := ratelimit.NewKeySet([]string{hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string // X-API-Key of each request, all from one IP
+		want    []int
+	}{
+		{
+			name:    "no key counts by IP",
+			headers: []string{"", "", ""},
+			want:    []int{200, 200, 429},
+		},
+		{
+			name:    "rotating unknown keys share the IP bucket",
+			headers: []string{"forged-1", "forged-2", "forged-3"},
+			want:    []int{200, 200, 429},
+		},
+		{
+			name:    "issued key has its own bucket",
+			headers: []string{"forged-1", "forged-2", "issued-key", "issued-key", "issued-key"},
+			want:    []int{200, 200, 200, 200, 429},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := config.RateLimitPolicy{Requests: 2, Period: time.Hour, By: ratelimit.ByAPIKey}
+
+			router := gin.New()
+			router.Use(RateLimit(ratelimit.NewMemoryStore(), keys, "api", policy))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i, key := range tt.headers {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				if key != "" {
+					req.Header.Set(APIKeyHeader, key)
+				}
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != tt.want[i] {
+					t.Errorf("request %d (key %q) = %d, want %d", i, key, w.Code, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitNestedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		api, login int // burst of each limiter
+		requests   int
+		wantCode   int
+		wantHeader string // RateLimit-Policy of the last response
+		wantLeft   string // RateLimit-Remaining of the last response
+	}{
+		{name: "inner has fewer tokens", api: 10, login: 3, requests: 1, wantCode: 200, wantHeader: "3;w=3600;burst=3", wantLeft: "2"},
+		{name: "outer has fewer tokens", api: 3, login: 10, requests: 1, wantCode: 200, wantHeader: "3;w=3600;burst=3", wantLeft: "2"},
+		{name: "outer exhausted", api: 2, login: 10, requests: 3, wantCode: 429, wantHeader: "2;w=3600;burst=2", wantLeft: "0"},
+		{name: "inner exhausted", api: 10, login: 2, requests: 3, wantCode: 429, wantHeader: "2;w=3600;burst=2", wantLeft: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := ratelimit.NewMemoryStore()
+			policy := func(burst int) config.RateLimitPolicy {
+				return config.RateLimitPolicy{Requests: burst, Period: time.Hour, By: ratelimit.ByIP}
+			}
+
+			router := gin.New()
+			router.Use(RateLimit(store, nil, "api", policy(tt.api)))
+			router.POST("/login", RateLimit(store, nil, "login", policy(tt.login)), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+			}
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("RateLimit-Policy"); got != tt.wantHeader {
+				t.Errorf("RateLimit-Policy = %q, want %q", got, tt.wantHeader)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != tt.wantLeft {
+				t.Errorf("RateLimit-Remaining = %q, want %q", got, tt.wantLeft)
+			}
+		})
+	}
+}
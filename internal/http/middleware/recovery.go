@@ -2,11 +2,11 @@ package middleware
 
 import (
 	"log"
-	"net/http"
 	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
 )
 
 // Recovery returns a middleware that recovers from panics
@@ -15,16 +15,13 @@ func Recovery() gin.HandlerFunc {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf(
-					"🔥 PANIC: %v\n%s",
+					"🔥 PANIC [%s]: %v\n%s",
+					c.GetString(problem.RequestIDKey),
 					err,
 					debug.Stack(),
 				)
 
-				c.JSON(http.StatusInternalServerError, domain.NewErrorResponse(
-					"Internal Server Error",
-					"An unexpected error occurred",
-				))
-				c.Abort()
+				problem.Respond(c, domain.ErrCodeInternal, "An unexpected error occurred")
 			}
 		}()
 
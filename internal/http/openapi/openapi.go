@@ -0,0 +1,366 @@
+// Package openapi builds the OpenAPI 3.1 description of the portal API from
+// the documented operations and the domain request/response types.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/patch"
+)
+
+// Operation documents a single route. Path uses gin syntax (":id").
+type Operation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+
+	// Auth marks routes behind middleware.Auth
+	Auth bool
+
+	// Query is a struct whose form-tagged fields are query parameters
+	Query interface{}
+	// Params lists extra parameters not covered by Query
+	Params []Param
+	// Body is the JSON request body type
+	Body interface{}
+	// BodyContentTypes lists other media types accepted for the same body
+	BodyContentTypes []string
+	// Patch is the document type a PATCH route applies merge patches and
+	// JSON Patches to
+	Patch interface{}
+
+	// Status is the success status code (200 if unset)
+	Status int
+	// Data is the type of APIResponse.Data
+	Data interface{}
+	// Paginated wraps Data items in domain.PaginatedResponse
+	Paginated bool
+	// Raw responds with Data itself instead of an APIResponse envelope
+	Raw bool
+	// ContentTypes lists non-JSON success media types (e.g. text/html)
+	ContentTypes []string
+
+	// Errors lists operation-specific error codes in addition to those
+	// implied by the request shape
+	Errors []domain.ErrorCode
+}
+
+// Param documents an extra parameter
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Enum        []string
+}
+
+// CreatedID is the payload returned by create operations
+type CreatedID struct {
+	ID int `json:"id"`
+}
+
+// problemContentType is the media type of error responses
+const problemContentType = "application/problem+json"
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// OpenAPIPath converts a gin route path into OpenAPI template syntax
+func OpenAPIPath(path string) string {
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+// Document builds the OpenAPI document for the given operations
+func Document(version string, operations []Operation) map[string]interface{} {
+	g := newSchemaGenerator()
+	g.schemaFor(domain.APIResponse{})
+
+	paths := map[string]interface{}{}
+	tags := map[string]bool{}
+
+	for _, op := range operations {
+		path := OpenAPIPath(op.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = g.operation(op)
+		tags[op.Tag] = true
+	}
+
+	tagList := []interface{}{}
+	tagNames := make([]string, 0, len(tags))
+	for name := range tags {
+		tagNames = append(tagNames, name)
+	}
+	sort.Strings(tagNames)
+	for _, name := range tagNames {
+		tagList = append(tagList, map[string]interface{}{"name": name})
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "Kanyaars Portal API",
+			"version":     version,
+			"description": "Public API (/api/v1) and admin API (/admin) of the Kanyaars Cloud Portal.",
+		},
+		"tags":  tagList,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+}
+
+// operation builds the OpenAPI operation object for op
+func (g *schemaGenerator) operation(op Operation) map[string]interface{} {
+	o := map[string]interface{}{
+		"tags":        []interface{}{op.Tag},
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+
+	var params []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		schema := map[string]interface{}{"type": "string"}
+		if match[1] == "id" || strings.HasSuffix(match[1], "_id") {
+			schema = map[string]interface{}{"type": "integer"}
+		}
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+	if op.Query != nil {
+		params = append(params, g.queryParameters(op.Query)...)
+	}
+	for _, p := range op.Params {
+		schema := map[string]interface{}{"type": "string"}
+		if len(p.Enum) > 0 {
+			enum := make([]interface{}, len(p.Enum))
+			for i, v := range p.Enum {
+				enum[i] = v
+			}
+			schema["enum"] = enum
+		}
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		params = append(params, map[string]interface{}{
+			"name":        p.Name,
+			"in":          in,
+			"description": p.Description,
+			"required":    p.Required || in == "path",
+			"schema":      schema,
+		})
+	}
+	if idempotent(op) {
+		params = append(params, map[string]interface{}{
+			"name":        "Idempotency-Key",
+			"in":          "header",
+			"description": "Run the request at most once; retries with the same key and body replay the stored response",
+			"required":    false,
+			"schema":      map[string]interface{}{"type": "string", "maxLength": 255},
+		})
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+
+	if op.Body != nil {
+		content := map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": g.schemaFor(op.Body)},
+		}
+		for _, ct := range op.BodyContentTypes {
+			content[ct] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	if op.Patch != nil {
+		// Every member of a merge patch is optional
+		doc := g.structSchema(reflect.TypeOf(op.Patch))
+		delete(doc, "required")
+		o["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				patch.MergePatchType: map[string]interface{}{"schema": doc},
+				patch.JSONPatchType:  map[string]interface{}{"schema": g.schemaFor(patch.Operations{})},
+			},
+		}
+	}
+
+	if op.Auth {
+		o["security"] = []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}}}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	responses := map[string]interface{}{
+		statusKey(status): g.successResponse(op),
+	}
+
+	for code, response := range g.errorResponses(op, len(params) > 0) {
+		responses[code] = response
+	}
+	o["responses"] = responses
+
+	return o
+}
+
+// idempotent reports whether op accepts an Idempotency-Key header, as
+// admin POST and PATCH routes do
+func idempotent(op Operation) bool {
+	return op.Auth && strings.HasPrefix(op.Path, "/admin") && (op.Method == http.MethodPost || op.Method == http.MethodPatch)
+}
+
+// errorResponses describes the problem responses of op, grouped by status
+func (g *schemaGenerator) errorResponses(op Operation, hasParams bool) map[string]interface{} {
+	var codes []domain.ErrorCode
+	if op.Body != nil || op.Patch != nil || hasParams {
+		codes = append(codes, domain.ErrCodeValidationFailed, domain.ErrCodeMalformedRequest)
+	}
+	if op.Patch != nil {
+		codes = append(codes, domain.ErrCodeUnsupportedMedia, domain.ErrCodePatchConflict)
+	}
+	if op.Auth {
+		codes = append(codes, domain.ErrCodeAuthTokenMissing, domain.ErrCodeAuthTokenMalformed, domain.ErrCodeAuthTokenInvalid, domain.ErrCodeAuthTokenExpired)
+	}
+	if strings.Contains(op.Path, ":id") {
+		codes = append(codes, domain.ErrCodeInvalidID)
+	}
+	if idempotent(op) {
+		codes = append(codes, domain.ErrCodeIdempotencyInvalid, domain.ErrCodeIdempotencyReused, domain.ErrCodeIdempotencyPending)
+	}
+	codes = append(codes, op.Errors...)
+	codes = append(codes, domain.ErrCodeRateLimited, domain.ErrCodeInternal)
+
+	byStatus := map[int][]string{}
+	var statuses []int
+	for _, code := range codes {
+		def, ok := domain.LookupError(code)
+		if !ok {
+			continue
+		}
+		if _, seen := byStatus[def.Status]; !seen {
+			statuses = append(statuses, def.Status)
+		}
+		byStatus[def.Status] = append(byStatus[def.Status], string(def.Code))
+	}
+
+	responses := map[string]interface{}{}
+	for _, status := range statuses {
+		responses[statusKey(status)] = map[string]interface{}{
+			"description": strings.Join(byStatus[status], ", "),
+			"content": map[string]interface{}{
+				problemContentType: map[string]interface{}{"schema": g.schemaFor(domain.Problem{})},
+			},
+		}
+	}
+
+	return responses
+}
+
+// successResponse describes the success body of op
+func (g *schemaGenerator) successResponse(op Operation) map[string]interface{} {
+	content := map[string]interface{}{}
+
+	for _, ct := range op.ContentTypes {
+		content[ct] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+
+	if op.Data != nil || len(op.ContentTypes) == 0 {
+		var schema map[string]interface{}
+		switch {
+		case op.Raw:
+			schema = g.schemaFor(op.Data)
+		case op.Paginated:
+			schema = envelope(g.paginated(op.Data))
+		case op.Data != nil:
+			schema = envelope(g.schemaFor(op.Data))
+		default:
+			schema = g.schemaFor(domain.APIResponse{})
+		}
+		content["application/json"] = map[string]interface{}{"schema": schema}
+	}
+
+	return map[string]interface{}{
+		"description": op.Summary,
+		"content":     content,
+	}
+}
+
+// paginated describes a domain.PaginatedResponse holding items of v's type
+func (g *schemaGenerator) paginated(v interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"allOf": []interface{}{
+			g.schemaFor(domain.PaginatedResponse{}),
+			map[string]interface{}{
+				"properties": map[string]interface{}{
+					"data": map[string]interface{}{"type": "array", "items": g.schemaFor(v)},
+				},
+			},
+		},
+	}
+}
+
+// envelope describes a domain.APIResponse whose data has the given schema
+func envelope(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"allOf": []interface{}{
+			map[string]interface{}{"$ref": "#/components/schemas/APIResponse"},
+			map[string]interface{}{
+				"properties": map[string]interface{}{"data": data},
+			},
+		},
+	}
+}
+
+// operationID derives a stable identifier such as getAdminProjectsById
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+
+	for _, segment := range strings.Split(op.Path, "/") {
+		if segment == "" || segment == "api" || segment == "v1" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") {
+			segment = "by-" + segment[1:]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+
+	return b.String()
+}
+
+// statusKey formats a status code as an OpenAPI responses key
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,386 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/gql"
+)
+
+// SearchResults is the payload of the search endpoint
+type SearchResults struct {
+	Data  []domain.ProjectSearchResult `json:"data"`
+	Total int                          `json:"total"`
+}
+
+// GraphQLResult is a GraphQL response; failures are reported in errors
+// with a catalogued code in their extensions
+type GraphQLResult struct {
+	Data   map[string]interface{}   `json:"data,omitempty"`
+	Errors []map[string]interface{} `json:"errors,omitempty"`
+}
+
+// Operations documents every route under /api and /admin. The router
+// test fails when a route is registered without an entry here.
+var Operations = []Operation{
+	// Public API
+	{
+		Method: http.MethodGet, Path: "/api/v1/health", Tag: "System",
+		Summary: "Readiness check with build info and dependency status; 503 while a critical dependency is down",
+		Data:    domain.HealthCheckResponse{}, Raw: true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/openapi.json", Tag: "System",
+		Summary: "OpenAPI document",
+		Data:    map[string]interface{}{}, Raw: true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/docs", Tag: "System",
+		Summary:      "API documentation viewer",
+		ContentTypes: []string{"text/html"},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/errors", Tag: "System",
+		Summary: "Error code catalog",
+		Data:    []domain.ErrorDefinition{},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/auth/login", Tag: "Auth",
+		Summary: "Log in and obtain a JWT",
+		Body:    domain.UserLoginRequest{},
+		Data:    domain.UserLoginResponse{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeAuthInvalidLogin},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/portal", Tag: "Portal",
+		Summary: "Get portal information",
+		Data:    domain.Portal{},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects", Tag: "Projects",
+		Summary: "List active projects",
+		Query:   domain.ProjectFilter{},
+		Data:    domain.Project{}, Paginated: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeInvalidSort, domain.ErrCodeInvalidCursor},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/:id", Tag: "Projects",
+		Summary: "Get an active project",
+		Data:    domain.Project{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/:id/health", Tag: "Uptime",
+		Summary: "Current uptime status with 24-hour availability and latency",
+		Data:    domain.ProjectHealth{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/:id/checks", Tag: "Uptime",
+		Summary: "Uptime check history, newest first",
+		Query:   domain.ProjectCheckFilter{},
+		Data:    []domain.ProjectCheck{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/:id/maintenance", Tag: "Maintenance",
+		Summary: "A project's scheduled and running maintenance windows, or its full history",
+		Query:   domain.MaintenanceFilter{},
+		Data:    []domain.MaintenanceWindow{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/:id/deployments", Tag: "Deployments",
+		Summary: "A project's deployments, newest first",
+		Query:   domain.DeploymentFilter{},
+		Data:    domain.Deployment{}, Paginated: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/projects/by-slug/:slug", Tag: "Projects",
+		Summary: "Get a project by slug; old slugs redirect with 301 to the current one",
+		Data:    domain.Project{},
+		Errors:  []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/search", Tag: "Projects",
+		Summary: "Full-text search over active projects",
+		Query:   domain.SearchRequest{},
+		Data:    SearchResults{},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/status", Tag: "Status",
+		Summary: "Current state of every project with active and recently resolved incidents",
+		Data:    domain.StatusPage{},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/maintenance", Tag: "Maintenance",
+		Summary: "Scheduled and running maintenance windows of all projects, soonest first",
+		Data:    []domain.MaintenanceWindow{},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/deployments", Tag: "Deployments",
+		Summary: "Record a deployment event from CI, signed with the deployments secret",
+		Params: []Param{
+			{Name: "X-Portal-Signature", In: "header", Description: "t=<unix>,v1=<HMAC-SHA256 of \"<unix>.<body>\">", Required: true},
+		},
+		Body: domain.DeploymentEvent{},
+		Data: domain.Deployment{},
+		Errors: []domain.ErrorCode{
+			domain.ErrCodeDeploymentsDisabled, domain.ErrCodeDeploymentSignature, domain.ErrCodeDeploymentProject,
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/events", Tag: "Projects",
+		Summary: "Stream project events as Server-Sent Events",
+		Params: []Param{
+			{Name: "Last-Event-ID", In: "header", Description: "Resume after this event ID"},
+		},
+		ContentTypes: []string{"text/event-stream"},
+	},
+
+	// GraphQL
+	{
+		Method: http.MethodGet, Path: "/api/graphql", Tag: "GraphQL",
+		Summary: "Run a GraphQL query (mutations need POST)",
+		Query:   gql.Request{},
+		Params:  []Param{{Name: "variables", Description: "JSON-encoded variables"}},
+		Data:    GraphQLResult{}, Raw: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeAuthTokenMalformed, domain.ErrCodeAuthTokenInvalid, domain.ErrCodeAuthTokenExpired},
+	},
+	{
+		Method: http.MethodPost, Path: "/api/graphql", Tag: "GraphQL",
+		Summary: "Run a GraphQL query or mutation; mutations need a bearer token",
+		Body:    gql.Request{},
+		Data:    GraphQLResult{}, Raw: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeAuthTokenMalformed, domain.ErrCodeAuthTokenInvalid, domain.ErrCodeAuthTokenExpired},
+	},
+
+	// Admin API
+	{
+		Method: http.MethodGet, Path: "/admin/", Tag: "Admin",
+		Summary: "Admin dashboard", Auth: true,
+		ContentTypes: []string{"text/html"},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/projects", Tag: "Admin Projects",
+		Summary: "List projects", Auth: true,
+		Query: domain.ProjectFilter{},
+		Data:  domain.Project{}, Paginated: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeInvalidSort, domain.ErrCodeInvalidCursor},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/projects", Tag: "Admin Projects",
+		Summary: "Create a project", Auth: true,
+		Body:   domain.CreateProjectRequest{},
+		Status: http.StatusCreated, Data: CreatedID{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectSlugTaken, domain.ErrCodeProjectSlugInvalid, domain.ErrCodeProjectSlugReserved},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/projects/export", Tag: "Admin Projects",
+		Summary: "Export all projects", Auth: true,
+		Params: []Param{
+			{Name: "format", Description: "Export format (default json)", Enum: []string{"csv", "json", "yaml"}},
+		},
+		Data: []domain.CreateProjectRequest{}, Raw: true,
+		Errors:       []domain.ErrorCode{domain.ErrCodeInvalidFormat},
+		ContentTypes: []string{"text/csv", "application/yaml"},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/projects/import", Tag: "Admin Projects",
+		Summary: "Import projects from CSV, JSON or YAML", Auth: true,
+		Query:            domain.ProjectImportOptions{},
+		Body:             []domain.CreateProjectRequest{},
+		BodyContentTypes: []string{"text/csv", "application/yaml"},
+		Data:             domain.ProjectImportResult{},
+		Errors:           []domain.ErrorCode{domain.ErrCodeInvalidFormat, domain.ErrCodeImportInvalid},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/projects/:id", Tag: "Admin Projects",
+		Summary: "Get a project", Auth: true,
+		Data:   domain.Project{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/projects/:id", Tag: "Admin Projects",
+		Summary: "Update a project", Auth: true,
+		Body:   domain.UpdateProjectRequest{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound, domain.ErrCodeProjectSlugTaken, domain.ErrCodeProjectSlugInvalid, domain.ErrCodeProjectSlugReserved, domain.ErrCodeProjectManaged},
+	},
+	{
+		Method: http.MethodPatch, Path: "/admin/projects/:id", Tag: "Admin Projects",
+		Summary: "Partially update a project with a merge patch or JSON Patch", Auth: true,
+		Patch:  domain.ProjectDocument{},
+		Data:   domain.Project{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound, domain.ErrCodeProjectSlugTaken, domain.ErrCodeProjectSlugInvalid, domain.ErrCodeProjectSlugReserved, domain.ErrCodeProjectManaged},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/projects/:id", Tag: "Admin Projects",
+		Summary: "Delete a project", Auth: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound, domain.ErrCodeProjectManaged},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/projects/:id/monitor", Tag: "Uptime",
+		Summary: "Get a project's uptime check configuration", Auth: true,
+		Data:   domain.ProjectMonitor{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/projects/:id/monitor", Tag: "Uptime",
+		Summary: "Update a project's uptime check configuration", Auth: true,
+		Body:   domain.UpdateProjectMonitorRequest{},
+		Data:   domain.ProjectMonitor{},
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/projects/:id/maintenance", Tag: "Maintenance",
+		Summary: "Schedule a maintenance window; the project switches to maintenance while it runs", Auth: true,
+		Body: domain.CreateMaintenanceRequest{},
+		Data: domain.MaintenanceWindow{}, Status: http.StatusCreated,
+		Errors: []domain.ErrorCode{domain.ErrCodeProjectNotFound, domain.ErrCodeMaintenanceInvalid},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/projects/:id/maintenance/:window_id", Tag: "Maintenance",
+		Summary: "Reschedule a maintenance window or change its message", Auth: true,
+		Body:   domain.UpdateMaintenanceRequest{},
+		Data:   domain.MaintenanceWindow{},
+		Errors: []domain.ErrorCode{domain.ErrCodeMaintenanceNotFound, domain.ErrCodeMaintenanceInvalid, domain.ErrCodeMaintenanceDone},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/projects/:id/maintenance/:window_id", Tag: "Maintenance",
+		Summary: "Delete a maintenance window, restoring the project's status if it runs", Auth: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeMaintenanceNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/portal", Tag: "Admin Portal",
+		Summary: "Get portal configuration", Auth: true,
+		Data: domain.Portal{},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/portal", Tag: "Admin Portal",
+		Summary: "Update portal configuration", Auth: true,
+		Body: domain.UpdatePortalRequest{},
+	},
+	{
+		Method: http.MethodPatch, Path: "/admin/portal", Tag: "Admin Portal",
+		Summary: "Partially update portal configuration with a merge patch or JSON Patch", Auth: true,
+		Patch:  domain.PortalDocument{},
+		Data:   domain.Portal{},
+		Errors: []domain.ErrorCode{domain.ErrCodePortalNotConfigured},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/incidents", Tag: "Incidents",
+		Summary: "List incidents, newest first", Auth: true,
+		Query: domain.IncidentFilter{},
+		Data:  []domain.Incident{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/incidents", Tag: "Incidents",
+		Summary: "Open an incident with its first update", Auth: true,
+		Body: domain.CreateIncidentRequest{},
+		Data: domain.Incident{}, Status: http.StatusCreated,
+		Errors: []domain.ErrorCode{domain.ErrCodeIncidentProject},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/incidents/:id", Tag: "Incidents",
+		Summary: "Get an incident with its updates", Auth: true,
+		Data:   domain.Incident{},
+		Errors: []domain.ErrorCode{domain.ErrCodeIncidentNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/incidents/:id", Tag: "Incidents",
+		Summary: "Update an incident's title, severity or projects", Auth: true,
+		Body:   domain.UpdateIncidentRequest{},
+		Data:   domain.Incident{},
+		Errors: []domain.ErrorCode{domain.ErrCodeIncidentNotFound, domain.ErrCodeIncidentProject},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/incidents/:id", Tag: "Incidents",
+		Summary: "Delete an incident and its updates", Auth: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeIncidentNotFound},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/incidents/:id/updates", Tag: "Incidents",
+		Summary: "Post an update and move the incident to its state", Auth: true,
+		Body: domain.CreateIncidentUpdateRequest{},
+		Data: domain.Incident{}, Status: http.StatusCreated,
+		Errors: []domain.ErrorCode{domain.ErrCodeIncidentNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/webhooks", Tag: "Webhooks",
+		Summary: "List webhook subscriptions", Auth: true,
+		Data: []domain.Webhook{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/webhooks", Tag: "Webhooks",
+		Summary: "Create a webhook; the response holds its signing secret", Auth: true,
+		Body:   domain.CreateWebhookRequest{},
+		Status: http.StatusCreated, Data: domain.WebhookSecret{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/webhooks/:id", Tag: "Webhooks",
+		Summary: "Get a webhook", Auth: true,
+		Data:   domain.Webhook{},
+		Errors: []domain.ErrorCode{domain.ErrCodeWebhookNotFound},
+	},
+	{
+		Method: http.MethodPut, Path: "/admin/webhooks/:id", Tag: "Webhooks",
+		Summary: "Update a webhook", Auth: true,
+		Body:   domain.UpdateWebhookRequest{},
+		Errors: []domain.ErrorCode{domain.ErrCodeWebhookNotFound},
+	},
+	{
+		Method: http.MethodDelete, Path: "/admin/webhooks/:id", Tag: "Webhooks",
+		Summary: "Delete a webhook and its delivery log", Auth: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeWebhookNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/webhooks/:id/deliveries", Tag: "Webhooks",
+		Summary: "List recent deliveries of a webhook", Auth: true,
+		Data:   []domain.WebhookDelivery{},
+		Errors: []domain.ErrorCode{domain.ErrCodeWebhookNotFound},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "Webhooks",
+		Summary: "Queue a delivery's event again", Auth: true,
+		Status: http.StatusAccepted, Data: domain.WebhookDelivery{},
+		Errors: []domain.ErrorCode{domain.ErrCodeDeliveryNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/audit-logs", Tag: "Audit",
+		Summary: "List audit log entries", Auth: true,
+		Query: domain.AuditLogFilter{},
+		Data:  domain.AuditLog{}, Paginated: true,
+		Errors: []domain.ErrorCode{domain.ErrCodeInvalidCursor},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/audit-logs/export", Tag: "Audit",
+		Summary: "Export audit log entries", Auth: true,
+		Query: domain.AuditLogFilter{},
+		Params: []Param{
+			{Name: "format", Description: "Export format (default csv)", Enum: []string{"csv", "ndjson"}},
+		},
+		Errors:       []domain.ErrorCode{domain.ErrCodeInvalidFormat},
+		ContentTypes: []string{"text/csv", "application/x-ndjson"},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/audit-logs/verify", Tag: "Audit",
+		Summary: "Verify the audit hash chain", Auth: true,
+		Data: domain.AuditVerifyResult{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/retention/runs", Tag: "Retention",
+		Summary: "List recent retention runs", Auth: true,
+		Data: []domain.RetentionRun{},
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/retention/runs", Tag: "Retention",
+		Summary: "Apply retention policies now", Auth: true,
+		Data: []domain.RetentionRun{},
+	},
+	{
+		Method: http.MethodGet, Path: "/admin/metrics", Tag: "System",
+		Summary: "Runtime metrics (expvar)", Auth: true,
+		Data: map[string]interface{}{}, Raw: true,
+	},
+}
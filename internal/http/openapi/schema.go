@@ -0,0 +1,277 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGenerator derives JSON Schemas from Go types using their json and
+// binding tags, collecting named structs as reusable components
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{components: map[string]interface{}{}}
+}
+
+// schemaFor returns the schema of v's type; named structs become $refs
+func (g *schemaGenerator) schemaFor(v interface{}) map[string]interface{} {
+	return g.schema(reflect.TypeOf(v), "")
+}
+
+// schema returns the schema for t, applying the constraints in binding
+func (g *schemaGenerator) schema(t reflect.Type, binding string) map[string]interface{} {
+	rules := parseBinding(binding)
+
+	switch {
+	case t == nil:
+		return map[string]interface{}{}
+
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+
+	case t == rawType:
+		// Any JSON value
+		return map[string]interface{}{}
+
+	case t.Kind() == reflect.Ptr:
+		s := g.schema(t.Elem(), binding)
+		if _, isRef := s["$ref"]; isRef {
+			return map[string]interface{}{"oneOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+		}
+		if typ, ok := s["type"].(string); ok {
+			s["type"] = []interface{}{typ, "null"}
+		}
+		return s
+
+	case t.Kind() == reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so that recursive types terminate
+			g.components[name] = map[string]interface{}{}
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s := map[string]interface{}{
+			"type":  "array",
+			"items": g.schema(t.Elem(), rules.dive),
+		}
+		applyLengthRules(s, rules, "minItems", "maxItems")
+		return s
+
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": g.schema(t.Elem(), ""),
+		}
+
+	case t.Kind() == reflect.Interface:
+		return map[string]interface{}{}
+
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		s := map[string]interface{}{"type": "integer"}
+		applyLengthRules(s, rules, "minimum", "maximum")
+		applyEnum(s, rules, true)
+		return s
+
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s := map[string]interface{}{"type": "number"}
+		applyLengthRules(s, rules, "minimum", "maximum")
+		return s
+
+	case t.Kind() == reflect.String:
+		s := map[string]interface{}{"type": "string"}
+		applyLengthRules(s, rules, "minLength", "maxLength")
+		applyEnum(s, rules, false)
+		if rules.format != "" {
+			s["format"] = rules.format
+		}
+		return s
+	}
+
+	return map[string]interface{}{}
+}
+
+// structSchema builds an object schema from the exported json fields of t
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for _, f := range jsonFields(t) {
+		properties[f.name] = g.schema(f.field.Type, f.field.Tag.Get("binding"))
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+// queryParameters describes the form-tagged fields of v as query parameters
+func (g *schemaGenerator) queryParameters(v interface{}) []interface{} {
+	t := reflect.TypeOf(v)
+	var params []interface{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		binding := field.Tag.Get("binding")
+		param := map[string]interface{}{
+			"name":     name,
+			"in":       "query",
+			"required": parseBinding(binding).required,
+			"schema":   g.schema(field.Type, binding),
+		}
+		if field.Type.Kind() == reflect.Slice {
+			param["explode"] = true
+		}
+		params = append(params, param)
+	}
+
+	return params
+}
+
+// jsonField is a struct field as it appears in JSON
+type jsonField struct {
+	name     string
+	field    reflect.StructField
+	required bool
+}
+
+// jsonFields lists the JSON-visible fields of t, flattening embedded structs
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if field.Anonymous && parts[0] == "" && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, jsonField{
+			name:     name,
+			field:    field,
+			required: parseBinding(field.Tag.Get("binding")).required,
+		})
+	}
+
+	return fields
+}
+
+// bindingRules are the validator constraints that map onto JSON Schema
+type bindingRules struct {
+	required bool
+	min, max string
+	oneOf    []string
+	format   string
+	dive     string
+}
+
+// parseBinding extracts schema constraints from a gin binding tag. Rules
+// after "dive" apply to the elements of a slice.
+func parseBinding(tag string) bindingRules {
+	var rules bindingRules
+	if tag == "" {
+		return rules
+	}
+
+	parts := strings.Split(tag, ",")
+	for i, part := range parts {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			rules.required = true
+		case "min":
+			rules.min = value
+		case "max":
+			rules.max = value
+		case "oneof":
+			rules.oneOf = strings.Fields(value)
+		case "url":
+			rules.format = "uri"
+		case "email":
+			rules.format = "email"
+		case "dive":
+			rules.dive = strings.Join(parts[i+1:], ",")
+			return rules
+		}
+	}
+
+	return rules
+}
+
+// applyLengthRules sets the min/max keywords from binding rules
+func applyLengthRules(s map[string]interface{}, rules bindingRules, minKey, maxKey string) {
+	if n, err := strconv.Atoi(rules.min); err == nil {
+		s[minKey] = n
+	}
+	if n, err := strconv.Atoi(rules.max); err == nil {
+		s[maxKey] = n
+	}
+}
+
+// applyEnum sets the enum keyword from a oneof rule
+func applyEnum(s map[string]interface{}, rules bindingRules, numeric bool) {
+	if len(rules.oneOf) == 0 {
+		return
+	}
+
+	values := make([]interface{}, 0, len(rules.oneOf))
+	for _, v := range rules.oneOf {
+		if numeric {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+			values = append(values, n)
+		} else {
+			values = append(values, v)
+		}
+	}
+	s["enum"] = values
+}
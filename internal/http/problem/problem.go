@@ -0,0 +1,156 @@
+// Package problem writes RFC 9457 problem+json error responses and keeps
+// internal error details out of them.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// RequestIDKey is the context key holding the request correlation ID
+const RequestIDKey = "request_id"
+
+func init() {
+	// Report validation failures by their JSON or query parameter name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(field.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Write sends p, filling in the request path and correlation ID, and aborts
+// the handler chain
+func Write(c *gin.Context, p *domain.Problem) {
+	p.Instance = c.Request.URL.Path
+	p.RequestID = c.GetString(RequestIDKey)
+
+	// Errors must never be served from a cache
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// Respond sends the problem for a catalogued error code
+func Respond(c *gin.Context, code domain.ErrorCode, detail string) {
+	Write(c, domain.NewProblem(code, detail))
+}
+
+// Internal logs err under the request's correlation ID and sends a generic
+// internal error that does not reveal it
+func Internal(c *gin.Context, err error) {
+	log.Printf("[%s] %s %s: %v", c.GetString(RequestIDKey), c.Request.Method, c.Request.URL.Path, err)
+	Respond(c, domain.ErrCodeInternal, "An unexpected error occurred; quote the request ID when reporting it")
+}
+
+// Validation sends a binding error, listing every failed field rule
+func Validation(c *gin.Context, err error) {
+	var syntaxErr *json.SyntaxError
+
+	if fields, ok := FieldErrors(err); ok {
+		p := domain.NewProblem(domain.ErrCodeValidationFailed, "One or more fields are invalid")
+		p.Errors = fields
+		Write(c, p)
+		return
+	}
+
+	if errors.As(err, &syntaxErr) {
+		Respond(c, domain.ErrCodeMalformedRequest, "Request body is not valid JSON")
+		return
+	}
+
+	Respond(c, domain.ErrCodeMalformedRequest, err.Error())
+}
+
+// FieldErrors converts validator, JSON type and service validation errors
+// into per-field details. It reports false for any other error.
+func FieldErrors(err error) ([]domain.FieldError, bool) {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		serviceErr     *domain.ValidationError
+	)
+
+	switch {
+	case errors.As(err, &serviceErr):
+		return serviceErr.Fields, true
+
+	case errors.As(err, &validationErrs):
+		fields := make([]domain.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, domain.FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return fields, true
+
+	case errors.As(err, &typeErr):
+		return []domain.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}}, true
+	}
+
+	return nil, false
+}
+
+// fieldPath returns the field's path without the top-level struct name,
+// e.g. "tags[0]"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// fieldMessage describes a failed rule in English
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(fe.Param()), ", "))
+	case "url":
+		return "must be a valid URL"
+	case "email":
+		return "must be a valid email address"
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
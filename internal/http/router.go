@@ -1,16 +1,32 @@
 package http
 
 import (
+	"context"
 	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kanyaarss/kanyaars-portal/internal/buildinfo"
 	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/database"
+	"github.com/kanyaarss/kanyaars-portal/internal/health"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/gql"
 	"github.com/kanyaarss/kanyaars-portal/internal/http/handlers"
 	"github.com/kanyaarss/kanyaars-portal/internal/http/middleware"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/openapi"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/rpc"
+	"github.com/kanyaarss/kanyaars-portal/internal/ratelimit"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 )
 
-// NewRouter creates and configures the Gin router
-func NewRouter(cfg *config.Config, db *sql.DB) *gin.Engine {
+// NewRouter creates and configures the Gin router on the shared services;
+// redisClient is nil when Redis is disabled
+func NewRouter(cfg *config.Config, db *sql.DB, redisClient *redis.Client, services *service.Services) *gin.Engine {
 	// Set Gin mode
 	if cfg.App.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -20,47 +36,173 @@ func NewRouter(cfg *config.Config, db *sql.DB) *gin.Engine {
 
 	router := gin.New()
 
+	if len(cfg.Server.TrustedProxies) > 0 {
+		if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			log.Fatalf("Invalid trusted proxies: %v", err)
+		}
+	}
+
 	// Load HTML Templates
 	router.LoadHTMLGlob("web/templates/*.html")
 	
 	// Global middleware
+	router.Use(middleware.RequestID())
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS(cfg.CORS))
 
+	graphqlServer, err := gql.NewServer(services.Projects, services.Portal, cfg.GraphQL)
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+
+	// gRPC PortalService, on its own port or multiplexed over h2c. Calls are
+	// answered before the route groups below, so the rate-limit and
+	// idempotency middleware do not apply to them.
+	if cfg.GRPC.Enabled {
+		grpcServer, err := rpc.NewServer(services.Projects, services.Portal, services.Events, cfg.JWT.Secret)
+		if err != nil {
+			log.Fatalf("Failed to build gRPC server: %v", err)
+		}
+
+		if cfg.GRPC.Port == 0 {
+			router.UseH2C = true
+			router.Use(middleware.GRPC(grpcServer))
+		} else {
+			go serveGRPC(grpcServer, fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port))
+		}
+	}
+
+	// Rate limiting per route group
+	var limiter ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		limiter = newRateLimitStore(cfg, redisClient)
+	}
+	apiKeys, err := ratelimit.NewKeySet(cfg.RateLimit.APIKeys)
+	if err != nil {
+		log.Fatalf("Invalid rate limit API keys: %v", err)
+	}
+	rateLimit := func(name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+		return middleware.RateLimit(limiter, apiKeys, name, policy)
+	}
+
+	// Readiness checks; Redis only backs caching and rate limiting, which
+	// fall back to the database and failing open
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Register("database", true, db.PingContext)
+	checker.Register("migrations", true, func(ctx context.Context) error {
+		return database.CheckSchema(ctx, db)
+	})
+	if redisClient != nil {
+		checker.Register("redis", false, func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg.JWT.Secret)
-	publicHandler := handlers.NewPublicHandler(db)
-	apiHandler := handlers.NewAPIHandler(db)
-	adminHandler := handlers.NewAdminHandler(db)
+	authHandler := handlers.NewAuthHandler(services.Auth)
+	publicHandler := handlers.NewPublicHandler(services.Projects, services.Incidents, services.Maintenance, services.Deployments, services.Cache)
+	apiHandler := handlers.NewAPIHandler(services.Projects, services.Portal, services.Search)
+	healthHandler := handlers.NewHealthHandler(checker)
+	adminHandler := handlers.NewAdminHandler(services.Projects, services.Portal)
+	auditHandler := handlers.NewAuditHandler(services.Audit)
+	retentionHandler := handlers.NewRetentionHandler(services.Retention)
+	uptimeHandler := handlers.NewUptimeHandler(services.Uptime)
+	incidentHandler := handlers.NewIncidentHandler(services.Incidents)
+	maintenanceHandler := handlers.NewMaintenanceHandler(services.Maintenance)
+	deploymentHandler := handlers.NewDeploymentHandler(services.Deployments)
+	webhookHandler := handlers.NewWebhookHandler(services.Webhooks)
+	eventsHandler := handlers.NewEventsHandler(services.Events)
+	graphqlHandler := handlers.NewGraphQLHandler(graphqlServer)
+	docsHandler := handlers.NewDocsHandler(openapi.Document(buildinfo.Version, openapi.Operations))
+
+	// Probes, outside rate limiting
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
 
 	// Public routes
-	router.GET("/", publicHandler.Home)
-	router.GET("/projects", publicHandler.Projects)
-	router.GET("/projects/:slug", publicHandler.ProjectDetail)
+	pages := router.Group("")
+	pages.Use(middleware.CacheControl(cfg.HTTPCache.Pages))
+	{
+		pages.GET("/", publicHandler.Home)
+		pages.GET("/projects", publicHandler.Projects)
+		pages.GET("/projects/:slug", publicHandler.ProjectDetail)
+		pages.GET("/status", publicHandler.Status)
+		pages.GET("/status/feed.atom", publicHandler.StatusFeed)
+	}
 
 	// API routes (public)
 	api := router.Group("/api/v1")
+	api.Use(rateLimit("api", cfg.RateLimit.API), middleware.CacheControl(cfg.HTTPCache.API))
 	{
-		api.GET("/health", apiHandler.HealthCheck)
-		api.POST("/auth/login", authHandler.Login)
+		api.GET("/health", healthHandler.Readyz)
+		api.GET("/openapi.json", docsHandler.OpenAPI)
+		api.GET("/docs", docsHandler.Docs)
+		api.GET("/errors", docsHandler.Errors)
+		api.POST("/auth/login", rateLimit("login", cfg.RateLimit.Login), authHandler.Login)
 		api.GET("/portal", apiHandler.GetPortal)
 		api.GET("/projects", apiHandler.GetProjects)
 		api.GET("/projects/:id", apiHandler.GetProject)
+		api.GET("/projects/:id/health", uptimeHandler.GetHealth)
+		api.GET("/projects/:id/checks", uptimeHandler.ListChecks)
+		api.GET("/projects/:id/maintenance", maintenanceHandler.ListWindows)
+		api.GET("/projects/:id/deployments", deploymentHandler.ListDeployments)
+		api.GET("/projects/by-slug/:slug", apiHandler.GetProjectBySlug)
+		api.GET("/search", apiHandler.Search)
+		api.GET("/status", incidentHandler.Status)
+		api.GET("/maintenance", maintenanceHandler.ListUpcoming)
+		api.POST("/deployments", deploymentHandler.RecordEvent) // signed by CI instead of a token
+		api.GET("/events", eventsHandler.Stream)
+	}
+
+	// GraphQL (anonymous reads, mutations need a token)
+	graphqlRoute := router.Group("/api/graphql")
+	graphqlRoute.Use(rateLimit("graphql", cfg.RateLimit.GraphQL), middleware.NoStore(), middleware.OptionalAuth(cfg.JWT.Secret))
+	{
+		graphqlRoute.GET("", graphqlHandler.Query)
+		graphqlRoute.POST("", graphqlHandler.Query)
 	}
 
 	// Admin routes (protected)
 	admin := router.Group("/admin")
-	admin.Use(middleware.Auth(cfg.JWT.Secret))
+	admin.Use(middleware.NoStore(), middleware.Auth(cfg.JWT.Secret), rateLimit("admin", cfg.RateLimit.Admin), middleware.Idempotency(services.Idempotency))
 	{
 		admin.GET("/", adminHandler.Dashboard)
 		admin.GET("/projects", adminHandler.ListProjects)
 		admin.POST("/projects", adminHandler.CreateProject)
+		admin.GET("/projects/export", adminHandler.ExportProjects)
+		admin.POST("/projects/import", adminHandler.ImportProjects)
 		admin.GET("/projects/:id", adminHandler.GetProject)
 		admin.PUT("/projects/:id", adminHandler.UpdateProject)
+		admin.PATCH("/projects/:id", adminHandler.PatchProject)
 		admin.DELETE("/projects/:id", adminHandler.DeleteProject)
+		admin.GET("/projects/:id/monitor", uptimeHandler.GetMonitor)
+		admin.PUT("/projects/:id/monitor", uptimeHandler.UpdateMonitor)
+		admin.POST("/projects/:id/maintenance", maintenanceHandler.CreateWindow)
+		admin.PUT("/projects/:id/maintenance/:window_id", maintenanceHandler.UpdateWindow)
+		admin.DELETE("/projects/:id/maintenance/:window_id", maintenanceHandler.DeleteWindow)
 		admin.GET("/portal", adminHandler.GetPortal)
 		admin.PUT("/portal", adminHandler.UpdatePortal)
+		admin.PATCH("/portal", adminHandler.PatchPortal)
+		admin.GET("/incidents", incidentHandler.ListIncidents)
+		admin.POST("/incidents", incidentHandler.CreateIncident)
+		admin.GET("/incidents/:id", incidentHandler.GetIncident)
+		admin.PUT("/incidents/:id", incidentHandler.UpdateIncident)
+		admin.DELETE("/incidents/:id", incidentHandler.DeleteIncident)
+		admin.POST("/incidents/:id/updates", incidentHandler.PostUpdate)
+		admin.GET("/webhooks", webhookHandler.ListWebhooks)
+		admin.POST("/webhooks", webhookHandler.CreateWebhook)
+		admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+		admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+		admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+		admin.GET("/audit-logs", auditHandler.ListAuditLogs)
+		admin.GET("/audit-logs/export", auditHandler.ExportAuditLogs)
+		admin.GET("/audit-logs/verify", auditHandler.VerifyAuditLogs)
+		admin.GET("/retention/runs", retentionHandler.ListRuns)
+		admin.POST("/retention/runs", retentionHandler.RunNow)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
 	}
 
 	// Static files
@@ -68,3 +210,34 @@ func NewRouter(cfg *config.Config, db *sql.DB) *gin.Engine {
 
 	return router
 }
+
+// serveGRPC serves server on addr until the process exits
+func serveGRPC(server *grpc.Server, addr string) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+
+	log.Printf("Starting gRPC server on %s", addr)
+	if err := server.Serve(lis); err != nil {
+		log.Fatalf("Failed to serve gRPC: %v", err)
+	}
+}
+
+// newRateLimitStore returns the configured rate limit store
+func newRateLimitStore(cfg *config.Config, redisClient *redis.Client) ratelimit.Store {
+	switch cfg.RateLimit.Store {
+	case ratelimit.StoreMemory:
+		return ratelimit.NewMemoryStore()
+
+	case ratelimit.StoreRedis:
+		if redisClient == nil {
+			log.Fatalf("Rate limit store %q requires redis.enabled", cfg.RateLimit.Store)
+		}
+		return ratelimit.NewRedisStore(redisClient)
+
+	default:
+		log.Fatalf("Unknown rate limit store %q", cfg.RateLimit.Store)
+		return nil
+	}
+}
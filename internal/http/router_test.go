@@ -0,0 +1,92 @@
+package http
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/openapi"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+)
+
+// TestRoutesDocumented fails when a route under /api or /admin is added
+// without an openapi.Operations entry, or an entry outlives its route
+func TestRoutesDocumented(t *testing.T) {
+	// Templates are loaded relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	router := NewRouter(cfg, nil, nil, service.NewServices(cfg, nil, nil))
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") && !strings.HasPrefix(route.Path, "/admin") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		registered[key] = true
+	}
+
+	documented := map[string]bool{}
+	for _, op := range openapi.Operations {
+		key := op.Method + " " + op.Path
+		if documented[key] {
+			t.Errorf("route %s documented twice", key)
+		}
+		documented[key] = true
+	}
+
+	for key := range registered {
+		if !documented[key] {
+			t.Errorf("route %s has no entry in openapi.Operations", key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("openapi.Operations documents %s, which is not registered", key)
+		}
+	}
+}
+
+// TestDocumentReferencesResolve checks that every $ref in the generated
+// document points at a component schema
+func TestDocumentReferencesResolve(t *testing.T) {
+	doc := openapi.Document("test", openapi.Operations)
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	schemas := decoded["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := node["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, child := range node {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(decoded)
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type contextKey struct{}
+
+// caller is who sent a call, stored in the context by the interceptors
+type caller struct {
+	actor         domain.Actor
+	authenticated bool
+}
+
+// callerFrom returns the caller stored by the auth interceptors
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(contextKey{}).(caller)
+	return c
+}
+
+// mutations are the methods that require a bearer token, like /admin
+var mutations = map[string]bool{
+	"/" + ServiceName + "/CreateProject": true,
+	"/" + ServiceName + "/UpdateProject": true,
+	"/" + ServiceName + "/DeleteProject": true,
+	"/" + ServiceName + "/UpdatePortal":  true,
+}
+
+// authenticator validates "authorization" metadata with the same JWT rules
+// as middleware.Auth. Calls without a token are anonymous reads.
+type authenticator struct {
+	jwtSecret string
+}
+
+func (a authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate stores the caller in ctx, rejecting unusable tokens and
+// anonymous mutations
+func (a authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	c := caller{actor: domain.Actor{UserAgent: first(md, "user-agent")}}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		c.actor.IPAddress = p.Addr.String()
+		if i := strings.LastIndex(c.actor.IPAddress, ":"); i > 0 {
+			c.actor.IPAddress = strings.Trim(c.actor.IPAddress[:i], "[]")
+		}
+	}
+
+	if header := first(md, "authorization"); header != "" {
+		claims, p := middleware.BearerClaims(header, a.jwtSecret)
+		if p != nil {
+			return nil, problemStatus(p)
+		}
+		c.actor.UserID = claims.UserID
+		c.authenticated = true
+	} else if mutations[method] {
+		return nil, codeError(domain.ErrCodeAuthTokenMissing, "Mutations require a bearer token")
+	}
+
+	return context.WithValue(ctx, contextKey{}, c), nil
+}
+
+// authenticatedStream overrides the context of a server stream
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
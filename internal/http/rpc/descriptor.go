@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ServiceName is the fully qualified name of the gRPC service
+const ServiceName = "portal.v1.PortalService"
+
+// protoFile is the path of the proto file this descriptor mirrors
+const protoFile = "portal/v1/portal.proto"
+
+type (
+	fieldType = descriptorpb.FieldDescriptorProto_Type
+	fieldDef  = *descriptorpb.FieldDescriptorProto
+)
+
+const (
+	typeString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	typeInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	typeInt32   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+	typeBool    = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	typeMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+)
+
+// timestamp is the type name of google.protobuf.Timestamp fields
+const timestamp = ".google.protobuf.Timestamp"
+
+// buildFile builds the descriptor of api/proto/portal/v1/portal.proto and
+// registers it so that server reflection can serve it
+func buildFile() (protoreflect.FileDescriptor, error) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(protoFile),
+		Package:    proto.String("portal.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{timestamppb.File_google_protobuf_timestamp_proto.Path()},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("Portal",
+				scalar(1, "name", typeString),
+				scalar(2, "description", typeString),
+				scalar(3, "logo_url", typeString),
+				scalar(4, "website", typeString),
+				scalar(5, "email", typeString),
+				scalar(6, "phone", typeString),
+				scalar(7, "address", typeString),
+				nested(8, "updated_at", timestamp),
+			),
+			message("Project",
+				scalar(1, "id", typeInt64),
+				scalar(2, "name", typeString),
+				scalar(3, "slug", typeString),
+				scalar(4, "description", typeString),
+				scalar(5, "url", typeString),
+				scalar(6, "icon_url", typeString),
+				scalar(7, "status", typeString),
+				scalar(8, "order", typeInt32),
+				repeated(9, "tags", typeString),
+				scalar(10, "managed", typeBool),
+				nested(11, "created_at", timestamp),
+				nested(12, "updated_at", timestamp),
+			),
+			message("GetPortalRequest"),
+			message("ListProjectsRequest",
+				scalar(1, "status", typeString),
+				repeated(2, "tags", typeString),
+				scalar(3, "q", typeString),
+				scalar(4, "sort", typeString),
+				scalar(5, "page", typeInt32),
+				scalar(6, "page_size", typeInt32),
+				scalar(7, "cursor", typeString),
+			),
+			message("ListProjectsResponse",
+				repeatedMessage(1, "projects", ".portal.v1.Project"),
+				scalar(2, "total", typeInt32),
+				scalar(3, "page", typeInt32),
+				scalar(4, "page_size", typeInt32),
+				scalar(5, "total_pages", typeInt32),
+				scalar(6, "next_cursor", typeString),
+			),
+			message("GetProjectRequest",
+				scalar(1, "id", typeInt64),
+				scalar(2, "slug", typeString),
+			),
+			message("WatchProjectsRequest",
+				scalar(1, "last_event_id", typeInt64),
+			),
+			message("ProjectEvent",
+				scalar(1, "id", typeInt64),
+				scalar(2, "type", typeString),
+				nested(3, "project", ".portal.v1.Project"),
+				repeated(4, "changed_fields", typeString),
+				nested(5, "created_at", timestamp),
+			),
+			message("CreateProjectRequest",
+				scalar(1, "name", typeString),
+				scalar(2, "slug", typeString),
+				scalar(3, "description", typeString),
+				scalar(4, "url", typeString),
+				scalar(5, "icon_url", typeString),
+				scalar(6, "status", typeString),
+				repeated(7, "tags", typeString),
+			),
+			message("UpdateProjectRequest",
+				scalar(1, "id", typeInt64),
+				scalar(2, "name", typeString),
+				scalar(3, "slug", typeString),
+				scalar(4, "description", typeString),
+				scalar(5, "url", typeString),
+				scalar(6, "icon_url", typeString),
+				scalar(7, "status", typeString),
+				repeated(8, "tags", typeString),
+			),
+			message("DeleteProjectRequest",
+				scalar(1, "id", typeInt64),
+			),
+			message("DeleteProjectResponse"),
+			message("UpdatePortalRequest",
+				scalar(1, "name", typeString),
+				scalar(2, "description", typeString),
+				scalar(3, "logo_url", typeString),
+				scalar(4, "website", typeString),
+				scalar(5, "email", typeString),
+				scalar(6, "phone", typeString),
+				scalar(7, "address", typeString),
+			),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("PortalService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetPortal", "GetPortalRequest", "Portal", false),
+				method("ListProjects", "ListProjectsRequest", "ListProjectsResponse", false),
+				method("GetProject", "GetProjectRequest", "Project", false),
+				method("WatchProjects", "WatchProjectsRequest", "ProjectEvent", true),
+				method("CreateProject", "CreateProjectRequest", "Project", false),
+				method("UpdateProject", "UpdateProjectRequest", "Project", false),
+				method("DeleteProject", "DeleteProjectRequest", "DeleteProjectResponse", false),
+				method("UpdatePortal", "UpdatePortalRequest", "Portal", false),
+			},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor: %w", err)
+	}
+
+	// Several servers may be built in one process (e.g. in tests)
+	if _, err := protoregistry.GlobalFiles.FindFileByPath(protoFile); err != nil {
+		if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+			return nil, err
+		}
+	}
+
+	return fd, nil
+}
+
+func message(name string, fields ...fieldDef) *descriptorpb.DescriptorProto {
+	for _, f := range fields {
+		f.JsonName = proto.String(jsonName(f.GetName()))
+	}
+	return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+}
+
+func field(number int32, name string, typ fieldType, label descriptorpb.FieldDescriptorProto_Label) fieldDef {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   typ.Enum(),
+		Label:  label.Enum(),
+	}
+}
+
+func scalar(number int32, name string, typ fieldType) fieldDef {
+	return field(number, name, typ, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL)
+}
+
+func repeated(number int32, name string, typ fieldType) fieldDef {
+	return field(number, name, typ, descriptorpb.FieldDescriptorProto_LABEL_REPEATED)
+}
+
+func nested(number int32, name, typeName string) fieldDef {
+	f := scalar(number, name, typeMessage)
+	f.TypeName = proto.String(typeName)
+	return f
+}
+
+func repeatedMessage(number int32, name, typeName string) fieldDef {
+	f := repeated(number, name, typeMessage)
+	f.TypeName = proto.String(typeName)
+	return f
+}
+
+func method(name, input, output string, serverStreaming bool) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:            proto.String(name),
+		InputType:       proto.String(".portal.v1." + input),
+		OutputType:      proto.String(".portal.v1." + output),
+		ServerStreaming: proto.Bool(serverStreaming),
+	}
+}
+
+// jsonName converts a snake_case field name to lowerCamelCase as protoc does
+func jsonName(name string) string {
+	b := make([]byte, 0, len(name))
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b = append(b, c)
+	}
+	return string(b)
+}
@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	protoComment = regexp.MustCompile(`//[^\n]*`)
+	protoPackage = regexp.MustCompile(`\bpackage\s+([\w.]+)\s*;`)
+	protoImport  = regexp.MustCompile(`\bimport\s+"([^"]+)"\s*;`)
+	protoMessage = regexp.MustCompile(`\bmessage\s+(\w+)\s*\{([^}]*)\}`)
+	protoField   = regexp.MustCompile(`(repeated\s+)?([\w.]+)\s+(\w+)\s*=\s*(\d+)\s*;`)
+	protoRPC     = regexp.MustCompile(`\brpc\s+(\w+)\s*\(\s*(\w+)\s*\)\s*returns\s*\(\s*(stream\s+)?(\w+)\s*\)\s*;`)
+)
+
+// protoScalars are the scalar types used by portal.proto, named as
+// protoreflect.Kind names them
+var protoScalars = map[string]bool{"string": true, "int64": true, "int32": true, "bool": true}
+
+// TestDescriptorMatchesProto guards against the hand-written descriptor
+// drifting from api/proto/portal/v1/portal.proto, which clients generate
+// their code from
+func TestDescriptorMatchesProto(t *testing.T) {
+	source, err := os.ReadFile("../../../api/proto/" + protoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := buildFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := parseProto(t, string(source))
+	got := describeFile(fd)
+
+	for _, line := range difference(want, got) {
+		t.Errorf("missing from descriptor.go: %s", line)
+	}
+	for _, line := range difference(got, want) {
+		t.Errorf("missing from portal.proto: %s", line)
+	}
+}
+
+// parseProto lists the declarations of a proto file that has no nested
+// messages, enums or options other than go_package, one per line
+func parseProto(t *testing.T, source string) []string {
+	t.Helper()
+
+	source = protoComment.ReplaceAllString(source, "")
+
+	m := protoPackage.FindStringSubmatch(source)
+	if m == nil {
+		t.Fatal("portal.proto has no package")
+	}
+	pkg := m[1]
+
+	lines := []string{"package " + pkg}
+	for _, m := range protoImport.FindAllStringSubmatch(source, -1) {
+		lines = append(lines, "import "+m[1])
+	}
+
+	typeName := func(name string) string {
+		switch {
+		case protoScalars[name]:
+			return name
+		case strings.Contains(name, "."):
+			return "." + name
+		default:
+			return "." + pkg + "." + name
+		}
+	}
+
+	for _, m := range protoMessage.FindAllStringSubmatch(source, -1) {
+		lines = append(lines, "message "+m[1])
+		for _, f := range protoField.FindAllStringSubmatch(m[2], -1) {
+			lines = append(lines, fieldLine(m[1], f[4], f[3], typeName(f[2]), f[1] != ""))
+		}
+	}
+
+	for _, m := range protoRPC.FindAllStringSubmatch(source, -1) {
+		lines = append(lines, rpcLine(m[1], typeName(m[2]), typeName(m[4]), m[3] != ""))
+	}
+
+	if len(lines) < 10 {
+		t.Fatalf("parsed only %d declarations from portal.proto", len(lines))
+	}
+
+	return lines
+}
+
+// describeFile lists the declarations of fd in the format of parseProto
+func describeFile(fd protoreflect.FileDescriptor) []string {
+	lines := []string{"package " + string(fd.Package())}
+
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		lines = append(lines, "import "+imports.Get(i).Path())
+	}
+
+	messages := fd.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		lines = append(lines, "message "+string(msg.Name()))
+
+		fields := msg.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			f := fields.Get(j)
+			typ := f.Kind().String()
+			if f.Kind() == protoreflect.MessageKind {
+				typ = "." + string(f.Message().FullName())
+			}
+			lines = append(lines, fieldLine(string(msg.Name()), fmt.Sprint(f.Number()), string(f.Name()), typ, f.Cardinality() == protoreflect.Repeated))
+		}
+	}
+
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			m := methods.Get(j)
+			lines = append(lines, rpcLine(string(m.Name()), "."+string(m.Input().FullName()), "."+string(m.Output().FullName()), m.IsStreamingServer()))
+		}
+	}
+
+	return lines
+}
+
+func fieldLine(message, number, name, typ string, repeated bool) string {
+	if repeated {
+		typ = "repeated " + typ
+	}
+	return fmt.Sprintf("%s.%s = %s (%s)", message, name, number, typ)
+}
+
+func rpcLine(name, input, output string, stream bool) string {
+	if stream {
+		output = "stream " + output
+	}
+	return fmt.Sprintf("rpc %s(%s) returns (%s)", name, input, output)
+}
+
+// difference returns the lines of a that are not in b, sorted
+func difference(a, b []string) []string {
+	seen := make(map[string]bool, len(b))
+	for _, line := range b {
+		seen[line] = true
+	}
+
+	var diff []string
+	for _, line := range a {
+		if !seen[line] {
+			diff = append(diff, line)
+		}
+	}
+	sort.Strings(diff)
+
+	return diff
+}
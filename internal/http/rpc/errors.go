@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies the error catalog in google.rpc.ErrorInfo details
+const errorDomain = "kanyaars-portal"
+
+// grpcCodes maps problem HTTP statuses onto gRPC status codes
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusMethodNotAllowed:    codes.Unimplemented,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+}
+
+// problemStatus converts a problem into a gRPC status. The catalogued error
+// code travels as the ErrorInfo reason and field errors as BadRequest
+// violations, mirroring the REST problem responses.
+func problemStatus(p *domain.Problem) error {
+	code, ok := grpcCodes[p.Status]
+	if !ok {
+		code = codes.Internal
+	}
+
+	message := p.Title
+	if p.Detail != "" {
+		message += ": " + p.Detail
+	}
+
+	st := status.New(code, message)
+	if withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(p.Code), Domain: errorDomain}); err == nil {
+		st = withInfo
+	}
+
+	if len(p.Errors) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(p.Errors))
+		for _, fe := range p.Errors {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Message})
+		}
+		if withFields, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			st = withFields
+		}
+	}
+
+	return st.Err()
+}
+
+// codeError returns the status of a catalogued error code
+func codeError(code domain.ErrorCode, detail string) error {
+	return problemStatus(domain.NewProblem(code, detail))
+}
+
+// serviceError maps a service or validation error onto a gRPC status.
+// Unexpected errors are logged and not revealed to the client.
+func serviceError(ctx context.Context, err error) error {
+	if fields, ok := problem.FieldErrors(err); ok {
+		p := domain.NewProblem(domain.ErrCodeValidationFailed, "One or more fields are invalid")
+		p.Errors = fields
+		return problemStatus(p)
+	}
+
+	errorCodes := map[error]domain.ErrorCode{
+		service.ErrProjectNotFound: domain.ErrCodeProjectNotFound,
+		service.ErrSlugTaken:       domain.ErrCodeProjectSlugTaken,
+		service.ErrSlugInvalid:     domain.ErrCodeProjectSlugInvalid,
+		service.ErrSlugReserved:    domain.ErrCodeProjectSlugReserved,
+		service.ErrProjectManaged:  domain.ErrCodeProjectManaged,
+		service.ErrInvalidCursor:   domain.ErrCodeInvalidCursor,
+		service.ErrInvalidSort:     domain.ErrCodeInvalidSort,
+	}
+	for target, code := range errorCodes {
+		if errors.Is(err, target) {
+			return codeError(code, "")
+		}
+	}
+
+	method, _ := grpc.Method(ctx)
+	log.Printf("[grpc] %s: %v", method, err)
+	return codeError(domain.ErrCodeInternal, "An unexpected error occurred")
+}
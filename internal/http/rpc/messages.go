@@ -0,0 +1,176 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// messages creates and converts the dynamic messages of the portal.v1 file
+type messages struct {
+	file protoreflect.FileDescriptor
+}
+
+// new returns an empty message of the named type
+func (m messages) new(name string) *dynamicpb.Message {
+	return dynamicpb.NewMessage(m.file.Messages().ByName(protoreflect.Name(name)))
+}
+
+func (m messages) portal(p *domain.Portal) *dynamicpb.Message {
+	msg := m.new("Portal")
+	set(msg, "name", p.Name)
+	set(msg, "description", p.Description)
+	set(msg, "logo_url", p.LogoURL)
+	set(msg, "website", p.Website)
+	set(msg, "email", p.Email)
+	set(msg, "phone", p.Phone)
+	set(msg, "address", p.Address)
+	set(msg, "updated_at", p.UpdatedAt)
+	return msg
+}
+
+func (m messages) project(p *domain.Project) *dynamicpb.Message {
+	msg := m.new("Project")
+	set(msg, "id", p.ID)
+	set(msg, "name", p.Name)
+	set(msg, "slug", p.Slug)
+	set(msg, "description", p.Description)
+	set(msg, "url", p.URL)
+	set(msg, "icon_url", p.IconURL)
+	set(msg, "status", p.Status)
+	set(msg, "order", p.Order)
+	set(msg, "tags", p.Tags)
+	set(msg, "managed", p.Managed)
+	set(msg, "created_at", p.CreatedAt)
+	set(msg, "updated_at", p.UpdatedAt)
+	return msg
+}
+
+func (m messages) projectPage(page *domain.PaginatedResponse) *dynamicpb.Message {
+	msg := m.new("ListProjectsResponse")
+	list := msg.Mutable(fieldOf(msg, "projects")).List()
+	if projects, ok := page.Data.([]domain.Project); ok {
+		for i := range projects {
+			list.Append(protoreflect.ValueOfMessage(m.project(&projects[i])))
+		}
+	}
+	set(msg, "total", page.Total)
+	set(msg, "page", page.Page)
+	set(msg, "page_size", page.PageSize)
+	set(msg, "total_pages", page.TotalPages)
+	set(msg, "next_cursor", page.NextCursor)
+	return msg
+}
+
+func projectFilter(msg *dynamicpb.Message) domain.ProjectFilter {
+	return domain.ProjectFilter{
+		Status:   str(msg, "status"),
+		Tags:     strs(msg, "tags"),
+		Query:    str(msg, "q"),
+		Sort:     str(msg, "sort"),
+		Page:     num(msg, "page"),
+		PageSize: num(msg, "page_size"),
+		Cursor:   str(msg, "cursor"),
+	}
+}
+
+func createProjectRequest(msg *dynamicpb.Message) domain.CreateProjectRequest {
+	return domain.CreateProjectRequest{
+		Name:        str(msg, "name"),
+		Slug:        str(msg, "slug"),
+		Description: str(msg, "description"),
+		URL:         str(msg, "url"),
+		IconURL:     str(msg, "icon_url"),
+		Status:      str(msg, "status"),
+		Tags:        strs(msg, "tags"),
+	}
+}
+
+func updateProjectRequest(msg *dynamicpb.Message) domain.UpdateProjectRequest {
+	return domain.UpdateProjectRequest{
+		Name:        str(msg, "name"),
+		Slug:        str(msg, "slug"),
+		Description: str(msg, "description"),
+		URL:         str(msg, "url"),
+		IconURL:     str(msg, "icon_url"),
+		Status:      str(msg, "status"),
+		Tags:        strs(msg, "tags"),
+	}
+}
+
+func updatePortalRequest(msg *dynamicpb.Message) domain.UpdatePortalRequest {
+	return domain.UpdatePortalRequest{
+		Name:        str(msg, "name"),
+		Description: str(msg, "description"),
+		LogoURL:     str(msg, "logo_url"),
+		Website:     str(msg, "website"),
+		Email:       str(msg, "email"),
+		Phone:       str(msg, "phone"),
+		Address:     str(msg, "address"),
+	}
+}
+
+func fieldOf(msg *dynamicpb.Message, name string) protoreflect.FieldDescriptor {
+	return msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+}
+
+// set assigns value to the named field; zero values are left unset as
+// proto3 does not transmit them
+func set(msg *dynamicpb.Message, name string, value interface{}) {
+	fd := fieldOf(msg, name)
+
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			msg.Set(fd, protoreflect.ValueOfString(v))
+		}
+	case int:
+		if v == 0 {
+			return
+		}
+		if fd.Kind() == protoreflect.Int64Kind {
+			msg.Set(fd, protoreflect.ValueOfInt64(int64(v)))
+		} else {
+			msg.Set(fd, protoreflect.ValueOfInt32(int32(v)))
+		}
+	case bool:
+		if v {
+			msg.Set(fd, protoreflect.ValueOfBool(v))
+		}
+	case []string:
+		list := msg.Mutable(fd).List()
+		for _, s := range v {
+			list.Append(protoreflect.ValueOfString(s))
+		}
+	case time.Time:
+		if !v.IsZero() {
+			msg.Set(fd, protoreflect.ValueOfMessage(timestamppb.New(v).ProtoReflect()))
+		}
+	case *dynamicpb.Message:
+		msg.Set(fd, protoreflect.ValueOfMessage(v))
+	}
+}
+
+func str(msg *dynamicpb.Message, name string) string {
+	return msg.Get(fieldOf(msg, name)).String()
+}
+
+func num(msg *dynamicpb.Message, name string) int {
+	return int(msg.Get(fieldOf(msg, name)).Int())
+}
+
+func strs(msg *dynamicpb.Message, name string) []string {
+	list := msg.Get(fieldOf(msg, name)).List()
+	if list.Len() == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		values = append(values, list.Get(i).String())
+	}
+	return values
+}
@@ -0,0 +1,319 @@
+// Package rpc serves the gRPC PortalService, which mirrors the public and
+// admin project API on top of the service layer. Messages are dynamic and
+// described by api/proto/portal/v1/portal.proto.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// streamedEvents are the event types sent to WatchProjects callers
+var streamedEvents = map[string]bool{
+	domain.EventProjectCreated:       true,
+	domain.EventProjectUpdated:       true,
+	domain.EventProjectStatusChanged: true,
+	domain.EventProjectDeleted:       true,
+}
+
+// unaryMethod handles a unary call with its decoded request
+type unaryMethod func(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error)
+
+// portalServer implements PortalService
+type portalServer struct {
+	messages
+	projectService *service.ProjectService
+	portalService  *service.PortalService
+	broker         *service.EventBroker
+}
+
+// NewServer returns a gRPC server with PortalService and server reflection
+// registered. Calls are authenticated with the JWT secret of the REST API.
+func NewServer(projectService *service.ProjectService, portalService *service.PortalService, broker *service.EventBroker, jwtSecret string) (*grpc.Server, error) {
+	file, err := buildFile()
+	if err != nil {
+		return nil, err
+	}
+
+	auth := authenticator{jwtSecret: jwtSecret}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+
+	s := &portalServer{
+		messages:       messages{file: file},
+		projectService: projectService,
+		portalService:  portalService,
+		broker:         broker,
+	}
+	server.RegisterService(s.serviceDesc(), s)
+	reflection.Register(server)
+
+	return server, nil
+}
+
+// serviceDesc describes PortalService the way protoc-gen-go-grpc would
+func (s *portalServer) serviceDesc() *grpc.ServiceDesc {
+	unary := map[string]unaryMethod{
+		"GetPortal":     s.getPortal,
+		"ListProjects":  s.listProjects,
+		"GetProject":    s.getProject,
+		"CreateProject": s.createProject,
+		"UpdateProject": s.updateProject,
+		"DeleteProject": s.deleteProject,
+		"UpdatePortal":  s.updatePortal,
+	}
+
+	desc := &grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "WatchProjects",
+			Handler:       s.watchProjects,
+			ServerStreams: true,
+		}},
+		Metadata: protoFile,
+	}
+
+	names := make([]string, 0, len(unary))
+	for name := range unary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler:    s.unaryHandler(name, unary[name]),
+		})
+	}
+
+	return desc
+}
+
+// unaryHandler decodes the request of method into its dynamic input message
+// and runs fn through the interceptors
+func (s *portalServer) unaryHandler(name string, fn unaryMethod) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	method := s.file.Services().Get(0).Methods().ByName(protoreflect.Name(name))
+	fullMethod := "/" + ServiceName + "/" + name
+
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := dynamicpb.NewMessage(method.Input())
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(ctx, req.(*dynamicpb.Message))
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: fullMethod}, handler)
+	}
+}
+
+func (s *portalServer) getPortal(ctx context.Context, _ *dynamicpb.Message) (*dynamicpb.Message, error) {
+	portal, err := s.portalService.GetPortal()
+	if errors.Is(err, service.ErrPortalNotConfigured) {
+		return nil, status.Error(codes.NotFound, "Portal not configured")
+	}
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.portal(portal), nil
+}
+
+// listProjects returns a page of projects; anonymous callers only see
+// active ones, like GET /api/v1/projects
+func (s *portalServer) listProjects(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	filter := projectFilter(req)
+	if err := binding.Validator.ValidateStruct(&filter); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	page, err := s.projectService.ListProjects(&filter, !callerFrom(ctx).authenticated)
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.projectPage(page), nil
+}
+
+func (s *portalServer) getProject(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	var (
+		project *domain.Project
+		err     error
+	)
+
+	if id := num(req, "id"); id != 0 {
+		project, err = s.projectService.GetProjectByID(id)
+	} else if slug := str(req, "slug"); slug != "" {
+		project, err = s.projectService.GetProjectBySlug(slug)
+		if errors.Is(err, service.ErrProjectNotFound) {
+			// Old slugs resolve to the renamed project
+			var current string
+			if current, err = s.projectService.CurrentSlug(slug); err == nil {
+				project, err = s.projectService.GetProjectBySlug(current)
+			}
+		}
+	} else {
+		return nil, codeError(domain.ErrCodeValidationFailed, "id or slug is required")
+	}
+
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.project(project), nil
+}
+
+func (s *portalServer) createProject(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	create := createProjectRequest(req)
+	if err := binding.Validator.ValidateStruct(&create); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	id, err := s.projectService.CreateProject(callerFrom(ctx).actor, &create)
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.reloadProject(ctx, id)
+}
+
+func (s *portalServer) updateProject(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	update := updateProjectRequest(req)
+	if err := binding.Validator.ValidateStruct(&update); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	id := num(req, "id")
+	if err := s.projectService.UpdateProject(callerFrom(ctx).actor, id, &update); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.reloadProject(ctx, id)
+}
+
+func (s *portalServer) deleteProject(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	if err := s.projectService.DeleteProject(callerFrom(ctx).actor, num(req, "id")); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.new("DeleteProjectResponse"), nil
+}
+
+func (s *portalServer) updatePortal(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	update := updatePortalRequest(req)
+	if err := binding.Validator.ValidateStruct(&update); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	if err := s.portalService.UpdatePortal(callerFrom(ctx).actor, &update); err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	portal, err := s.portalService.GetPortal()
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.portal(portal), nil
+}
+
+// reloadProject returns the stored state of a project after a mutation
+func (s *portalServer) reloadProject(ctx context.Context, id int) (*dynamicpb.Message, error) {
+	project, err := s.projectService.GetProjectByID(id)
+	if err != nil {
+		return nil, serviceError(ctx, err)
+	}
+
+	return s.project(project), nil
+}
+
+// watchProjects streams project events until the caller cancels. Callers
+// resuming with last_event_id first receive the events they missed; a
+// "reset" event tells them some were no longer buffered.
+func (s *portalServer) watchProjects(_ interface{}, stream grpc.ServerStream) error {
+	req := s.new("WatchProjectsRequest")
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	replay, complete, events, cancel := s.broker.Subscribe(num(req, "last_event_id"))
+	defer cancel()
+
+	if !complete {
+		reset := s.new("ProjectEvent")
+		set(reset, "type", "reset")
+		if err := stream.SendMsg(reset); err != nil {
+			return err
+		}
+	}
+	for _, event := range replay {
+		if err := s.sendEvent(stream, event); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the caller resumes
+				return status.Error(codes.Unavailable, "Subscriber fell behind; resume with last_event_id")
+			}
+			if err := s.sendEvent(stream, event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendEvent sends a streamed event; other types are skipped
+func (s *portalServer) sendEvent(stream grpc.ServerStream, event domain.Event) error {
+	if !streamedEvents[event.Type] {
+		return nil
+	}
+
+	var data domain.ProjectEventData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		return nil
+	}
+
+	msg := s.new("ProjectEvent")
+	set(msg, "id", event.Position)
+	set(msg, "type", event.Type)
+	if data.Project != nil {
+		set(msg, "project", s.project(data.Project))
+	}
+
+	changed := make([]string, 0, len(data.Changes))
+	for field := range data.Changes {
+		changed = append(changed, field)
+	}
+	sort.Strings(changed)
+	set(msg, "changed_fields", changed)
+	set(msg, "created_at", event.CreatedAt)
+
+	return stream.SendMsg(msg)
+}
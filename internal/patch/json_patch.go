@@ -0,0 +1,356 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Operation is a single RFC 6902 operation
+type Operation struct {
+	Op    string          `json:"op" binding:"required,oneof=add remove replace move copy test"`
+	Path  string          `json:"path" binding:"required"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Operations is an RFC 6902 JSON Patch, applied in order and as a whole or
+// not at all
+type Operations []Operation
+
+func parseOperations(body []byte) (Operations, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, invalid("a JSON Patch must be an array of operations")
+	}
+
+	ops := make(Operations, len(raw))
+	for i, members := range raw {
+		op := &ops[i]
+		for name, dst := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+			if value, ok := members[name]; ok {
+				if err := json.Unmarshal(value, dst); err != nil {
+					return nil, invalid("operation %d: %s must be a string", i, name)
+				}
+			}
+		}
+
+		value, hasValue := members["value"]
+		op.Value = value
+
+		switch op.Op {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, invalid("operation %d: %s requires a value", i, op.Op)
+			}
+		case "move", "copy":
+			if _, ok := members["from"]; !ok {
+				return nil, invalid("operation %d: %s requires from", i, op.Op)
+			}
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, invalid("operation %d: from: %v", i, err)
+			}
+		case "remove":
+		default:
+			return nil, invalid("operation %d: unknown op %q", i, op.Op)
+		}
+
+		if _, ok := members["path"]; !ok {
+			return nil, invalid("operation %d: path is required", i)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, invalid("operation %d: path: %v", i, err)
+		}
+	}
+
+	return ops, nil
+}
+
+// Apply implements Patch
+func (ops Operations) Apply(doc []byte) ([]byte, error) {
+	root, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		path, _ := parsePointer(op.Path)
+
+		switch op.Op {
+		case "add":
+			value, err := decode(op.Value)
+			if err != nil {
+				return nil, invalid("operation %d: %v", i, err)
+			}
+			root, err = add(root, path, value)
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+
+		case "remove":
+			root, _, err = remove(root, path)
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+
+		case "replace":
+			value, err := decode(op.Value)
+			if err != nil {
+				return nil, invalid("operation %d: %v", i, err)
+			}
+			if root, _, err = remove(root, path); err == nil {
+				root, err = add(root, path, value)
+			}
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+
+		case "move":
+			from, _ := parsePointer(op.From)
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, invalid("operation %d: cannot move a value into itself", i)
+			}
+			var value interface{}
+			if root, value, err = remove(root, from); err == nil {
+				root, err = add(root, path, value)
+			}
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+
+		case "copy":
+			from, _ := parsePointer(op.From)
+			value, err := get(root, from)
+			if err == nil {
+				root, err = add(root, path, clone(value))
+			}
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+
+		case "test":
+			want, err := decode(op.Value)
+			if err != nil {
+				return nil, invalid("operation %d: %v", i, err)
+			}
+			got, err := get(root, path)
+			if err != nil {
+				return nil, conflict("operation %d: %v", i, err)
+			}
+			if !equal(got, want) {
+				return nil, conflict("operation %d: test failed at %q", i, op.Path)
+			}
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, invalidPointer(pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+type pointerError string
+
+func (e pointerError) Error() string {
+	return string(e)
+}
+
+func invalidPointer(pointer string) error {
+	return pointerError("invalid JSON pointer " + strconv.Quote(pointer))
+}
+
+func notFound(path []string) error {
+	return pointerError("no value at /" + strings.Join(path, "/"))
+}
+
+// get returns the value at path
+func get(root interface{}, path []string) (interface{}, error) {
+	node := root
+	for i, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, notFound(path[:i+1])
+			}
+			node = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, notFound(path[:i+1])
+			}
+			node = n[index]
+		default:
+			return nil, notFound(path[:i+1])
+		}
+	}
+
+	return node, nil
+}
+
+// add inserts value at path, replacing an object member and shifting array
+// elements, and returns the new root
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = value
+		return root, nil
+
+	case []interface{}:
+		index := len(p)
+		if token != "-" {
+			if index, err = arrayIndex(token, len(p)); err != nil {
+				return nil, notFound(path)
+			}
+		}
+		grown := append(p[:index:index], append([]interface{}{value}, p[index:]...)...)
+		return replaceParent(root, path[:len(path)-1], grown)
+	}
+
+	return nil, notFound(path)
+}
+
+// remove deletes the value at path and returns the new root and the value
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, root, nil
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		value, ok := p[token]
+		if !ok {
+			return nil, nil, notFound(path)
+		}
+		delete(p, token)
+		return root, value, nil
+
+	case []interface{}:
+		index, err := arrayIndex(token, len(p)-1)
+		if err != nil {
+			return nil, nil, notFound(path)
+		}
+		value := p[index]
+		shrunk := append(p[:index:index], p[index+1:]...)
+		root, err = replaceParent(root, path[:len(path)-1], shrunk)
+		return root, value, err
+	}
+
+	return nil, nil, notFound(path)
+}
+
+// replaceParent stores a resized array back at path, as slices cannot be
+// resized in place
+func replaceParent(root interface{}, path []string, array []interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return array, nil
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = array
+	case []interface{}:
+		index, _ := arrayIndex(token, len(p)-1)
+		p[index] = array
+	}
+
+	return root, nil
+}
+
+// arrayIndex parses an array index token no greater than max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, invalidPointer(token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max {
+		return 0, invalidPointer(token)
+	}
+
+	return index, nil
+}
+
+// equal compares decoded JSON values, numbers by value
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
+
+// normalize turns numbers into floats so that DeepEqual compares them by
+// value
+func normalize(v interface{}) interface{} {
+	switch n := v.(type) {
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			out[key] = normalize(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, value := range n {
+			out[i] = normalize(value)
+		}
+		return out
+	}
+	return v
+}
+
+// clone deep-copies a decoded JSON value
+func clone(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			out[key] = clone(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, value := range n {
+			out[i] = clone(value)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,54 @@
+package patch
+
+import "encoding/json"
+
+// Merge is an RFC 7396 merge patch: members set to null are removed, objects
+// are merged recursively and any other value replaces the target's
+type Merge map[string]interface{}
+
+func parseMerge(body []byte) (Merge, error) {
+	v, err := decode(body)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+
+	// A non-object would replace the whole resource, which PATCH never does
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, invalid("a merge patch must be a JSON object")
+	}
+
+	return Merge(m), nil
+}
+
+// Apply implements Patch
+func (m Merge) Apply(doc []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(merge(target, map[string]interface{}(m)))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+
+	return t
+}
@@ -0,0 +1,83 @@
+// Package patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch
+// documents to JSON resources.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+)
+
+// Patch media types
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// Errors returned while parsing and applying patches
+var (
+	// ErrUnsupportedType is returned for a body that is neither kind of patch
+	ErrUnsupportedType = errors.New("unsupported patch media type")
+	// ErrConflict is returned when a JSON Patch does not fit the document,
+	// e.g. a test operation failed or a path does not exist
+	ErrConflict = errors.New("patch conflicts with the resource")
+)
+
+// Error describes a malformed patch document
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return "invalid patch: " + e.Reason
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrConflict, fmt.Sprintf(format, args...))
+}
+
+// Patch changes a JSON document
+type Patch interface {
+	// Apply returns doc with the patch applied
+	Apply(doc []byte) ([]byte, error)
+}
+
+// Parse reads a patch body by its media type. Plain application/json is
+// taken as a merge patch.
+func Parse(contentType string, body []byte) (Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	switch mediaType {
+	case MergePatchType, "application/json":
+		return parseMerge(body)
+	case JSONPatchType:
+		return parseOperations(body)
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// decode parses JSON keeping numbers exact
+func decode(data []byte) (interface{}, error) {
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,185 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// errInvalid stands for any *Error in test tables
+var errInvalid = errors.New("invalid")
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error // ErrConflict or errInvalid
+		// errSubstr is matched against the error message when set
+		errSubstr string
+	}{
+		// RFC 6902 Appendix A
+		{name: "add object member", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`, want: `{"baz": "qux", "foo": "bar"}`},
+		{name: "add array element", doc: `{"foo": ["bar", "baz"]}`, patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, want: `{"foo": ["bar", "qux", "baz"]}`},
+		{name: "remove object member", doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "remove", "path": "/baz"}]`, want: `{"foo": "bar"}`},
+		{name: "remove array element", doc: `{"foo": ["bar", "qux", "baz"]}`, patch: `[{"op": "remove", "path": "/foo/1"}]`, want: `{"foo": ["bar", "baz"]}`},
+		{name: "replace value", doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`, want: `{"baz": "boo", "foo": "bar"}`},
+		{
+			name:  "move value",
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{name: "move array element", doc: `{"foo": ["all", "grass", "cows", "eat"]}`, patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, want: `{"foo": ["all", "cows", "eat", "grass"]}`},
+		{name: "test success", doc: `{"baz": "qux", "foo": ["a", 2, "c"]}`, patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`, want: `{"baz": "qux", "foo": ["a", 2, "c"]}`},
+		{name: "test failure", doc: `{"baz": "qux"}`, patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`, err: ErrConflict, errSubstr: `test failed at "/baz"`},
+		{name: "add nested member", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, want: `{"foo": "bar", "child": {"grandchild": {}}}`},
+		{name: "add to nonexistent target", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, err: ErrConflict, errSubstr: "no value at /baz"},
+		{name: "escaped pointer", doc: `{"/": 9, "~1": 10}`, patch: `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`, want: `{"~1": 10}`},
+		{name: "numbers compare by value", doc: `{"foo": 1}`, patch: `[{"op": "test", "path": "/foo", "value": 1.0}]`, want: `{"foo": 1}`},
+		{name: "add array value", doc: `{"foo": ["bar"]}`, patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, want: `{"foo": ["bar", ["abc", "def"]]}`},
+
+		// Other operations
+		{name: "copy is deep", doc: `{"a": {"b": 1}}`, patch: `[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "replace", "path": "/c/b", "value": 2}]`, want: `{"a": {"b": 1}, "c": {"b": 2}}`},
+		{name: "replace whole document", doc: `{"a": 1}`, patch: `[{"op": "replace", "path": "", "value": {"b": 2}}]`, want: `{"b": 2}`},
+		{name: "append to array", doc: `{"tags": ["a"]}`, patch: `[{"op": "add", "path": "/tags/-", "value": "b"}]`, want: `{"tags": ["a", "b"]}`},
+		{name: "add at array end index", doc: `{"tags": ["a"]}`, patch: `[{"op": "add", "path": "/tags/1", "value": "b"}]`, want: `{"tags": ["a", "b"]}`},
+		{name: "empty patch", doc: `{"a": 1}`, patch: `[]`, want: `{"a": 1}`},
+
+		// Conflicts
+		{name: "remove missing member", doc: `{"a": 1}`, patch: `[{"op": "remove", "path": "/b"}]`, err: ErrConflict},
+		{name: "replace missing member", doc: `{"a": 1}`, patch: `[{"op": "replace", "path": "/b", "value": 2}]`, err: ErrConflict},
+		{name: "array index out of range", doc: `{"tags": ["a"]}`, patch: `[{"op": "add", "path": "/tags/2", "value": "b"}]`, err: ErrConflict},
+		{name: "array index with leading zero", doc: `{"tags": ["a", "b"]}`, patch: `[{"op": "remove", "path": "/tags/01"}]`, err: ErrConflict},
+		{name: "copy from missing", doc: `{"a": 1}`, patch: `[{"op": "copy", "from": "/b", "path": "/c"}]`, err: ErrConflict},
+		{name: "test missing", doc: `{"a": 1}`, patch: `[{"op": "test", "path": "/b", "value": 1}]`, err: ErrConflict},
+		{name: "failure later in the patch", doc: `{"a": 1}`, patch: `[{"op": "add", "path": "/b", "value": 2}, {"op": "remove", "path": "/c"}]`, err: ErrConflict, errSubstr: "operation 1"},
+
+		// Malformed patches
+		{name: "not an array", doc: `{}`, patch: `{"op": "add"}`, err: errInvalid, errSubstr: "must be an array of operations"},
+		{name: "unknown op", doc: `{}`, patch: `[{"op": "upsert", "path": "/a"}]`, err: errInvalid, errSubstr: `unknown op "upsert"`},
+		{name: "missing path", doc: `{}`, patch: `[{"op": "remove"}]`, err: errInvalid, errSubstr: "path is required"},
+		{name: "missing value", doc: `{}`, patch: `[{"op": "add", "path": "/a"}]`, err: errInvalid, errSubstr: "add requires a value"},
+		{name: "null value is a value", doc: `{}`, patch: `[{"op": "add", "path": "/a", "value": null}]`, want: `{"a": null}`},
+		{name: "missing from", doc: `{}`, patch: `[{"op": "move", "path": "/a"}]`, err: errInvalid, errSubstr: "move requires from"},
+		{name: "path without slash", doc: `{}`, patch: `[{"op": "remove", "path": "a"}]`, err: errInvalid, errSubstr: "invalid JSON pointer"},
+		{name: "path not a string", doc: `{}`, patch: `[{"op": "remove", "path": 1}]`, err: errInvalid, errSubstr: "path must be a string"},
+		{name: "move into itself", doc: `{"a": {"b": 1}}`, patch: `[{"op": "move", "from": "/a", "path": "/a/b"}]`, err: errInvalid, errSubstr: "cannot move a value into itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apply(JSONPatchType, tt.patch, tt.doc)
+			checkResult(t, got, err, tt.want, tt.err, tt.errSubstr)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7396 Appendix A, for object patches
+	tests := []struct {
+		name      string
+		doc       string
+		patch     string
+		want      string
+		errSubstr string
+	}{
+		{name: "replace member", doc: `{"a": "b"}`, patch: `{"a": "c"}`, want: `{"a": "c"}`},
+		{name: "add member", doc: `{"a": "b"}`, patch: `{"b": "c"}`, want: `{"a": "b", "b": "c"}`},
+		{name: "remove member", doc: `{"a": "b"}`, patch: `{"a": null}`, want: `{}`},
+		{name: "remove one of two", doc: `{"a": "b", "b": "c"}`, patch: `{"a": null}`, want: `{"b": "c"}`},
+		{name: "arrays replaced", doc: `{"a": ["b"]}`, patch: `{"a": "c"}`, want: `{"a": "c"}`},
+		{name: "value replaced by array", doc: `{"a": "c"}`, patch: `{"a": ["b"]}`, want: `{"a": ["b"]}`},
+		{name: "nested merge", doc: `{"a": {"b": "c"}}`, patch: `{"a": {"b": "d", "c": null}}`, want: `{"a": {"b": "d"}}`},
+		{name: "array of objects replaced", doc: `{"a": [{"b": "c"}]}`, patch: `{"a": [1]}`, want: `{"a": [1]}`},
+		{name: "nulls inside new objects dropped", doc: `{}`, patch: `{"a": {"bb": {"ccc": null}}}`, want: `{"a": {"bb": {}}}`},
+		{name: "non-object patch", doc: `{"a": "b"}`, patch: `["c"]`, errSubstr: "a merge patch must be a JSON object"},
+		{name: "trailing data", doc: `{}`, patch: `{"a": 1} {}`, errSubstr: "unexpected data after the JSON value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := apply(MergePatchType, tt.patch, tt.doc)
+			var wantErr error
+			if tt.errSubstr != "" {
+				wantErr = errInvalid
+			}
+			checkResult(t, got, err, tt.want, wantErr, tt.errSubstr)
+		})
+	}
+}
+
+func TestParseMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        interface{}
+		err         error
+	}{
+		{contentType: "application/merge-patch+json", want: Merge{}},
+		{contentType: "application/json; charset=utf-8", want: Merge{}},
+		{contentType: "application/json-patch+json", want: Operations{}},
+		{contentType: "text/plain", err: ErrUnsupportedType},
+		{contentType: "", err: ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		body := `{}`
+		if _, ok := tt.want.(Operations); ok {
+			body = `[]`
+		}
+
+		p, err := Parse(tt.contentType, []byte(body))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.contentType, err, tt.err)
+			continue
+		}
+		if tt.want != nil && reflect.TypeOf(p) != reflect.TypeOf(tt.want) {
+			t.Errorf("Parse(%q) = %T, want %T", tt.contentType, p, tt.want)
+		}
+	}
+}
+
+// apply parses patch as contentType and applies it to doc
+func apply(contentType, patch, doc string) ([]byte, error) {
+	p, err := Parse(contentType, []byte(patch))
+	if err != nil {
+		return nil, err
+	}
+	return p.Apply([]byte(doc))
+}
+
+func checkResult(t *testing.T, got []byte, err error, want string, wantErr error, errSubstr string) {
+	t.Helper()
+
+	if wantErr != nil {
+		var invalidErr *Error
+		switch {
+		case err == nil:
+			t.Fatalf("got %s, want error", got)
+		case wantErr == errInvalid && !errors.As(err, &invalidErr):
+			t.Fatalf("error = %v, want a *patch.Error", err)
+		case wantErr == ErrConflict && !errors.Is(err, ErrConflict):
+			t.Fatalf("error = %v, want ErrConflict", err)
+		case !strings.Contains(err.Error(), errSubstr):
+			t.Fatalf("error = %v, want %q", err, errSubstr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
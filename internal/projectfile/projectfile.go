@@ -0,0 +1,298 @@
+// Package projectfile reads and writes project lists as CSV, JSON or YAML
+// for bulk import and export.
+package projectfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/http/problem"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// tagSeparator joins tags in a single CSV cell
+const tagSeparator = ";"
+
+// csvColumns is the CSV header written on export
+var csvColumns = []string{"name", "slug", "description", "url", "icon_url", "status", "tags"}
+
+// ContentType returns the media type of format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatYAML:
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// FormatFromContentType returns the format of a media type, or an empty
+// string if it is not supported
+func FormatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	}
+	return ""
+}
+
+// Record converts a project into its file representation
+func Record(p domain.Project) domain.CreateProjectRequest {
+	return domain.CreateProjectRequest{
+		Name:        p.Name,
+		Slug:        p.Slug,
+		Description: p.Description,
+		URL:         p.URL,
+		IconURL:     p.IconURL,
+		Status:      p.Status,
+		Tags:        p.Tags,
+	}
+}
+
+// Write encodes projects in format
+func Write(w io.Writer, format string, projects []domain.Project) error {
+	records := make([]domain.CreateProjectRequest, 0, len(projects))
+	for _, p := range projects {
+		records = append(records, Record(p))
+	}
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write([]string{r.Name, r.Slug, r.Description, r.URL, r.IconURL, r.Status, strings.Join(r.Tags, tagSeparator)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// Read decodes the records of a file in format and validates each of them
+// with the CreateProjectRequest binding rules. A list may also be wrapped in
+// an object under "projects". Errors in a single record are reported on its
+// row; an error is only returned when the file as a whole is unreadable.
+func Read(r io.Reader, format string) ([]domain.ProjectImportRow, error) {
+	var (
+		rows []domain.ProjectImportRow
+		err  error
+	)
+
+	switch format {
+	case FormatCSV:
+		rows, err = readCSV(r)
+	case FormatJSON:
+		rows, err = readJSON(r)
+	case FormatYAML:
+		rows, err = readYAML(r)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		if rows[i].Errors != nil {
+			continue
+		}
+		if err := binding.Validator.ValidateStruct(&rows[i].Project); err != nil {
+			rows[i].Errors = rowErrors(err)
+		}
+	}
+
+	return rows, nil
+}
+
+func readCSV(r io.Reader) ([]domain.ProjectImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return []domain.ProjectImportRow{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, c := range csvColumns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+
+	rows := []domain.ProjectImportRow{}
+	for n := 1; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var tags []string
+		for _, tag := range strings.Split(cell("tags"), tagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		rows = append(rows, domain.ProjectImportRow{
+			Row: n,
+			Project: domain.CreateProjectRequest{
+				Name:        cell("name"),
+				Slug:        cell("slug"),
+				Description: cell("description"),
+				URL:         cell("url"),
+				IconURL:     cell("icon_url"),
+				Status:      cell("status"),
+				Tags:        tags,
+			},
+		})
+	}
+
+	return rows, nil
+}
+
+func readJSON(r io.Reader) ([]domain.ProjectImportRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var wrapped struct {
+			Projects []json.RawMessage `json:"projects"`
+		}
+		err = json.Unmarshal(data, &wrapped)
+		records = wrapped.Projects
+	} else {
+		err = json.Unmarshal(data, &records)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	rows := make([]domain.ProjectImportRow, 0, len(records))
+	for i, record := range records {
+		row := domain.ProjectImportRow{Row: i + 1}
+		if err := json.Unmarshal(record, &row.Project); err != nil {
+			row.Errors = rowErrors(err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readYAML(r io.Reader) ([]domain.ProjectImportRow, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return []domain.ProjectImportRow{}, nil
+		}
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	list := doc.Content[0]
+	if list.Kind == yaml.MappingNode {
+		var projects *yaml.Node
+		for i := 0; i+1 < len(list.Content); i += 2 {
+			if list.Content[i].Value == "projects" {
+				projects = list.Content[i+1]
+			}
+		}
+		if projects == nil {
+			return nil, errors.New("invalid YAML: expected a list of projects or a projects key")
+		}
+		list = projects
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, errors.New("invalid YAML: expected a list of projects")
+	}
+
+	rows := make([]domain.ProjectImportRow, 0, len(list.Content))
+	for i, item := range list.Content {
+		row := domain.ProjectImportRow{Row: i + 1}
+		if err := item.Decode(&row.Project); err != nil {
+			row.Errors = rowErrors(err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// rowErrors converts a decoding or validation error of one record into
+// field errors
+func rowErrors(err error) []domain.FieldError {
+	if fields, ok := problem.FieldErrors(err); ok {
+		return fields
+	}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return []domain.FieldError{{Rule: "type", Message: strings.Join(typeErr.Errors, "; ")}}
+	}
+
+	return []domain.FieldError{{Rule: "type", Message: err.Error()}}
+}
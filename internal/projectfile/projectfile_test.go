@@ -0,0 +1,185 @@
+package projectfile
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// summary describes a parsed row as "slug" or the failing "field:rule"s
+func summary(row domain.ProjectImportRow) string {
+	if len(row.Errors) == 0 {
+		return row.Project.Slug
+	}
+
+	var failed []string
+	for _, e := range row.Errors {
+		failed = append(failed, e.Field+":"+e.Rule)
+	}
+	return strings.Join(failed, ",")
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		want      []string
+		errSubstr string
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			input: "\ufeffName, slug,description,url,status,tags\n" +
+				"Shortlink,shortlink,URL shortener,https://s.example.com,active, go ; web ;\n" +
+				"Pastebin,,Share text,https://p.example.com,inactive,\n",
+			want: []string{"shortlink", ""},
+		},
+		{
+			name:   "csv invalid row",
+			format: FormatCSV,
+			input:  "name,description,url,status\nAb,Too short,not-a-url,active\n",
+			want:   []string{"name:min,url:url"},
+		},
+		{name: "csv header only", format: FormatCSV, input: "name,url\n", want: []string{}},
+		{name: "csv empty", format: FormatCSV, input: "", want: []string{}},
+		{name: "csv unknown column", format: FormatCSV, input: "name,owner\n", errSubstr: `unknown CSV column "owner"`},
+		{name: "csv malformed", format: FormatCSV, input: "name\n\"unterminated\n", errSubstr: "invalid CSV"},
+		{
+			name:   "json list",
+			format: FormatJSON,
+			input:  `[{"name": "Shortlink", "slug": "shortlink", "description": "d", "url": "https://s.example.com", "status": "active"}]`,
+			want:   []string{"shortlink"},
+		},
+		{
+			name:   "json wrapped",
+			format: FormatJSON,
+			input:  ` {"projects": [{"name": "Shortlink", "slug": "shortlink", "description": "d", "url": "https://s.example.com", "status": "active"}]}`,
+			want:   []string{"shortlink"},
+		},
+		{
+			name:   "json wrong type in one row",
+			format: FormatJSON,
+			input:  `[{"name": 1}, {"name": "Shortlink", "slug": "shortlink", "description": "d", "url": "https://s.example.com", "status": "gone"}]`,
+			want:   []string{"name:type", "status:oneof"},
+		},
+		{name: "json malformed", format: FormatJSON, input: `[{"name": }]`, errSubstr: "invalid JSON"},
+		{
+			name:   "yaml list",
+			format: FormatYAML,
+			input:  "- name: Shortlink\n  slug: shortlink\n  description: d\n  url: https://s.example.com\n  status: active\n  tags: [go]\n",
+			want:   []string{"shortlink"},
+		},
+		{
+			name:   "yaml wrapped",
+			format: FormatYAML,
+			input:  "projects:\n  - name: Shortlink\n    slug: shortlink\n    description: d\n    url: https://s.example.com\n    status: active\n",
+			want:   []string{"shortlink"},
+		},
+		{
+			name:   "yaml wrong type in one row",
+			format: FormatYAML,
+			input:  "- name: [a, b]\n- name: Shortlink\n  slug: shortlink\n  description: d\n  url: https://s.example.com\n  status: active\n",
+			want:   []string{":type", "shortlink"},
+		},
+		{name: "yaml empty", format: FormatYAML, input: "", want: []string{}},
+		{name: "yaml mapping without projects", format: FormatYAML, input: "name: Shortlink\n", errSubstr: "expected a list of projects or a projects key"},
+		{name: "yaml scalar", format: FormatYAML, input: "shortlink\n", errSubstr: "expected a list of projects"},
+		{name: "yaml malformed", format: FormatYAML, input: "- name: [\n", errSubstr: "invalid YAML"},
+		{name: "unsupported format", format: "xml", input: "<projects/>", errSubstr: `unsupported format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Read(strings.NewReader(tt.input), tt.format)
+			if tt.errSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+					t.Fatalf("Read() error = %v, want %q", err, tt.errSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+
+			got := []string{}
+			for i, row := range rows {
+				if row.Row != i+1 {
+					t.Errorf("rows[%d].Row = %d, want %d", i, row.Row, i+1)
+				}
+				got = append(got, summary(row))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVTags(t *testing.T) {
+	rows, err := Read(strings.NewReader("name,description,url,status,tags\nShortlink,d,https://s.example.com,active, go ; web ;\n"), FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "web"}; !reflect.DeepEqual(rows[0].Project.Tags, want) {
+		t.Errorf("tags = %q, want %q", rows[0].Project.Tags, want)
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	projects := []domain.Project{
+		{Name: "Shortlink", Slug: "shortlink", Description: "URL shortener, with \"quotes\"", URL: "https://s.example.com", Status: "active", Tags: []string{"go", "web"}},
+		{Name: "Pastebin", Slug: "pastebin", Description: "Multi\nline", URL: "https://p.example.com", IconURL: "https://p.example.com/icon.png", Status: "maintenance"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, format, projects); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := Read(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != len(projects) {
+				t.Fatalf("read %d rows, want %d", len(rows), len(projects))
+			}
+			for i, row := range rows {
+				if row.Errors != nil {
+					t.Errorf("row %d errors: %+v", i+1, row.Errors)
+				}
+				if want := Record(projects[i]); !reflect.DeepEqual(row.Project, want) {
+					t.Errorf("row %d = %+v, want %+v", i+1, row.Project, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"text/csv; charset=utf-8", FormatCSV},
+		{"application/json", FormatJSON},
+		{"application/x-yaml", FormatYAML},
+		{"text/yaml", FormatYAML},
+		{"application/xml", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := FormatFromContentType(tt.contentType); got != tt.want {
+			t.Errorf("FormatFromContentType(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+		if tt.want != "" && FormatFromContentType(ContentType(tt.want)) != tt.want {
+			t.Errorf("ContentType(%q) does not map back", tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeySet holds the API keys issued to clients by the hex SHA-256 digest of
+// each, so that configuration never contains the keys themselves
+type KeySet map[string]bool
+
+// NewKeySet returns the set of the given digests
+func NewKeySet(digests []string) (KeySet, error) {
+	keys := KeySet{}
+	for _, digest := range digests {
+		digest = strings.ToLower(strings.TrimSpace(digest))
+		if digest == "" {
+			continue
+		}
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid API key digest %q: want 64 hex characters", digest)
+		}
+		keys[digest] = true
+	}
+	return keys, nil
+}
+
+// Identify returns the bucket identity of key, or false unless key was
+// issued. Unknown keys must not get buckets of their own, or a client
+// could reset its limit by sending a new one.
+func (k KeySet) Identify(key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	digest := hex.EncodeToString(sum[:])
+	if !k[digest] {
+		return "", false
+	}
+
+	return "key:" + digest[:16], true
+}
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func digest(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestKeySet(t *testing.T) {
+	keys, err := NewKeySet([]string{digest("issued"), " " + strings.ToUpper(digest("other")) + " ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{name: "issued", key: "issued", ok: true},
+		{name: "digest normalized", key: "other", ok: true},
+		{name: "unknown", key: "forged"},
+		{name: "empty", key: ""},
+		{name: "digest itself", key: digest("issued")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := keys.Identify(tt.key)
+			if ok != tt.ok {
+				t.Fatalf("Identify(%q) ok = %v, want %v", tt.key, ok, tt.ok)
+			}
+			if ok && id != "key:"+digest(tt.key)[:16] {
+				t.Errorf("Identify(%q) = %q", tt.key, id)
+			}
+		})
+	}
+
+	if _, err := NewKeySet([]string{"not-a-digest"}); err == nil {
+		t.Error("NewKeySet accepted an invalid digest")
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often full buckets are dropped from a MemoryStore
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	policy Policy
+	last   time.Time
+}
+
+// refill adds the tokens accrued since the last request
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.policy.Burst), b.tokens+now.Sub(b.last).Seconds()*b.policy.rate())
+	b.last = now
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so
+// it suits single-node deployments.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now(), now: time.Now}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(_ context.Context, key string, policy Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.policy != policy {
+		b = &bucket{tokens: float64(policy.Burst), policy: policy, last: now}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return result(policy, b.tokens, allowed), nil
+}
+
+// sweep drops buckets that have refilled completely, as a new bucket would
+// be identical
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.policy.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	policy := Policy{Requests: 60, Period: time.Minute, Burst: 3} // one token per second
+
+	type take struct {
+		after     time.Duration // since the previous take
+		allowed   bool
+		remaining int
+		retry     time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst then empty",
+			takes: []take{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retry: time.Second},
+			},
+		},
+		{
+			name: "refill one token",
+			takes: []take{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{after: time.Second, allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retry: time.Second},
+			},
+		},
+		{
+			name: "partial refill",
+			takes: []take{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{after: 500 * time.Millisecond, allowed: false, remaining: 0, retry: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill capped at burst",
+			takes: []take{
+				{allowed: true, remaining: 2},
+				{after: time.Hour, allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			store := NewMemoryStore()
+			store.now = func() time.Time { return now }
+			store.lastSweep = now
+
+			for i, tk := range tt.takes {
+				now = now.Add(tk.after)
+
+				r, err := store.Take(context.Background(), "k", policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.Allowed != tk.allowed || r.Remaining != tk.remaining || r.RetryAfter != tk.retry {
+					t.Errorf("take %d = allowed %v, remaining %d, retry %v; want %v, %d, %v",
+						i, r.Allowed, r.Remaining, r.RetryAfter, tk.allowed, tk.remaining, tk.retry)
+				}
+				if r.Limit != policy.Burst {
+					t.Errorf("take %d limit = %d, want %d", i, r.Limit, policy.Burst)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Requests: 1, Period: time.Hour, Burst: 1}
+
+	for _, key := range []string{"a", "b"} {
+		if r, _ := store.Take(context.Background(), key, policy); !r.Allowed {
+			t.Errorf("first take of %q denied", key)
+		}
+	}
+	if r, _ := store.Take(context.Background(), "a", policy); r.Allowed {
+		t.Error("second take of \"a\" allowed")
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		in   config.RateLimitPolicy
+		want Policy
+	}{
+		{name: "burst defaults to requests", in: config.RateLimitPolicy{Requests: 10, Period: time.Second}, want: Policy{Requests: 10, Period: time.Second, Burst: 10}},
+		{name: "period defaults to a minute", in: config.RateLimitPolicy{Requests: 10, Burst: 5}, want: Policy{Requests: 10, Period: time.Minute, Burst: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPolicy(tt.in)
+			if got != tt.want {
+				t.Errorf("NewPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package ratelimit implements token-bucket rate limiting with in-memory and
+// Redis backed stores.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+)
+
+// Identities a policy can count requests by
+const (
+	ByIP     = "ip"
+	ByUser   = "user"
+	ByAPIKey = "api_key"
+)
+
+// Stores a limiter can keep its buckets in
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Policy is a token bucket: it holds up to Burst tokens and refills
+// Requests tokens every Period. Each request takes one token.
+type Policy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// NewPolicy returns the bucket of a configured policy; Burst defaults to
+// Requests
+func NewPolicy(cfg config.RateLimitPolicy) Policy {
+	p := Policy{Requests: cfg.Requests, Period: cfg.Period, Burst: cfg.Burst}
+	if p.Period <= 0 {
+		p.Period = time.Minute
+	}
+	if p.Burst <= 0 {
+		p.Burst = p.Requests
+	}
+	return p
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Requests > 0
+}
+
+// rate returns the refill rate in tokens per second
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when not Allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key
+type Store interface {
+	// Take takes a token from the bucket of key, creating it full
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// result describes a bucket left with tokens after a request
+func result(policy Policy, tokens float64, allowed bool) Result {
+	rate := policy.rate()
+
+	r := Result{
+		Allowed:   allowed,
+		Limit:     policy.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(policy.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces rate limit buckets in Redis
+const keyPrefix = "ratelimit:"
+
+// takeScript refills and takes from a bucket atomically. Time comes from
+// the Redis server so that every node shares one clock. Buckets expire
+// once they would be full again.
+var takeScript = redis.NewScript(`
+-- Allow writes after TIME on Redis < 5
+redis.replicate_commands()
+
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis so that limits hold across instances
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a store on client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take implements Store
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{keyPrefix + key}, policy.rate(), policy.Burst).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("redis error: %w", err)
+	}
+
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	remaining, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected reply %v", reply)
+	}
+
+	return result(policy, tokens, allowed == 1), nil
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// AuditService handles audit log operations
+type AuditService struct {
+	db                 *sql.DB
+	checkpointSecret   string
+	checkpointInterval int
+}
+
+// NewAuditService creates a new audit service. Every checkpointInterval
+// entries a checkpoint signed with checkpointSecret is stored.
+func NewAuditService(db *sql.DB, checkpointSecret string, checkpointInterval int) *AuditService {
+	if checkpointInterval <= 0 {
+		checkpointInterval = 100
+	}
+
+	return &AuditService{
+		db:                 db,
+		checkpointSecret:   checkpointSecret,
+		checkpointInterval: checkpointInterval,
+	}
+}
+
+// Record writes an audit entry inside the given transaction so that it is
+// committed or rolled back together with the change it describes. The entry
+// is linked into the hash chain after the most recent entry.
+func (s *AuditService) Record(tx *sql.Tx, actor domain.Actor, action, resource string, resourceID int, details *domain.AuditDetails) error {
+	entry := domain.AuditLog{
+		Action:    action,
+		Resource:  resource,
+		IPAddress: actor.IPAddress,
+		UserAgent: actor.UserAgent,
+		// Postgres stores microseconds, so truncate before hashing
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if actor.UserID != 0 {
+		entry.UserID = &actor.UserID
+	}
+	if resourceID != 0 {
+		entry.ResourceID = &resourceID
+	}
+
+	var detailsJSON sql.NullString
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("audit details encoding failed: %w", err)
+		}
+		entry.Details = string(data)
+		detailsJSON = sql.NullString{String: entry.Details, Valid: true}
+	}
+
+	prevHash, err := s.lockChainHead(tx)
+	if err != nil {
+		return err
+	}
+	entry.PrevHash = prevHash
+	entry.Hash = auditEntryHash(&entry)
+
+	err = tx.QueryRow(
+		"INSERT INTO audit_logs (user_id, action, resource, resource_id, details, ip_address, user_agent, prev_hash, hash, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id",
+		nullInt(actor.UserID), action, resource, nullInt(resourceID), detailsJSON, actor.IPAddress, actor.UserAgent, entry.PrevHash, entry.Hash, entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("audit log error: %w", err)
+	}
+
+	return s.maybeCheckpoint(tx, &entry)
+}
+
+// RecordStandalone writes an audit entry in its own transaction, for events
+// such as login attempts that do not modify any other table
+func (s *AuditService) RecordStandalone(actor domain.Actor, action, resource string, resourceID int, details *domain.AuditDetails) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return s.Record(tx, actor, action, resource, resourceID, details)
+	})
+}
+
+// Audit log page size limits
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+const auditLogColumns = "id, user_id, action, resource, resource_id, details, ip_address, user_agent, prev_hash, hash, created_at"
+
+// ListAuditLogs returns a page of audit entries matching the filter, newest
+// first. Pages are addressed by the opaque cursor returned in NextCursor.
+func (s *AuditService) ListAuditLogs(filter *domain.AuditLogFilter) (*domain.PaginatedResponse, error) {
+	qf, err := auditLogQueryFilter(filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_logs"+qf.where(), qf.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if filter.Cursor != "" {
+		qf, err = auditLogQueryFilter(filter, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pageSize := clampPageSize(filter.PageSize, defaultAuditPageSize, maxAuditPageSize)
+
+	// Fetch one extra row to find out whether another page exists
+	query := "SELECT " + auditLogColumns + " FROM audit_logs" + qf.where() + " ORDER BY id DESC LIMIT " + qf.next(pageSize+1)
+
+	logs := []domain.AuditLog{}
+	err = s.queryAuditLogs(query, qf.args, func(entry domain.AuditLog) error {
+		logs = append(logs, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nextCursor string
+	if len(logs) > pageSize {
+		logs = logs[:pageSize]
+		nextCursor = encodeCursor(logs[pageSize-1].ID)
+	}
+
+	// Audit logs are only paged by cursor, which is reported as page 0
+	resp := domain.NewPaginatedResponse(logs, total, 0, pageSize)
+	resp.NextCursor = nextCursor
+
+	return resp, nil
+}
+
+// ExportAuditLogs streams every audit entry matching the filter, oldest
+// first, to fn. Cursor and page size are ignored.
+func (s *AuditService) ExportAuditLogs(filter *domain.AuditLogFilter, fn func(domain.AuditLog) error) error {
+	qf, err := auditLogQueryFilter(filter, false)
+	if err != nil {
+		return err
+	}
+
+	query := "SELECT " + auditLogColumns + " FROM audit_logs" + qf.where() + " ORDER BY id ASC"
+
+	return s.queryAuditLogs(query, qf.args, fn)
+}
+
+// queryAuditLogs runs query and passes every scanned entry to fn
+func (s *AuditService) queryAuditLogs(query string, args []interface{}, fn func(domain.AuditLog) error) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// auditLogQueryFilter translates the filter into SQL conditions. The cursor
+// condition is only included when withCursor is set.
+func auditLogQueryFilter(filter *domain.AuditLogFilter, withCursor bool) (*queryFilter, error) {
+	qf := &queryFilter{}
+
+	if filter.UserID != 0 {
+		qf.add("user_id = ?", filter.UserID)
+	}
+	if filter.Action != "" {
+		qf.add("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		qf.add("resource = ?", filter.Resource)
+	}
+	if filter.ResourceID != 0 {
+		qf.add("resource_id = ?", filter.ResourceID)
+	}
+	if filter.IPAddress != "" {
+		qf.add("ip_address = ?", filter.IPAddress)
+	}
+	if !filter.From.IsZero() {
+		qf.add("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		qf.add("created_at < ?", filter.To)
+	}
+
+	if withCursor && filter.Cursor != "" {
+		id, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		qf.add("id < ?", id)
+	}
+
+	return qf, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAuditLog scans a row selected with auditLogColumns
+func scanAuditLog(row rowScanner) (*domain.AuditLog, error) {
+	var (
+		entry      domain.AuditLog
+		userID     sql.NullInt64
+		resource   sql.NullString
+		resourceID sql.NullInt64
+		details    sql.NullString
+		ipAddress  sql.NullString
+		userAgent  sql.NullString
+		prevHash   sql.NullString
+		hash       sql.NullString
+	)
+
+	err := row.Scan(&entry.ID, &userID, &entry.Action, &resource, &resourceID, &details, &ipAddress, &userAgent, &prevHash, &hash, &entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("scan error: %w", err)
+	}
+
+	if userID.Valid {
+		id := int(userID.Int64)
+		entry.UserID = &id
+	}
+	if resourceID.Valid {
+		id := int(resourceID.Int64)
+		entry.ResourceID = &id
+	}
+	entry.Resource = resource.String
+	entry.Details = details.String
+	entry.IPAddress = ipAddress.String
+	entry.UserAgent = userAgent.String
+	entry.PrevHash = prevHash.String
+	entry.Hash = hash.String
+
+	return &entry, nil
+}
+
+// diffDetails builds audit details holding both snapshots and the set of
+// top-level JSON fields that differ between them
+func diffDetails(before, after interface{}) *domain.AuditDetails {
+	details := &domain.AuditDetails{Before: before, After: after}
+
+	beforeFields := toJSONFields(before)
+	afterFields := toJSONFields(after)
+
+	changes := map[string]domain.AuditChange{}
+	for key, to := range afterFields {
+		from, ok := beforeFields[key]
+		if !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = domain.AuditChange{From: from, To: to}
+		}
+	}
+	for key, from := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			changes[key] = domain.AuditChange{From: from, To: nil}
+		}
+	}
+
+	// Timestamps change on every write and only add noise to the diff
+	delete(changes, "updated_at")
+
+	if len(changes) > 0 {
+		details.Changes = changes
+	}
+
+	return details
+}
+
+// toJSONFields converts a value into its JSON object representation
+func toJSONFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+
+	return fields
+}
+
+// nullInt maps zero IDs to NULL
+func nullInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v != 0}
+}
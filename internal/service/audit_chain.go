@@ -0,0 +1,288 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// auditChainLockKey is the advisory lock that serialises appends to the
+// audit chain so that concurrent transactions never share a predecessor
+const auditChainLockKey = 7310457
+
+// lockChainHead takes the chain lock for the rest of the transaction and
+// returns the hash of the most recent chained entry
+func (s *AuditService) lockChainHead(tx *sql.Tx) (string, error) {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", auditChainLockKey); err != nil {
+		return "", fmt.Errorf("audit log error: %w", err)
+	}
+
+	var prevHash string
+	err := tx.QueryRow(
+		"SELECT hash FROM audit_logs WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1",
+	).Scan(&prevHash)
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("audit log error: %w", err)
+	}
+
+	return prevHash, nil
+}
+
+// maybeCheckpoint stores a signed checkpoint when at least checkpointInterval
+// entries were appended since the previous one
+func (s *AuditService) maybeCheckpoint(tx *sql.Tx, entry *domain.AuditLog) error {
+	var lastLogID int
+	err := tx.QueryRow("SELECT COALESCE(MAX(last_log_id), 0) FROM audit_checkpoints").Scan(&lastLogID)
+	if err != nil {
+		return fmt.Errorf("audit checkpoint error: %w", err)
+	}
+
+	var pending int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM audit_logs WHERE id > $1 AND hash IS NOT NULL",
+		lastLogID,
+	).Scan(&pending)
+	if err != nil {
+		return fmt.Errorf("audit checkpoint error: %w", err)
+	}
+
+	if pending < s.checkpointInterval {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO audit_checkpoints (last_log_id, last_hash, signature) VALUES ($1, $2, $3)",
+		entry.ID, entry.Hash, s.signCheckpoint(entry.ID, entry.Hash),
+	)
+	if err != nil {
+		return fmt.Errorf("audit checkpoint error: %w", err)
+	}
+
+	return nil
+}
+
+// signCheckpoint returns the HMAC-SHA256 signature of a checkpoint
+func (s *AuditService) signCheckpoint(lastLogID int, lastHash string) string {
+	mac := hmac.New(sha256.New, []byte(s.checkpointSecret))
+	fmt.Fprintf(mac, "%d:%s", lastLogID, lastHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyChain walks the audit chain from the oldest entry, recomputing every
+// hash, then checks each checkpoint signature against the entry it names.
+// The first broken or missing link is reported in the result. A chain whose
+// oldest entries were removed by retention is accepted only if it was cut
+// right after a signed checkpoint.
+func (s *AuditService) VerifyChain() (*domain.AuditVerifyResult, error) {
+	v := &chainVerifier{
+		result:   &domain.AuditVerifyResult{Valid: true},
+		sign:     s.signCheckpoint,
+		anchored: s.isCheckpointedHash,
+	}
+
+	if err := s.queryAuditLogs("SELECT "+auditLogColumns+" FROM audit_logs ORDER BY id ASC", nil, v.entry); err != nil {
+		return nil, err
+	}
+
+	if !v.result.Valid {
+		return v.result, nil
+	}
+
+	if err := s.verifyCheckpoints(v); err != nil {
+		return nil, err
+	}
+
+	return v.result, nil
+}
+
+// chainVerifier checks audit entries fed to it in ID order, then the
+// checkpoints, and records the first broken link in result
+type chainVerifier struct {
+	result *domain.AuditVerifyResult
+	// sign returns the signature of a checkpoint
+	sign func(lastLogID int, lastHash string) string
+	// anchored reports whether a validly signed checkpoint names a hash
+	anchored func(hash string) (bool, error)
+
+	prevHash       string
+	chained        bool
+	firstChainedID int
+}
+
+// entry checks the next entry of the chain
+func (v *chainVerifier) entry(entry domain.AuditLog) error {
+	if !v.result.Valid {
+		return nil
+	}
+
+	// Entries written before the chain existed carry no hash
+	if entry.Hash == "" {
+		if v.chained {
+			markBroken(v.result, entry.ID, 0, "entry has no hash")
+			return nil
+		}
+		v.result.LegacyEntries++
+		return nil
+	}
+
+	if !v.chained && entry.PrevHash != "" {
+		anchored, err := v.anchored(entry.PrevHash)
+		if err != nil {
+			return err
+		}
+		if anchored {
+			v.prevHash = entry.PrevHash
+		}
+	}
+	if !v.chained {
+		v.firstChainedID = entry.ID
+	}
+	v.chained = true
+	v.result.EntriesChecked++
+
+	if entry.PrevHash != v.prevHash {
+		markBroken(v.result, entry.ID, 0, "previous entry is missing or was modified")
+		return nil
+	}
+
+	if auditEntryHash(&entry) != entry.Hash {
+		markBroken(v.result, entry.ID, 0, "entry content does not match its hash")
+		return nil
+	}
+
+	v.prevHash = entry.Hash
+	return nil
+}
+
+// checkpoint checks a checkpoint's signature and that the entry it names
+// still exists with the recorded hash; logHash is the hash of that entry,
+// invalid when it no longer exists. Entries older than the first chained
+// entry have been pruned and are not expected to exist. It reports whether
+// the chain is still valid.
+func (v *chainVerifier) checkpoint(cp domain.AuditCheckpoint, logHash sql.NullString) bool {
+	v.result.CheckpointsChecked++
+
+	expected := v.sign(cp.LastLogID, cp.LastHash)
+	switch {
+	case !hmac.Equal([]byte(expected), []byte(cp.Signature)):
+		markBroken(v.result, 0, cp.ID, "checkpoint signature is invalid")
+	case !logHash.Valid && cp.LastLogID < v.firstChainedID:
+		return true
+	case !logHash.Valid:
+		markBroken(v.result, cp.LastLogID, cp.ID, "checkpointed entry is missing")
+	case logHash.String != cp.LastHash:
+		markBroken(v.result, cp.LastLogID, cp.ID, "checkpointed entry hash does not match")
+	default:
+		return true
+	}
+	return false
+}
+
+// verifyCheckpoints feeds every checkpoint, with the current hash of the
+// entry it names, to v until one is broken
+func (s *AuditService) verifyCheckpoints(v *chainVerifier) error {
+	rows, err := s.db.Query(
+		"SELECT c.id, c.last_log_id, c.last_hash, c.signature, c.created_at, l.hash FROM audit_checkpoints c LEFT JOIN audit_logs l ON l.id = c.last_log_id ORDER BY c.id ASC",
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cp      domain.AuditCheckpoint
+			logHash sql.NullString
+		)
+		if err := rows.Scan(&cp.ID, &cp.LastLogID, &cp.LastHash, &cp.Signature, &cp.CreatedAt, &logHash); err != nil {
+			return fmt.Errorf("scan error: %w", err)
+		}
+		if !v.checkpoint(cp, logHash) {
+			return nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// isCheckpointedHash reports whether a validly signed checkpoint names hash
+func (s *AuditService) isCheckpointedHash(hash string) (bool, error) {
+	rows, err := s.db.Query(
+		"SELECT last_log_id, signature FROM audit_checkpoints WHERE last_hash = $1",
+		hash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			lastLogID int
+			signature string
+		)
+		if err := rows.Scan(&lastLogID, &signature); err != nil {
+			return false, fmt.Errorf("scan error: %w", err)
+		}
+		if hmac.Equal([]byte(s.signCheckpoint(lastLogID, hash)), []byte(signature)) {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// markBroken records the first broken link in the verification result
+func markBroken(result *domain.AuditVerifyResult, entryID, checkpointID int, reason string) {
+	result.Valid = false
+	result.BrokenEntryID = entryID
+	result.BrokenCheckpointID = checkpointID
+	result.Reason = reason
+}
+
+// auditHashInput is the canonical form of an entry that gets hashed
+type auditHashInput struct {
+	UserID     *int   `json:"user_id"`
+	Action     string `json:"action"`
+	Resource   string `json:"resource"`
+	ResourceID *int   `json:"resource_id"`
+	Details    string `json:"details"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  string `json:"created_at"`
+	PrevHash   string `json:"prev_hash"`
+}
+
+// auditEntryHash returns the hex SHA-256 of an entry's content chained to
+// the hash of its predecessor
+func auditEntryHash(entry *domain.AuditLog) string {
+	data, _ := json.Marshal(auditHashInput{
+		UserID:     entry.UserID,
+		Action:     entry.Action,
+		Resource:   entry.Resource,
+		ResourceID: entry.ResourceID,
+		Details:    entry.Details,
+		IPAddress:  entry.IPAddress,
+		UserAgent:  entry.UserAgent,
+		CreatedAt:  entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		PrevHash:   entry.PrevHash,
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// testChain returns n chained entries with IDs 1 to n
+func testChain(n int) []domain.AuditLog {
+	entries := make([]domain.AuditLog, 0, n)
+	prev := ""
+	for id := 1; id <= n; id++ {
+		resourceID := id
+		e := domain.AuditLog{
+			ID:         id,
+			Action:     domain.AuditActionProjectUpdate,
+			Resource:   domain.AuditResourceProject,
+			ResourceID: &resourceID,
+			Details:    `{"changes":{}}`,
+			IPAddress:  "192.0.2.1",
+			CreatedAt:  time.Date(2024, 6, 1, 12, 0, id, 0, time.UTC),
+			PrevHash:   prev,
+		}
+		e.Hash = auditEntryHash(&e)
+		prev = e.Hash
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// verify runs a chainVerifier over entries and checkpoints held in memory,
+// as VerifyChain does over the tables
+func verify(t *testing.T, s *AuditService, entries []domain.AuditLog, checkpoints []domain.AuditCheckpoint) *domain.AuditVerifyResult {
+	t.Helper()
+
+	v := &chainVerifier{
+		result: &domain.AuditVerifyResult{Valid: true},
+		sign:   s.signCheckpoint,
+		anchored: func(hash string) (bool, error) {
+			for _, cp := range checkpoints {
+				if cp.LastHash == hash && s.signCheckpoint(cp.LastLogID, hash) == cp.Signature {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	}
+
+	for _, e := range entries {
+		if err := v.entry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !v.result.Valid {
+		return v.result
+	}
+
+	hashes := map[int]string{}
+	for _, e := range entries {
+		hashes[e.ID] = e.Hash
+	}
+	for _, cp := range checkpoints {
+		hash, ok := hashes[cp.LastLogID]
+		if !v.checkpoint(cp, sql.NullString{String: hash, Valid: ok}) {
+			break
+		}
+	}
+
+	return v.result
+}
+
+func TestVerifyChain(t *testing.T) {
+	s := &AuditService{checkpointSecret: "secret"}
+
+	// checkpointAt returns a checkpoint of entry id of the full chain
+	full := testChain(6)
+	checkpointAt := func(cpID, id int) domain.AuditCheckpoint {
+		hash := full[id-1].Hash
+		return domain.AuditCheckpoint{ID: cpID, LastLogID: id, LastHash: hash, Signature: s.signCheckpoint(id, hash)}
+	}
+
+	tests := []struct {
+		name        string
+		entries     func() []domain.AuditLog
+		checkpoints []domain.AuditCheckpoint
+		want        domain.AuditVerifyResult
+	}{
+		{
+			name:    "empty",
+			entries: func() []domain.AuditLog { return nil },
+			want:    domain.AuditVerifyResult{Valid: true},
+		},
+		{
+			name:        "intact",
+			entries:     func() []domain.AuditLog { return testChain(6) },
+			checkpoints: []domain.AuditCheckpoint{checkpointAt(1, 3), checkpointAt(2, 6)},
+			want:        domain.AuditVerifyResult{Valid: true, EntriesChecked: 6, CheckpointsChecked: 2},
+		},
+		{
+			name: "legacy entries before the chain",
+			entries: func() []domain.AuditLog {
+				legacy := []domain.AuditLog{{ID: -1}, {ID: 0}}
+				return append(legacy, testChain(3)...)
+			},
+			want: domain.AuditVerifyResult{Valid: true, EntriesChecked: 3, LegacyEntries: 2},
+		},
+		{
+			name: "entry without hash inside the chain",
+			entries: func() []domain.AuditLog {
+				entries := testChain(4)
+				entries[2].Hash = ""
+				return entries
+			},
+			want: domain.AuditVerifyResult{EntriesChecked: 2, BrokenEntryID: 3, Reason: "entry has no hash"},
+		},
+		{
+			name: "modified content",
+			entries: func() []domain.AuditLog {
+				entries := testChain(4)
+				entries[2].Details = `{"changes":{"status":{"from":"active","to":"inactive"}}}`
+				return entries
+			},
+			want: domain.AuditVerifyResult{EntriesChecked: 3, BrokenEntryID: 3, Reason: "entry content does not match its hash"},
+		},
+		{
+			name: "modified entry rehashed",
+			entries: func() []domain.AuditLog {
+				entries := testChain(4)
+				entries[2].Details = `{}`
+				entries[2].Hash = auditEntryHash(&entries[2])
+				return entries
+			},
+			want: domain.AuditVerifyResult{EntriesChecked: 4, BrokenEntryID: 4, Reason: "previous entry is missing or was modified"},
+		},
+		{
+			name: "missing row",
+			entries: func() []domain.AuditLog {
+				entries := testChain(4)
+				return append(entries[:2:2], entries[3:]...)
+			},
+			want: domain.AuditVerifyResult{EntriesChecked: 3, BrokenEntryID: 4, Reason: "previous entry is missing or was modified"},
+		},
+		{
+			name:        "pruned at a checkpoint",
+			entries:     func() []domain.AuditLog { return testChain(6)[3:] },
+			checkpoints: []domain.AuditCheckpoint{checkpointAt(1, 3), checkpointAt(2, 6)},
+			want:        domain.AuditVerifyResult{Valid: true, EntriesChecked: 3, CheckpointsChecked: 2},
+		},
+		{
+			name:        "pruned between checkpoints",
+			entries:     func() []domain.AuditLog { return testChain(6)[4:] },
+			checkpoints: []domain.AuditCheckpoint{checkpointAt(1, 3)},
+			want:        domain.AuditVerifyResult{EntriesChecked: 1, BrokenEntryID: 5, Reason: "previous entry is missing or was modified"},
+		},
+		{
+			name:    "pruned at a forged checkpoint",
+			entries: func() []domain.AuditLog { return testChain(6)[3:] },
+			checkpoints: []domain.AuditCheckpoint{func() domain.AuditCheckpoint {
+				cp := checkpointAt(1, 3)
+				cp.Signature = (&AuditService{checkpointSecret: "guessed"}).signCheckpoint(cp.LastLogID, cp.LastHash)
+				return cp
+			}()},
+			want: domain.AuditVerifyResult{EntriesChecked: 1, BrokenEntryID: 4, Reason: "previous entry is missing or was modified"},
+		},
+		{
+			name:    "checkpoint signature mismatch",
+			entries: func() []domain.AuditLog { return testChain(6) },
+			checkpoints: []domain.AuditCheckpoint{checkpointAt(1, 3), func() domain.AuditCheckpoint {
+				cp := checkpointAt(2, 6)
+				cp.Signature = s.signCheckpoint(5, cp.LastHash)
+				return cp
+			}()},
+			want: domain.AuditVerifyResult{EntriesChecked: 6, CheckpointsChecked: 2, BrokenCheckpointID: 2, Reason: "checkpoint signature is invalid"},
+		},
+		{
+			name:    "checkpointed entry hash mismatch",
+			entries: func() []domain.AuditLog { return testChain(6) },
+			checkpoints: []domain.AuditCheckpoint{func() domain.AuditCheckpoint {
+				cp := checkpointAt(1, 3)
+				cp.LastHash = full[1].Hash
+				cp.Signature = s.signCheckpoint(cp.LastLogID, cp.LastHash)
+				return cp
+			}()},
+			want: domain.AuditVerifyResult{EntriesChecked: 6, CheckpointsChecked: 1, BrokenEntryID: 3, BrokenCheckpointID: 1, Reason: "checkpointed entry hash does not match"},
+		},
+		{
+			name:        "truncated after a checkpoint",
+			entries:     func() []domain.AuditLog { return testChain(6)[:4] },
+			checkpoints: []domain.AuditCheckpoint{checkpointAt(1, 3), checkpointAt(2, 6)},
+			want:        domain.AuditVerifyResult{EntriesChecked: 4, CheckpointsChecked: 2, BrokenEntryID: 6, BrokenCheckpointID: 2, Reason: "checkpointed entry is missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verify(t, s, tt.entries(), tt.checkpoints)
+			if *got != tt.want {
+				t.Errorf("result = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditEntryHashIgnoresTimeZone(t *testing.T) {
+	e := testChain(1)[0]
+	utc := auditEntryHash(&e)
+
+	e.CreatedAt = e.CreatedAt.In(time.FixedZone("WIB", 7*60*60))
+	if got := auditEntryHash(&e); got != utc {
+		t.Errorf("hash in WIB = %s, want %s", got, utc)
+	}
+}
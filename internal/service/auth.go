@@ -3,6 +3,7 @@ package service
 import (
 	"database/sql"
 	"fmt"
+	"log"
 
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
 	"github.com/kanyaarss/kanyaars-portal/pkg/jwt"
@@ -12,21 +13,33 @@ import (
 // AuthService handles authentication operations
 type AuthService struct {
 	db        *sql.DB
+	audit     *AuditService
 	jwtSecret string
 	jwtExpiry int64
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(db *sql.DB, jwtSecret string, jwtExpiry int64) *AuthService {
+func NewAuthService(db *sql.DB, audit *AuditService, jwtSecret string, jwtExpiry int64) *AuthService {
+	if jwtExpiry <= 0 {
+		jwtExpiry = 86400
+	}
+
 	return &AuthService{
 		db:        db,
+		audit:     audit,
 		jwtSecret: jwtSecret,
 		jwtExpiry: jwtExpiry,
 	}
 }
 
-// Login authenticates user and returns JWT token
-func (s *AuthService) Login(email, password string) (*domain.User, string, error) {
+// TokenExpiry returns the lifetime of issued tokens in seconds
+func (s *AuthService) TokenExpiry() int64 {
+	return s.jwtExpiry
+}
+
+// Login authenticates user and returns JWT token. Both successful and
+// failed attempts are written to the audit log.
+func (s *AuthService) Login(actor domain.Actor, email, password string) (*domain.User, string, error) {
 	// Find user by email
 	var user domain.User
 	err := s.db.QueryRow(
@@ -35,7 +48,8 @@ func (s *AuthService) Login(email, password string) (*domain.User, string, error
 	).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role)
 
 	if err == sql.ErrNoRows {
-		return nil, "", fmt.Errorf("invalid email or password")
+		s.recordLoginFailure(actor, 0, email, "unknown or inactive user")
+		return nil, "", ErrInvalidCredentials
 	}
 
 	if err != nil {
@@ -44,7 +58,8 @@ func (s *AuthService) Login(email, password string) (*domain.User, string, error
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, "", fmt.Errorf("invalid email or password")
+		s.recordLoginFailure(actor, user.ID, email, "invalid password")
+		return nil, "", ErrInvalidCredentials
 	}
 
 	// Generate JWT token
@@ -53,6 +68,11 @@ func (s *AuthService) Login(email, password string) (*domain.User, string, error
 		return nil, "", fmt.Errorf("token generation failed: %w", err)
 	}
 
+	actor.UserID = user.ID
+	if err := s.audit.RecordStandalone(actor, domain.AuditActionLoginSuccess, domain.AuditResourceUser, user.ID, &domain.AuditDetails{Email: email}); err != nil {
+		return nil, "", err
+	}
+
 	return &user, token, nil
 }
 
@@ -65,7 +85,7 @@ func (s *AuthService) GetUserByID(id int) (*domain.User, error) {
 	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.IsActive)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	if err != nil {
@@ -76,41 +96,60 @@ func (s *AuthService) GetUserByID(id int) (*domain.User, error) {
 }
 
 // CreateUser creates a new user
-func (s *AuthService) CreateUser(user *domain.User) error {
+func (s *AuthService) CreateUser(actor domain.Actor, user *domain.User) error {
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("password hashing failed: %w", err)
 	}
 
-	_, err = s.db.Exec(
-		"INSERT INTO users (email, name, password, role, is_active) VALUES ($1, $2, $3, $4, $5)",
-		user.Email, user.Name, string(hashedPassword), user.Role, user.IsActive,
-	)
-
-	if err != nil {
-		return fmt.Errorf("database error: %w", err)
-	}
-
-	return nil
+	return withTx(s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			"INSERT INTO users (email, name, password, role, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			user.Email, user.Name, string(hashedPassword), user.Role, user.IsActive,
+		).Scan(&user.ID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		// Password is excluded from the snapshot by its json:"-" tag
+		return s.audit.Record(tx, actor, domain.AuditActionUserCreate, domain.AuditResourceUser, user.ID, diffDetails(nil, user))
+	})
 }
 
 // UpdatePassword updates user password
-func (s *AuthService) UpdatePassword(userID int, newPassword string) error {
+func (s *AuthService) UpdatePassword(actor domain.Actor, userID int, newPassword string) error {
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("password hashing failed: %w", err)
 	}
 
-	_, err = s.db.Exec(
-		"UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
-		string(hashedPassword), userID,
-	)
+	return withTx(s.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+			string(hashedPassword), userID,
+		)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if rowsAffected, err := result.RowsAffected(); err == nil && rowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionUserPassword, domain.AuditResourceUser, userID, nil)
+	})
+}
 
+// recordLoginFailure writes a failed login attempt to the audit log.
+// Errors are only logged so that they never change the login response.
+func (s *AuthService) recordLoginFailure(actor domain.Actor, userID int, email, reason string) {
+	err := s.audit.RecordStandalone(actor, domain.AuditActionLoginFailure, domain.AuditResourceUser, userID, &domain.AuditDetails{
+		Email:  email,
+		Reason: reason,
+	})
 	if err != nil {
-		return fmt.Errorf("database error: %w", err)
+		log.Printf("Failed to record login failure: %v", err)
 	}
-
-	return nil
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/pkg/webhook"
+)
+
+// Deployment list page size limits
+const (
+	defaultDeploymentPageSize = 20
+	maxDeploymentPageSize     = 100
+)
+
+// deploymentColumns is the column list scanned by scanDeployment
+const deploymentColumns = `id, project_id, version, COALESCE(commit_sha, ''), environment, status,
+	COALESCE(run_id, ''), COALESCE(url, ''), finished_at, created_at, updated_at`
+
+// DeploymentService records deployments reported by CI and lists them per
+// project
+type DeploymentService struct {
+	db       *sql.DB
+	projects *ProjectService
+	cfg      config.DeploymentsConfig
+}
+
+// NewDeploymentService creates a new deployment service
+func NewDeploymentService(db *sql.DB, projects *ProjectService, cfg config.DeploymentsConfig) *DeploymentService {
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = 5 * time.Minute
+	}
+
+	return &DeploymentService{db: db, projects: projects, cfg: cfg}
+}
+
+// Verify checks the signature header CI sent with body. Events are refused
+// altogether while no secret is configured.
+func (s *DeploymentService) Verify(header string, body []byte) error {
+	if s.cfg.Secret == "" {
+		return ErrDeploymentsDisabled
+	}
+
+	if err := webhook.Verify(s.cfg.Secret, header, body, s.cfg.Tolerance); err != nil {
+		return ErrDeploymentSignature
+	}
+
+	return nil
+}
+
+// Record stores a deployment event. An event carrying the run ID of an
+// earlier one, including a replay of the same event, updates that
+// deployment instead of adding another, except
+// that a finished deployment is never set back to pending or running by an
+// event delivered late; the stored deployment is returned unchanged then.
+// Projects are looked up by slug, following renames.
+func (s *DeploymentService) Record(event *domain.DeploymentEvent) (*domain.Deployment, error) {
+	project, err := s.resolveProject(event.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := event.Status == domain.DeploymentSucceeded || event.Status == domain.DeploymentFailed
+
+	var deployment *domain.Deployment
+	err = withTx(s.db, func(tx *sql.Tx) error {
+		deployment, err = scanDeployment(tx.QueryRow(`
+			INSERT INTO deployments (project_id, version, commit_sha, environment, status, run_id, url, finished_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), NULLIF($7, ''),
+				CASE WHEN $8 THEN CURRENT_TIMESTAMP END)
+			ON CONFLICT (project_id, run_id) WHERE run_id IS NOT NULL DO UPDATE SET
+				version = EXCLUDED.version,
+				commit_sha = EXCLUDED.commit_sha,
+				environment = EXCLUDED.environment,
+				status = EXCLUDED.status,
+				url = COALESCE(EXCLUDED.url, deployments.url),
+				finished_at = EXCLUDED.finished_at,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE deployments.status NOT IN ($9, $10) OR EXCLUDED.status IN ($9, $10)
+			RETURNING `+deploymentColumns,
+			project.ID, event.Version, event.Commit, event.Environment, event.Status, event.RunID, event.URL, finished,
+			domain.DeploymentSucceeded, domain.DeploymentFailed,
+		))
+		if err == sql.ErrNoRows {
+			// A late event for a finished deployment
+			deployment, err = scanDeployment(tx.QueryRow(
+				"SELECT "+deploymentColumns+" FROM deployments WHERE project_id = $1 AND run_id = $2",
+				project.ID, event.RunID,
+			))
+			if err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		// Project pages show the current version
+		return s.projects.invalidate(tx, project, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// resolveProject returns the project with the given slug, or the one that
+// used it before being renamed
+func (s *DeploymentService) resolveProject(slug string) (*domain.Project, error) {
+	project, err := s.projects.GetProjectBySlug(slug)
+	if errors.Is(err, ErrProjectNotFound) {
+		var current string
+		if current, err = s.projects.CurrentSlug(slug); err == nil {
+			project, err = s.projects.GetProjectBySlug(current)
+		}
+	}
+
+	if errors.Is(err, ErrProjectNotFound) {
+		return nil, ErrDeploymentProject
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// ListDeployments returns a page of a project's deployments, newest first
+func (s *DeploymentService) ListDeployments(projectID int, filter *domain.DeploymentFilter) (*domain.PaginatedResponse, error) {
+	if _, err := getProjectByID(s.db, projectID); err != nil {
+		return nil, err
+	}
+
+	qf := queryFilter{}
+	qf.add("project_id = ?", projectID)
+	if filter.Environment != "" {
+		qf.add("environment = ?", filter.Environment)
+	}
+	if filter.Status != "" {
+		qf.add("status = ?", filter.Status)
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM deployments"+qf.where(), qf.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	pageSize := clampPageSize(filter.PageSize, defaultDeploymentPageSize, maxDeploymentPageSize)
+	page := filter.Page
+	if page == 0 {
+		page = 1
+	}
+
+	query := "SELECT " + deploymentColumns + " FROM deployments" + qf.where() + " ORDER BY created_at DESC, id DESC LIMIT " + qf.next(pageSize)
+	if page > 1 {
+		query += " OFFSET " + qf.next((page-1)*pageSize)
+	}
+
+	deployments, err := queryDeployments(s.db, query, qf.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewPaginatedResponse(deployments, total, page, pageSize), nil
+}
+
+// CurrentDeployments returns the latest successful deployment of a project
+// to each environment, most recently deployed first
+func (s *DeploymentService) CurrentDeployments(projectID int) ([]domain.Deployment, error) {
+	return queryDeployments(s.db, `
+		SELECT `+deploymentColumns+` FROM (
+			SELECT DISTINCT ON (environment) * FROM deployments
+			WHERE project_id = $1 AND status = $2
+			ORDER BY environment, finished_at DESC, id DESC
+		) d
+		ORDER BY finished_at DESC, id DESC`,
+		projectID, domain.DeploymentSucceeded,
+	)
+}
+
+// queryDeployments runs a query selecting deploymentColumns
+func queryDeployments(q querier, query string, args ...interface{}) ([]domain.Deployment, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	deployments := []domain.Deployment{}
+	for rows.Next() {
+		d, err := scanDeployment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		deployments = append(deployments, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return deployments, nil
+}
+
+// scanDeployment scans a row selected with deploymentColumns
+func scanDeployment(row rowScanner) (*domain.Deployment, error) {
+	var (
+		d        domain.Deployment
+		finished sql.NullTime
+	)
+
+	err := row.Scan(&d.ID, &d.ProjectID, &d.Version, &d.Commit, &d.Environment, &d.Status, &d.RunID, &d.URL, &finished, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns are TIMESTAMPTZ, read in the session time zone
+	if finished.Valid {
+		finishedAt := finished.Time.UTC()
+		d.FinishedAt = &finishedAt
+	}
+	d.CreatedAt, d.UpdatedAt = d.CreatedAt.UTC(), d.UpdatedAt.UTC()
+
+	return &d, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Errors returned by the service layer that handlers map to HTTP statuses
+var (
+	ErrProjectNotFound     = errors.New("project not found")
+	ErrPortalNotConfigured = errors.New("portal not configured")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrInvalidSort         = errors.New("invalid sort")
+	ErrSlugTaken           = errors.New("slug already taken")
+	ErrSlugReserved        = errors.New("slug is reserved")
+	ErrSlugInvalid         = errors.New("invalid slug")
+	ErrProjectManaged      = errors.New("project is managed by sync")
+	ErrImportInvalid       = errors.New("import contains invalid rows")
+	ErrSyncEmptyManifest   = errors.New("manifest lists no projects")
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrIncidentNotFound    = errors.New("incident not found")
+	ErrIncidentProject     = errors.New("incident refers to an unknown project")
+	ErrMaintenanceNotFound = errors.New("maintenance window not found")
+	ErrMaintenanceInvalid  = errors.New("maintenance window must end after it starts and in the future")
+	ErrMaintenanceDone     = errors.New("maintenance window has already ended")
+	ErrDeploymentsDisabled = errors.New("deployment tracking not configured")
+	ErrDeploymentSignature = errors.New("invalid or expired deployment signature")
+	ErrDeploymentProject   = errors.New("deployment refers to an unknown project")
+	ErrIdempotencyReused   = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyPending  = errors.New("idempotency key in progress")
+)
+
+// isUniqueViolation reports whether err is a unique constraint violation of
+// the named constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// EventChannel is the Postgres NOTIFY channel carrying the IDs of newly
+// committed outbox events
+const EventChannel = "portal_events"
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before it is disconnected
+const subscriberBuffer = 64
+
+// eventSequenceLockKey is the advisory lock that serialises the numbering
+// of outbox events, so that positions are handed out in commit order
+const eventSequenceLockKey = 7310458
+
+// eventColumns is the column list scanned by query
+const eventColumns = "id, type, payload, created_at, position"
+
+// EventBroker fans committed outbox events out to live subscribers. Every
+// replica listens on EventChannel, so a change made through any replica
+// reaches the clients of all of them. The most recent events are kept in a
+// bounded buffer so reconnecting clients can resume after Last-Event-ID.
+//
+// Outbox IDs are assigned when a transaction inserts its event, not when it
+// commits, so a slow transaction can commit an ID below one a client has
+// already seen. Events are therefore streamed and resumed by position,
+// which brokers assign to committed events only, one at a time under
+// eventSequenceLockKey: an event committing late gets a later position.
+type EventBroker struct {
+	db  *sql.DB
+	dsn string
+	cfg config.EventsConfig
+
+	start sync.Once
+
+	mu          sync.Mutex
+	buffer      []domain.Event // ordered by position
+	floor       int            // every event positioned after floor is buffered
+	subscribers map[chan domain.Event]struct{}
+}
+
+// NewEventBroker creates a new event broker; it starts listening when the
+// first client subscribes
+func NewEventBroker(db *sql.DB, dsn string, cfg config.EventsConfig) *EventBroker {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 500
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+
+	return &EventBroker{
+		db:          db,
+		dsn:         dsn,
+		cfg:         cfg,
+		subscribers: map[chan domain.Event]struct{}{},
+	}
+}
+
+// Heartbeat returns how often idle streams should send a keep-alive
+func (b *EventBroker) Heartbeat() time.Duration {
+	return b.cfg.Heartbeat
+}
+
+// Subscribe registers a subscriber and returns the buffered events after
+// position last. complete is false when events after last have already
+// left the buffer. The channel is closed if the subscriber falls too far
+// behind; cancel must be called when the subscriber goes away.
+func (b *EventBroker) Subscribe(last int) (replay []domain.Event, complete bool, events <-chan domain.Event, cancel func()) {
+	b.start.Do(b.listen)
+
+	ch := make(chan domain.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[ch] = struct{}{}
+
+	complete = true
+	if last > 0 {
+		complete = last >= b.floor
+		for _, event := range b.buffer {
+			if event.Position > last {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return replay, complete, ch, cancel
+}
+
+// listen starts the LISTEN connection and fills the buffer with the most
+// recent events
+func (b *EventBroker) listen() {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event listener: %v", err)
+		}
+	})
+
+	if err := listener.Listen(EventChannel); err != nil {
+		log.Printf("Failed to listen on %s: %v", EventChannel, err)
+	}
+
+	// Listen before loading so that no event falls between the two
+	if err := b.preload(); err != nil {
+		log.Printf("Failed to load recent events: %v", err)
+	}
+
+	go b.run(listener)
+}
+
+// run receives notifications until the listener is closed. Every
+// notification, and the nil one following a reconnect, during which
+// notifications may have been missed, is answered by positioning the
+// committed events and catching up on them; the periodic ping does the same
+// in case a notification was lost.
+func (b *EventBroker) run(listener *pq.Listener) {
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case _, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+
+		case <-ping.C:
+			go listener.Ping()
+		}
+
+		if err := b.sequence(); err != nil {
+			log.Printf("Failed to position events: %v", err)
+		}
+		if err := b.catchUp(); err != nil {
+			log.Printf("Failed to catch up on events: %v", err)
+		}
+	}
+}
+
+// sequence gives a position to every committed event that has none yet.
+// Replicas take turns under the advisory lock, and each one only sees
+// events committed before it got the lock, so every position it hands out
+// is greater than those of events already visible to readers.
+func (b *EventBroker) sequence() error {
+	for {
+		var positioned int64
+		err := withTx(b.db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", eventSequenceLockKey); err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+
+			res, err := tx.Exec(`
+				UPDATE webhook_events e SET position = p.position
+				FROM (
+					SELECT id, nextval('webhook_events_position_seq') AS position FROM (
+						SELECT id FROM webhook_events WHERE position IS NULL ORDER BY id LIMIT $1
+					) pending
+				) p
+				WHERE e.id = p.id
+			`, b.cfg.BufferSize)
+			if err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+
+			positioned, err = res.RowsAffected()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if positioned < int64(b.cfg.BufferSize) {
+			return nil
+		}
+	}
+}
+
+// preload positions the committed events and buffers the most recent ones
+func (b *EventBroker) preload() error {
+	if err := b.sequence(); err != nil {
+		return err
+	}
+
+	events, err := b.latest(0, b.cfg.BufferSize)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(events) == b.cfg.BufferSize {
+		b.floor = events[0].Position - 1
+	}
+	for _, event := range events {
+		b.insert(event)
+	}
+
+	return nil
+}
+
+// catchUp loads and publishes events positioned after the buffer. When
+// more events arrived than the buffer holds, only the most recent are kept
+// and subscribers are dropped, so that they resume and learn of the gap.
+func (b *EventBroker) catchUp() error {
+	b.mu.Lock()
+	last := b.floor
+	if len(b.buffer) > 0 {
+		last = b.buffer[len(b.buffer)-1].Position
+	}
+	b.mu.Unlock()
+
+	// One extra event tells whether any were skipped
+	events, err := b.latest(last, b.cfg.BufferSize+1)
+	if err != nil {
+		return err
+	}
+
+	if len(events) > b.cfg.BufferSize {
+		b.mu.Lock()
+		b.buffer = nil
+		b.floor = events[0].Position
+		events = events[1:]
+		for ch := range b.subscribers {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}
+
+	for _, event := range events {
+		b.publish(event)
+	}
+
+	return nil
+}
+
+// latest returns at most limit of the most recent events positioned after
+// last, oldest first
+func (b *EventBroker) latest(last, limit int) ([]domain.Event, error) {
+	return b.query(`
+		SELECT `+eventColumns+` FROM (
+			SELECT `+eventColumns+` FROM webhook_events WHERE position > $1 ORDER BY position DESC LIMIT $2
+		) recent ORDER BY position
+	`, last, limit)
+}
+
+// publish buffers event and sends it to every subscriber. Subscribers
+// whose channel is full are dropped; they resume with Last-Event-ID.
+func (b *EventBroker) publish(event domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.insert(event) {
+		return
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// insert adds event to the buffer in position order, trimming the oldest
+// events beyond BufferSize. It reports false for events already buffered
+// or too old to be kept.
+func (b *EventBroker) insert(event domain.Event) bool {
+	if event.Position <= b.floor {
+		return false
+	}
+
+	i := sort.Search(len(b.buffer), func(i int) bool { return b.buffer[i].Position >= event.Position })
+	if i < len(b.buffer) && b.buffer[i].Position == event.Position {
+		return false
+	}
+
+	b.buffer = append(b.buffer, domain.Event{})
+	copy(b.buffer[i+1:], b.buffer[i:])
+	b.buffer[i] = event
+
+	if over := len(b.buffer) - b.cfg.BufferSize; over > 0 {
+		b.floor = b.buffer[over-1].Position
+		b.buffer = append(b.buffer[:0:0], b.buffer[over:]...)
+	}
+
+	return true
+}
+
+// query runs a query selecting event columns and scans every row
+func (b *EventBroker) query(query string, args ...interface{}) ([]domain.Event, error) {
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.Event
+	for rows.Next() {
+		var (
+			event   domain.Event
+			payload string
+		)
+		if err := rows.Scan(&event.ID, &event.Type, &payload, &event.CreatedAt, &event.Position); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		event.Data = json.RawMessage(payload)
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return events, nil
+}
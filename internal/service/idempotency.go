@@ -0,0 +1,205 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// A claimed key is held by its request for as long as the request runs:
+// KeepAlive refreshes its heartbeat every heartbeatInterval. A key whose
+// heartbeat is older than abandonedAfter was left by a crashed replica and
+// may be claimed again, however long the original request was meant to
+// take.
+const (
+	heartbeatInterval = 10 * time.Second
+	abandonedAfter    = time.Minute
+)
+
+// IdempotencyService remembers the responses of requests sent with an
+// Idempotency-Key so that retries are answered without running them again
+type IdempotencyService struct {
+	db  *sql.DB
+	cfg config.IdempotencyConfig
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(db *sql.DB, cfg config.IdempotencyConfig) *IdempotencyService {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.SweepInterval <= 0 {
+		cfg.SweepInterval = 10 * time.Minute
+	}
+
+	return &IdempotencyService{db: db, cfg: cfg}
+}
+
+// Start deletes expired and abandoned keys every configured interval until
+// stop is closed
+func (s *IdempotencyService) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sweep(); err != nil {
+			log.Printf("Idempotency key sweep failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Sweep deletes expired and abandoned keys and returns how many it deleted.
+// Begin ignores such keys anyway; this only reclaims their storage.
+func (s *IdempotencyService) Sweep() (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE created_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'
+		OR (status IS NULL AND heartbeat_at < CURRENT_TIMESTAMP - $2 * INTERVAL '1 second')
+	`, s.cfg.TTL.Seconds(), abandonedAfter.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// IdempotencyClaim is a request's hold on a key. Once a claim has been
+// taken over, e.g. after its request stopped sending heartbeats, it can no
+// longer complete or release the key.
+type IdempotencyClaim struct {
+	UserID int
+	Key    string
+	token  string
+}
+
+// Begin claims key for the user's request identified by requestHash. It
+// returns the claim when the request should run, or the stored response of
+// an earlier run. ErrIdempotencyReused is returned when the key was used
+// for a different request, ErrIdempotencyPending while that request still
+// runs.
+func (s *IdempotencyService) Begin(userID int, key, requestHash string) (*IdempotencyClaim, *domain.IdempotentResponse, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, nil, fmt.Errorf("claim token generation failed: %w", err)
+	}
+	claim := &IdempotencyClaim{UserID: userID, Key: key, token: hex.EncodeToString(b)}
+
+	// Expired and abandoned keys are claimed over
+	result, err := s.db.Exec(`
+		INSERT INTO idempotency_keys (user_id, key, request_hash, claim_token) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			claim_token = EXCLUDED.claim_token,
+			status = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = CURRENT_TIMESTAMP,
+			heartbeat_at = CURRENT_TIMESTAMP
+		WHERE idempotency_keys.created_at < CURRENT_TIMESTAMP - $5 * INTERVAL '1 second'
+		OR (idempotency_keys.status IS NULL AND idempotency_keys.heartbeat_at < CURRENT_TIMESTAMP - $6 * INTERVAL '1 second')
+	`, userID, key, requestHash, claim.token, s.cfg.TTL.Seconds(), abandonedAfter.Seconds())
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 1 {
+		return claim, nil, nil
+	}
+
+	var (
+		storedHash  string
+		status      sql.NullInt64
+		contentType sql.NullString
+		body        []byte
+	)
+	err = s.db.QueryRow(
+		"SELECT request_hash, status, content_type, response_body FROM idempotency_keys WHERE user_id = $1 AND key = $2",
+		userID, key,
+	).Scan(&storedHash, &status, &contentType, &body)
+	if err == sql.ErrNoRows {
+		// Swept between the insert and this read; claim it again
+		return s.Begin(userID, key, requestHash)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if storedHash != requestHash {
+		return nil, nil, ErrIdempotencyReused
+	}
+	if !status.Valid {
+		return nil, nil, ErrIdempotencyPending
+	}
+
+	return nil, &domain.IdempotentResponse{Status: int(status.Int64), ContentType: contentType.String, Body: body}, nil
+}
+
+// KeepAlive refreshes the heartbeat of a claim until the returned function
+// is called, so that the key is not taken for abandoned while its request
+// runs
+func (s *IdempotencyService) KeepAlive(claim *IdempotencyClaim) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			_, err := s.db.Exec(
+				"UPDATE idempotency_keys SET heartbeat_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND key = $2 AND claim_token = $3 AND status IS NULL",
+				claim.UserID, claim.Key, claim.token,
+			)
+			if err != nil {
+				log.Printf("Idempotency key heartbeat failed: %v", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Complete stores the response of a claimed key. A claim taken over
+// meanwhile stores nothing.
+func (s *IdempotencyService) Complete(claim *IdempotencyClaim, response *domain.IdempotentResponse) error {
+	_, err := s.db.Exec(
+		"UPDATE idempotency_keys SET status = $1, content_type = $2, response_body = $3 WHERE user_id = $4 AND key = $5 AND claim_token = $6",
+		response.Status, response.ContentType, response.Body, claim.UserID, claim.Key, claim.token,
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// Release forgets a claimed key whose request failed, so that it can be
+// retried. A claim taken over meanwhile leaves the key to its new owner.
+func (s *IdempotencyService) Release(claim *IdempotencyClaim) error {
+	_, err := s.db.Exec(
+		"DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND claim_token = $3",
+		claim.UserID, claim.Key, claim.token,
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// incidentColumns is the column list scanned by scanIncident
+const incidentColumns = `id, title, severity, state, started_at, resolved_at, created_at, updated_at`
+
+const (
+	// defaultIncidentLimit is the number of incidents listed when the
+	// filter sets no limit
+	defaultIncidentLimit = 50
+	// statusHistory is how long resolved incidents stay on the status page
+	statusHistory = 14 * 24 * time.Hour
+)
+
+// severityStatus is the project status an active incident of each severity
+// implies
+var severityStatus = map[string]string{
+	domain.SeverityMinor:    domain.StatusDegraded,
+	domain.SeverityMajor:    domain.StatusPartialOutage,
+	domain.SeverityCritical: domain.StatusMajorOutage,
+}
+
+// statusRank orders project statuses from best to worst
+var statusRank = map[string]int{
+	domain.StatusOperational:   0,
+	domain.StatusMaintenance:   1,
+	domain.StatusDegraded:      2,
+	domain.StatusPartialOutage: 3,
+	domain.StatusMajorOutage:   4,
+}
+
+// IncidentService manages incidents and assembles the public status page
+type IncidentService struct {
+	db    *sql.DB
+	audit *AuditService
+}
+
+// NewIncidentService creates a new incident service
+func NewIncidentService(db *sql.DB, audit *AuditService) *IncidentService {
+	return &IncidentService{db: db, audit: audit}
+}
+
+// ListIncidents returns incidents newest first, with their updates
+func (s *IncidentService) ListIncidents(filter domain.IncidentFilter) ([]domain.Incident, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultIncidentLimit
+	}
+
+	qf := queryFilter{}
+	if filter.State != "" {
+		qf.add("state = ?", filter.State)
+	}
+
+	return s.queryIncidents(
+		"SELECT "+incidentColumns+" FROM incidents"+qf.where()+" ORDER BY started_at DESC, id DESC LIMIT "+qf.next(filter.Limit),
+		qf.args...,
+	)
+}
+
+// GetIncident retrieves an incident by ID with its updates
+func (s *IncidentService) GetIncident(id int) (*domain.Incident, error) {
+	incident, err := getIncident(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	incidents := []domain.Incident{*incident}
+	if err := attachUpdates(s.db, incidents); err != nil {
+		return nil, err
+	}
+
+	return &incidents[0], nil
+}
+
+// CreateIncident opens an incident with its first update
+func (s *IncidentService) CreateIncident(actor domain.Actor, req *domain.CreateIncidentRequest) (*domain.Incident, error) {
+	state := req.State
+	if state == "" {
+		state = domain.IncidentInvestigating
+	}
+
+	var id int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			INSERT INTO incidents (title, severity, state, started_at, resolved_at)
+			VALUES ($1, $2, $3, COALESCE($4, CURRENT_TIMESTAMP), CASE WHEN $5 THEN CURRENT_TIMESTAMP END)
+			RETURNING id
+		`, req.Title, req.Severity, state, req.StartedAt, state == domain.IncidentResolved).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if err := setIncidentProjects(tx, id, req.ProjectIDs); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("INSERT INTO incident_updates (incident_id, state, message) VALUES ($1, $2, $3)", id, state, req.Message); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionIncidentCreate, domain.AuditResourceIncident, id, diffDetails(nil, after))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(id)
+}
+
+// UpdateIncident changes an incident's title, severity or affected projects
+func (s *IncidentService) UpdateIncident(actor domain.Actor, id int, req *domain.UpdateIncidentRequest) (*domain.Incident, error) {
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE incidents SET title = COALESCE(NULLIF($1, ''), title), severity = COALESCE(NULLIF($2, ''), severity), updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+			req.Title, req.Severity, id,
+		)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if len(req.ProjectIDs) > 0 {
+			if _, err := tx.Exec("DELETE FROM incident_projects WHERE incident_id = $1", id); err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+			if err := setIncidentProjects(tx, id, req.ProjectIDs); err != nil {
+				return err
+			}
+		}
+
+		after, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionIncidentUpdate, domain.AuditResourceIncident, id, diffDetails(before, after))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(id)
+}
+
+// PostUpdate adds a timestamped update to an incident and moves it to the
+// update's state. Resolving sets resolved_at; any other state reopens it.
+func (s *IncidentService) PostUpdate(actor domain.Actor, id int, req *domain.CreateIncidentUpdateRequest) (*domain.Incident, error) {
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE incidents SET
+				state = $1,
+				resolved_at = CASE WHEN $2 THEN COALESCE(resolved_at, CURRENT_TIMESTAMP) END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3
+		`, req.State, req.State == domain.IncidentResolved, id)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if _, err := tx.Exec("INSERT INTO incident_updates (incident_id, state, message) VALUES ($1, $2, $3)", id, req.State, req.Message); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		details := diffDetails(before, after)
+		details.Reason = req.Message
+
+		return s.audit.Record(tx, actor, domain.AuditActionIncidentPost, domain.AuditResourceIncident, id, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetIncident(id)
+}
+
+// DeleteIncident deletes an incident together with its updates
+func (s *IncidentService) DeleteIncident(actor domain.Actor, id int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getIncident(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM incidents WHERE id = $1", id); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionIncidentDelete, domain.AuditResourceIncident, id, diffDetails(before, nil))
+	})
+}
+
+// Status returns the current state of every project that is not inactive,
+// derived from its active incidents and its status, with active and
+// recently resolved incidents
+func (s *IncidentService) Status() (*domain.StatusPage, error) {
+	incidents, err := s.queryIncidents(
+		"SELECT "+incidentColumns+" FROM incidents WHERE resolved_at IS NULL OR resolved_at > CURRENT_TIMESTAMP - $1 * INTERVAL '1 second' ORDER BY resolved_at DESC NULLS FIRST, started_at DESC",
+		int(statusHistory.Seconds()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The worst status each project's active incidents imply
+	affected := map[int]string{}
+	for _, incident := range incidents {
+		if incident.State == domain.IncidentResolved {
+			continue
+		}
+		for _, project := range incident.Projects {
+			if status := severityStatus[incident.Severity]; statusRank[status] > statusRank[affected[project.ID]] {
+				affected[project.ID] = status
+			}
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT p.id, p.name, p.slug, p.url, p.status, c.up
+		FROM projects p
+		LEFT JOIN LATERAL (
+			SELECT up FROM project_checks WHERE project_id = p.id ORDER BY checked_at DESC LIMIT 1
+		) c ON true
+		WHERE p.status <> 'inactive'
+		ORDER BY p."order" ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	page := &domain.StatusPage{
+		Status:    domain.StatusOperational,
+		Projects:  []domain.ProjectStatus{},
+		Incidents: incidents,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	for rows.Next() {
+		var (
+			p             domain.ProjectStatus
+			projectStatus string
+			up            sql.NullBool
+		)
+		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.URL, &projectStatus, &up); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+
+		p.Status = domain.StatusOperational
+		if projectStatus == domain.ProjectMaintenance {
+			p.Status = domain.StatusMaintenance
+		}
+		if status, ok := affected[p.ID]; ok {
+			p.Status = status
+		}
+
+		p.Health = domain.HealthUnknown
+		if up.Valid {
+			p.Health = domain.HealthDown
+			if up.Bool {
+				p.Health = domain.HealthUp
+			}
+		}
+
+		if statusRank[p.Status] > statusRank[page.Status] {
+			page.Status = p.Status
+		}
+		page.Projects = append(page.Projects, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return page, nil
+}
+
+// RecentUpdates returns the latest incident updates across all incidents,
+// newest first
+func (s *IncidentService) RecentUpdates(limit int) ([]domain.IncidentFeedItem, error) {
+	rows, err := s.db.Query(`
+		SELECT u.id, u.incident_id, u.state, u.message, u.created_at, i.title, i.severity
+		FROM incident_updates u JOIN incidents i ON i.id = u.incident_id
+		ORDER BY u.created_at DESC, u.id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	items := []domain.IncidentFeedItem{}
+	for rows.Next() {
+		var item domain.IncidentFeedItem
+		if err := rows.Scan(&item.ID, &item.IncidentID, &item.State, &item.Message, &item.CreatedAt, &item.Title, &item.Severity); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return items, nil
+}
+
+// queryIncidents runs an incident query and loads the projects and updates
+// of the incidents it returns
+func (s *IncidentService) queryIncidents(query string, args ...interface{}) ([]domain.Incident, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []domain.Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		incidents = append(incidents, *incident)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := attachProjects(s.db, incidents); err != nil {
+		return nil, err
+	}
+	if err := attachUpdates(s.db, incidents); err != nil {
+		return nil, err
+	}
+
+	return incidents, nil
+}
+
+// setIncidentProjects links an incident to the given projects, failing with
+// ErrIncidentProject when any of them does not exist
+func setIncidentProjects(tx *sql.Tx, incidentID int, projectIDs []int) error {
+	result, err := tx.Exec(
+		"INSERT INTO incident_projects (incident_id, project_id) SELECT $1, id FROM projects WHERE id = ANY($2)",
+		incidentID, pq.Array(projectIDs),
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	unique := map[int]bool{}
+	for _, id := range projectIDs {
+		unique[id] = true
+	}
+
+	if n, _ := result.RowsAffected(); int(n) != len(unique) {
+		return ErrIncidentProject
+	}
+
+	return nil
+}
+
+// getIncident retrieves an incident by ID with its projects but without its
+// updates, using the given querier
+func getIncident(q querier, id int) (*domain.Incident, error) {
+	incident, err := scanIncident(q.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id = $1", id))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	incidents := []domain.Incident{*incident}
+	if err := attachProjects(q, incidents); err != nil {
+		return nil, err
+	}
+
+	return &incidents[0], nil
+}
+
+// attachProjects loads the affected projects of incidents
+func attachProjects(q querier, incidents []domain.Incident) error {
+	index := incidentIndex(incidents)
+	if len(index) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(`
+		SELECT ip.incident_id, p.id, p.name, p.slug
+		FROM incident_projects ip JOIN projects p ON p.id = ip.project_id
+		WHERE ip.incident_id = ANY($1)
+		ORDER BY p."order" ASC, p.id ASC
+	`, pq.Array(incidentIDs(incidents)))
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			incidentID int
+			p          domain.IncidentProject
+		)
+		if err := rows.Scan(&incidentID, &p.ID, &p.Name, &p.Slug); err != nil {
+			return fmt.Errorf("scan error: %w", err)
+		}
+		incident := &incidents[index[incidentID]]
+		incident.Projects = append(incident.Projects, p)
+	}
+
+	return rows.Err()
+}
+
+// attachUpdates loads the updates of incidents, newest first
+func attachUpdates(q querier, incidents []domain.Incident) error {
+	index := incidentIndex(incidents)
+	if len(index) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(
+		"SELECT id, incident_id, state, message, created_at FROM incident_updates WHERE incident_id = ANY($1) ORDER BY created_at DESC, id DESC",
+		pq.Array(incidentIDs(incidents)),
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u domain.IncidentUpdate
+		if err := rows.Scan(&u.ID, &u.IncidentID, &u.State, &u.Message, &u.CreatedAt); err != nil {
+			return fmt.Errorf("scan error: %w", err)
+		}
+		incident := &incidents[index[u.IncidentID]]
+		incident.Updates = append(incident.Updates, u)
+	}
+
+	return rows.Err()
+}
+
+// incidentIndex maps incident IDs to their position in incidents
+func incidentIndex(incidents []domain.Incident) map[int]int {
+	index := make(map[int]int, len(incidents))
+	for i, incident := range incidents {
+		index[incident.ID] = i
+	}
+	return index
+}
+
+func incidentIDs(incidents []domain.Incident) []int {
+	ids := make([]int, len(incidents))
+	for i, incident := range incidents {
+		ids[i] = incident.ID
+	}
+	return ids
+}
+
+// scanIncident scans a row selected with incidentColumns
+func scanIncident(row rowScanner) (*domain.Incident, error) {
+	var (
+		i        domain.Incident
+		resolved sql.NullTime
+	)
+
+	err := row.Scan(&i.ID, &i.Title, &i.Severity, &i.State, &i.StartedAt, &resolved, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved.Valid {
+		i.ResolvedAt = &resolved.Time
+	}
+	i.Projects = []domain.IncidentProject{}
+
+	return &i, nil
+}
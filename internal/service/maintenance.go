@@ -0,0 +1,487 @@
+package service
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// Maintenance window transitions, published through expvar
+var maintenanceTransitions = expvar.NewMap("maintenance_windows")
+
+// maintenanceActor identifies status changes made by the maintenance
+// scheduler in the audit log
+var maintenanceActor = domain.Actor{UserAgent: "portal maintenance"}
+
+// defaultMaintenanceLimit is the number of windows listed when no limit is
+// given
+const defaultMaintenanceLimit = 100
+
+// maintenanceColumns is the column list scanned by scanMaintenance, selected
+// from maintenance_windows w joined with projects p
+const maintenanceColumns = `w.id, w.project_id, p.name, p.slug, w.starts_at, w.ends_at, w.message,
+	CASE WHEN w.ends_at <= CURRENT_TIMESTAMP THEN 'completed' WHEN w.starts_at <= CURRENT_TIMESTAMP THEN 'in_progress' ELSE 'scheduled' END,
+	w.created_at, w.updated_at`
+
+// MaintenanceService manages maintenance windows and switches project
+// status as they start and end
+type MaintenanceService struct {
+	db       *sql.DB
+	audit    *AuditService
+	projects *ProjectService
+	cfg      config.MaintenanceConfig
+}
+
+// NewMaintenanceService creates a new maintenance service; status changes
+// go through projects so that they are audited and published like any other
+func NewMaintenanceService(db *sql.DB, audit *AuditService, projects *ProjectService, cfg config.MaintenanceConfig) *MaintenanceService {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30 * time.Second
+	}
+
+	return &MaintenanceService{db: db, audit: audit, projects: projects, cfg: cfg}
+}
+
+// Start starts and ends due windows every poll interval until stop is
+// closed
+func (s *MaintenanceService) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Apply(); err != nil {
+			log.Printf("Maintenance scheduling failed: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Apply starts every window whose start has passed and ends every window
+// whose end has passed, oldest first, and returns how many it handled.
+// Windows are claimed with SKIP LOCKED, so several replicas can run the
+// scheduler at once.
+func (s *MaintenanceService) Apply() (int, error) {
+	n := 0
+	for {
+		applied, err := s.applyNext()
+		if err != nil || !applied {
+			return n, err
+		}
+		n++
+	}
+}
+
+// applyNext starts or ends the next due window and reports whether there
+// was one. A window starting at the moment another ends is started first,
+// so the project does not flip back to its status in between.
+func (s *MaintenanceService) applyNext() (bool, error) {
+	applied := false
+
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var (
+			id, projectID int
+			starting      bool
+			previous      sql.NullString
+		)
+		err := tx.QueryRow(`
+			SELECT id, project_id, started_at IS NULL AND ends_at > CURRENT_TIMESTAMP, previous_status
+			FROM maintenance_windows
+			WHERE ended_at IS NULL AND (ends_at <= CURRENT_TIMESTAMP OR (started_at IS NULL AND starts_at <= CURRENT_TIMESTAMP))
+			ORDER BY CASE WHEN ends_at <= CURRENT_TIMESTAMP THEN ends_at ELSE starts_at END, ends_at <= CURRENT_TIMESTAMP
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		`).Scan(&id, &projectID, &starting, &previous)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		applied = true
+		if starting {
+			maintenanceTransitions.Add("started", 1)
+			return s.start(tx, id, projectID)
+		}
+
+		maintenanceTransitions.Add("ended", 1)
+		return s.end(tx, maintenanceActor, id, projectID, previous)
+	})
+
+	return applied, err
+}
+
+// start switches an active project to maintenance and remembers its status
+// on the window. Projects in any other status are left alone.
+func (s *MaintenanceService) start(tx *sql.Tx, id, projectID int) error {
+	before, err := lockProject(tx, projectID)
+	if err != nil {
+		return err
+	}
+
+	var previous sql.NullString
+	if before.Status == domain.ProjectActive {
+		doc := projectDocument(before)
+		doc.Status = domain.ProjectMaintenance
+		if err := s.projects.saveProject(tx, maintenanceActor, before, &doc); err != nil {
+			return err
+		}
+		previous = sql.NullString{String: before.Status, Valid: true}
+	} else if err := s.projects.invalidate(tx, before, nil); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE maintenance_windows SET started_at = CURRENT_TIMESTAMP, previous_status = $1 WHERE id = $2", previous, id)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// end marks a window ended and restores the status it switched from, unless
+// an admin changed the status meanwhile. While another window of the project
+// is still running, that window restores the status when it ends instead.
+func (s *MaintenanceService) end(tx *sql.Tx, actor domain.Actor, id, projectID int, previous sql.NullString) error {
+	before, err := lockProject(tx, projectID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE maintenance_windows SET previous_status = COALESCE(previous_status, $1)
+		WHERE id = (
+			SELECT id FROM maintenance_windows
+			WHERE project_id = $2 AND id <> $3 AND started_at IS NOT NULL AND ended_at IS NULL
+			ORDER BY ends_at DESC LIMIT 1
+		)
+	`, previous, projectID, id)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if handedOver, _ := result.RowsAffected(); handedOver == 0 && previous.Valid && before.Status == domain.ProjectMaintenance {
+		doc := projectDocument(before)
+		doc.Status = previous.String
+		if err := s.projects.saveProject(tx, actor, before, &doc); err != nil {
+			return err
+		}
+	} else if err := s.projects.invalidate(tx, before, nil); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE maintenance_windows SET ended_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// ListWindows returns a project's scheduled and running windows, soonest
+// first, or all of its windows newest first when completed ones are included
+func (s *MaintenanceService) ListWindows(projectID int, filter domain.MaintenanceFilter) ([]domain.MaintenanceWindow, error) {
+	if _, err := getProjectByID(s.db, projectID); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultMaintenanceLimit
+	}
+
+	qf := queryFilter{}
+	qf.add("w.project_id = ?", projectID)
+	order := "w.starts_at DESC"
+	if !filter.IncludeCompleted {
+		qf.add("w.ends_at > CURRENT_TIMESTAMP")
+		order = "w.starts_at ASC"
+	}
+
+	return queryMaintenance(s.db,
+		"SELECT "+maintenanceColumns+" FROM maintenance_windows w JOIN projects p ON p.id = w.project_id"+qf.where()+" ORDER BY "+order+" LIMIT "+qf.next(filter.Limit),
+		qf.args...,
+	)
+}
+
+// ListUpcoming returns the scheduled and running windows of every project
+// that is not inactive, soonest first
+func (s *MaintenanceService) ListUpcoming(limit int) ([]domain.MaintenanceWindow, error) {
+	if limit <= 0 {
+		limit = defaultMaintenanceLimit
+	}
+
+	return queryMaintenance(s.db,
+		"SELECT "+maintenanceColumns+" FROM maintenance_windows w JOIN projects p ON p.id = w.project_id WHERE w.ends_at > CURRENT_TIMESTAMP AND p.status <> $1 ORDER BY w.starts_at ASC, w.id ASC LIMIT $2",
+		domain.ProjectInactive, limit,
+	)
+}
+
+// NextWindows returns the running window of each project, or its next
+// scheduled one when none is running, keyed by project ID
+func (s *MaintenanceService) NextWindows() (map[int]domain.MaintenanceWindow, error) {
+	windows, err := queryMaintenance(s.db,
+		"SELECT DISTINCT ON (w.project_id) "+maintenanceColumns+" FROM maintenance_windows w JOIN projects p ON p.id = w.project_id WHERE w.ends_at > CURRENT_TIMESTAMP ORDER BY w.project_id, w.starts_at ASC",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	next := make(map[int]domain.MaintenanceWindow, len(windows))
+	for _, w := range windows {
+		next[w.ProjectID] = w
+	}
+
+	return next, nil
+}
+
+// NextWindow returns the running window of a project, or its next scheduled
+// one when none is running, or nil when it has neither
+func (s *MaintenanceService) NextWindow(projectID int) (*domain.MaintenanceWindow, error) {
+	w, err := scanMaintenance(s.db.QueryRow(
+		"SELECT "+maintenanceColumns+" FROM maintenance_windows w JOIN projects p ON p.id = w.project_id WHERE w.project_id = $1 AND w.ends_at > CURRENT_TIMESTAMP ORDER BY w.starts_at ASC LIMIT 1",
+		projectID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return w, nil
+}
+
+// CreateWindow schedules a maintenance window for a project
+func (s *MaintenanceService) CreateWindow(actor domain.Actor, projectID int, req *domain.CreateMaintenanceRequest) (*domain.MaintenanceWindow, error) {
+	startsAt, endsAt := req.StartsAt.UTC(), req.EndsAt.UTC()
+	if err := validateWindow(startsAt, endsAt); err != nil {
+		return nil, err
+	}
+
+	var window *domain.MaintenanceWindow
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		project, err := getProjectByID(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		var id int
+		err = tx.QueryRow(
+			"INSERT INTO maintenance_windows (project_id, starts_at, ends_at, message) VALUES ($1, $2, $3, $4) RETURNING id",
+			projectID, startsAt, endsAt, req.Message,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		window, err = getWindow(tx, projectID, id, false)
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit.Record(tx, actor, domain.AuditActionMaintenanceCreate, domain.AuditResourceMaintenance, id, diffDetails(nil, window)); err != nil {
+			return err
+		}
+
+		// Project pages show the upcoming window
+		return s.projects.invalidate(tx, project, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return window, nil
+}
+
+// UpdateWindow reschedules a window or changes its message. A running
+// window moved into the future ends now and starts again on schedule.
+// Completed windows cannot be changed.
+func (s *MaintenanceService) UpdateWindow(actor domain.Actor, projectID, id int, req *domain.UpdateMaintenanceRequest) (*domain.MaintenanceWindow, error) {
+	var window *domain.MaintenanceWindow
+
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getWindow(tx, projectID, id, true)
+		if err != nil {
+			return err
+		}
+
+		if before.State == domain.MaintenanceCompleted {
+			return ErrMaintenanceDone
+		}
+
+		startsAt, endsAt := before.StartsAt, before.EndsAt
+		if req.StartsAt != nil {
+			startsAt = req.StartsAt.UTC()
+		}
+		if req.EndsAt != nil {
+			endsAt = req.EndsAt.UTC()
+		}
+		if err := validateWindow(startsAt, endsAt); err != nil {
+			return err
+		}
+
+		var (
+			started  bool
+			previous sql.NullString
+		)
+		err = tx.QueryRow("SELECT started_at IS NOT NULL AND ended_at IS NULL, previous_status FROM maintenance_windows WHERE id = $1", id).Scan(&started, &previous)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if started && startsAt.After(time.Now()) {
+			if err := s.end(tx, actor, id, projectID, previous); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("UPDATE maintenance_windows SET started_at = NULL, ended_at = NULL, previous_status = NULL WHERE id = $1", id); err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+		}
+
+		_, err = tx.Exec(
+			"UPDATE maintenance_windows SET starts_at = $1, ends_at = $2, message = COALESCE(NULLIF($3, ''), message), updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+			startsAt, endsAt, req.Message, id,
+		)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		window, err = getWindow(tx, projectID, id, false)
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit.Record(tx, actor, domain.AuditActionMaintenanceUpdate, domain.AuditResourceMaintenance, id, diffDetails(before, window)); err != nil {
+			return err
+		}
+
+		project, err := getProjectByID(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		return s.projects.invalidate(tx, project, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return window, nil
+}
+
+// DeleteWindow deletes a window; a running window is ended first, restoring
+// the project's status
+func (s *MaintenanceService) DeleteWindow(actor domain.Actor, projectID, id int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getWindow(tx, projectID, id, true)
+		if err != nil {
+			return err
+		}
+
+		var (
+			started  bool
+			previous sql.NullString
+		)
+		err = tx.QueryRow("SELECT started_at IS NOT NULL AND ended_at IS NULL, previous_status FROM maintenance_windows WHERE id = $1", id).Scan(&started, &previous)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if started {
+			if err := s.end(tx, actor, id, projectID, previous); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec("DELETE FROM maintenance_windows WHERE id = $1", id); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if err := s.audit.Record(tx, actor, domain.AuditActionMaintenanceDelete, domain.AuditResourceMaintenance, id, diffDetails(before, nil)); err != nil {
+			return err
+		}
+
+		project, err := getProjectByID(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		return s.projects.invalidate(tx, project, nil)
+	})
+}
+
+// validateWindow rejects windows that end before they start or have
+// already ended
+func validateWindow(startsAt, endsAt time.Time) error {
+	if !endsAt.After(startsAt) || !endsAt.After(time.Now()) {
+		return ErrMaintenanceInvalid
+	}
+	return nil
+}
+
+// getWindow retrieves a project's window by ID, locking it when lock is set
+func getWindow(q querier, projectID, id int, lock bool) (*domain.MaintenanceWindow, error) {
+	query := "SELECT " + maintenanceColumns + " FROM maintenance_windows w JOIN projects p ON p.id = w.project_id WHERE w.id = $1 AND w.project_id = $2"
+	if lock {
+		query += " FOR UPDATE OF w"
+	}
+
+	w, err := scanMaintenance(q.QueryRow(query, id, projectID))
+	if err == sql.ErrNoRows {
+		return nil, ErrMaintenanceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return w, nil
+}
+
+// queryMaintenance runs a query selecting maintenanceColumns and scans
+// every row
+func queryMaintenance(q querier, query string, args ...interface{}) ([]domain.MaintenanceWindow, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []domain.MaintenanceWindow{}
+	for rows.Next() {
+		w, err := scanMaintenance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		windows = append(windows, *w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return windows, nil
+}
+
+// scanMaintenance scans a row selected with maintenanceColumns
+func scanMaintenance(row rowScanner) (*domain.MaintenanceWindow, error) {
+	var w domain.MaintenanceWindow
+	err := row.Scan(&w.ID, &w.ProjectID, &w.ProjectName, &w.ProjectSlug, &w.StartsAt, &w.EndsAt, &w.Message, &w.State, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Columns are TIMESTAMPTZ, read in the session time zone
+	w.StartsAt, w.EndsAt = w.StartsAt.UTC(), w.EndsAt.UTC()
+	w.CreatedAt, w.UpdatedAt = w.CreatedAt.UTC(), w.UpdatedAt.UTC()
+
+	return &w, nil
+}
@@ -4,26 +4,154 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/kanyaarss/kanyaars-portal/internal/cache"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
 )
 
 // PortalService handles portal configuration operations
 type PortalService struct {
-	db *sql.DB
+	db       *sql.DB
+	audit    *AuditService
+	webhooks *WebhookService
+	cache    *cache.Loader
 }
 
-// NewPortalService creates a new portal service
-func NewPortalService(db *sql.DB) *PortalService {
-	return &PortalService{db: db}
+// NewPortalService creates a new portal service; loader may be nil to
+// disable caching
+func NewPortalService(db *sql.DB, audit *AuditService, webhooks *WebhookService, loader *cache.Loader) *PortalService {
+	return &PortalService{db: db, audit: audit, webhooks: webhooks, cache: loader}
 }
 
-// GetPortal retrieves portal configuration
+// GetPortal retrieves portal configuration, through the cache
 func (s *PortalService) GetPortal() (*domain.Portal, error) {
+	var portal *domain.Portal
+	err := s.cache.Fetch(cache.KeyPortal, &portal, func() (err error) {
+		portal, err = getPortal(s.db, false)
+		return err
+	})
+	return portal, err
+}
+
+// UpdatePortal updates portal configuration, creating it on first use, when
+// a name is required
+func (s *PortalService) UpdatePortal(actor domain.Actor, req *domain.UpdatePortalRequest) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getPortal(tx, true)
+
+		if err == ErrPortalNotConfigured {
+			if len(req.Name) < 3 {
+				return &domain.ValidationError{Fields: []domain.FieldError{
+					{Field: "name", Rule: "required", Message: "is required to create the portal"},
+				}}
+			}
+
+			_, err := tx.Exec(
+				"INSERT INTO portal_config (name, description, logo_url, website, email, phone, address) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+				req.Name, req.Description, req.LogoURL, req.Website, req.Email, req.Phone, req.Address,
+			)
+			if err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+			return s.recordUpdate(tx, actor, nil)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		doc := portalDocument(before)
+		overwrite(&doc.Name, req.Name)
+		overwrite(&doc.Description, req.Description)
+		overwrite(&doc.LogoURL, req.LogoURL)
+		overwrite(&doc.Website, req.Website)
+		overwrite(&doc.Email, req.Email)
+		overwrite(&doc.Phone, req.Phone)
+		overwrite(&doc.Address, req.Address)
+
+		return s.savePortal(tx, actor, before, &doc)
+	})
+}
+
+// PatchPortal applies a patch to the portal configuration, which must
+// exist. The patch function runs inside the transaction on the stored state
+// and its errors are returned unchanged.
+func (s *PortalService) PatchPortal(actor domain.Actor, patch func(doc *domain.PortalDocument) error) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getPortal(tx, true)
+		if err != nil {
+			return err
+		}
+
+		doc := portalDocument(before)
+		if err := patch(&doc); err != nil {
+			return err
+		}
+
+		return s.savePortal(tx, actor, before, &doc)
+	})
+}
+
+// savePortal writes doc over the portal configuration before inside tx
+func (s *PortalService) savePortal(tx *sql.Tx, actor domain.Actor, before *domain.Portal, doc *domain.PortalDocument) error {
+	_, err := tx.Exec(
+		"UPDATE portal_config SET name = $1, description = NULLIF($2, ''), logo_url = NULLIF($3, ''), website = NULLIF($4, ''), email = NULLIF($5, ''), phone = NULLIF($6, ''), address = NULLIF($7, ''), updated_at = CURRENT_TIMESTAMP WHERE id = $8",
+		doc.Name, doc.Description, doc.LogoURL, doc.Website, doc.Email, doc.Phone, doc.Address, before.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return s.recordUpdate(tx, actor, before)
+}
+
+// recordUpdate records the audit entry of a portal change from before,
+// which is nil when the portal was created, and publishes portal.updated
+func (s *PortalService) recordUpdate(tx *sql.Tx, actor domain.Actor, before *domain.Portal) error {
+	after, err := getPortal(tx, false)
+	if err != nil {
+		return err
+	}
+
+	details := diffDetails(before, after)
+	if err := s.audit.Record(tx, actor, domain.AuditActionPortalUpdate, domain.AuditResourcePortal, after.ID, details); err != nil {
+		return err
+	}
+
+	if len(details.Changes) == 0 {
+		return nil
+	}
+
+	if err := s.cache.Invalidate(tx, cache.KeyPortal); err != nil {
+		return err
+	}
+
+	return s.webhooks.Publish(tx, domain.EventPortalUpdated, domain.PortalEventData{Portal: after, Changes: details.Changes})
+}
+
+// portalDocument returns the editable fields of p
+func portalDocument(p *domain.Portal) domain.PortalDocument {
+	return domain.PortalDocument{
+		Name:        p.Name,
+		Description: p.Description,
+		LogoURL:     p.LogoURL,
+		Website:     p.Website,
+		Email:       p.Email,
+		Phone:       p.Phone,
+		Address:     p.Address,
+	}
+}
+
+// getPortal retrieves portal configuration using the given querier,
+// locking it until the transaction ends when lock is set
+func getPortal(q querier, lock bool) (*domain.Portal, error) {
 	var portal domain.Portal
 
-	err := s.db.QueryRow(
-		"SELECT id, name, description, logo_url, website, email, phone, address, created_at, updated_at FROM portal_config LIMIT 1",
-	).Scan(
+	query := "SELECT id, name, COALESCE(description, ''), COALESCE(logo_url, ''), COALESCE(website, ''), COALESCE(email, ''), COALESCE(phone, ''), COALESCE(address, ''), created_at, updated_at FROM portal_config LIMIT 1"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	err := q.QueryRow(query).Scan(
 		&portal.ID,
 		&portal.Name,
 		&portal.Description,
@@ -37,7 +165,7 @@ func (s *PortalService) GetPortal() (*domain.Portal, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("portal not configured")
+		return nil, ErrPortalNotConfigured
 	}
 
 	if err != nil {
@@ -46,34 +174,3 @@ func (s *PortalService) GetPortal() (*domain.Portal, error) {
 
 	return &portal, nil
 }
-
-// UpdatePortal updates portal configuration
-func (s *PortalService) UpdatePortal(req *domain.UpdatePortalRequest) error {
-	// Check if portal config exists
-	var id int
-	err := s.db.QueryRow("SELECT id FROM portal_config LIMIT 1").Scan(&id)
-
-	if err == sql.ErrNoRows {
-		// Create new portal config
-		_, err := s.db.Exec(
-			"INSERT INTO portal_config (name, description, logo_url, website, email, phone, address) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-			req.Name, req.Description, req.LogoURL, req.Website, req.Email, req.Phone, req.Address,
-		)
-		if err != nil {
-			return fmt.Errorf("database error: %w", err)
-		}
-	} else if err == nil {
-		// Update existing portal config
-		_, err := s.db.Exec(
-			"UPDATE portal_config SET name = COALESCE(NULLIF($1, ''), name), description = COALESCE(NULLIF($2, ''), description), logo_url = COALESCE(NULLIF($3, ''), logo_url), website = COALESCE(NULLIF($4, ''), website), email = COALESCE(NULLIF($5, ''), email), phone = COALESCE(NULLIF($6, ''), phone), address = COALESCE(NULLIF($7, ''), address), updated_at = CURRENT_TIMESTAMP WHERE id = $8",
-			req.Name, req.Description, req.LogoURL, req.Website, req.Email, req.Phone, req.Address, id,
-		)
-		if err != nil {
-			return fmt.Errorf("database error: %w", err)
-		}
-	} else {
-		return fmt.Errorf("database error: %w", err)
-	}
-
-	return nil
-}
@@ -4,141 +4,413 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/kanyaarss/kanyaars-portal/internal/cache"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
 	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
 )
 
+// projectColumns is the column list scanned by scanProject
+const projectColumns = `id, name, slug, COALESCE(description, ''), url, COALESCE(icon_url, ''), status, "order", tags, managed, created_at, updated_at`
+
 // ProjectService handles project operations
 type ProjectService struct {
-	db *sql.DB
+	db       *sql.DB
+	audit    *AuditService
+	webhooks *WebhookService
+	cache    *cache.Loader
+	cfg      config.SyncConfig
 }
 
-// NewProjectService creates a new project service
-func NewProjectService(db *sql.DB) *ProjectService {
-	return &ProjectService{db: db}
+// NewProjectService creates a new project service; loader may be nil to
+// disable caching
+func NewProjectService(db *sql.DB, audit *AuditService, webhooks *WebhookService, loader *cache.Loader, cfg config.SyncConfig) *ProjectService {
+	if cfg.ManagedPolicy == "" {
+		cfg.ManagedPolicy = domain.ManagedPolicyWarn
+	}
+
+	return &ProjectService{db: db, audit: audit, webhooks: webhooks, cache: loader, cfg: cfg}
 }
 
 // GetAllProjects retrieves all projects
 func (s *ProjectService) GetAllProjects() ([]domain.Project, error) {
-	rows, err := s.db.Query(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at, updated_at FROM projects ORDER BY \"order\" ASC",
-	)
+	return queryProjects(s.db, "SELECT "+projectColumns+" FROM projects ORDER BY \"order\" ASC")
+}
+
+// GetActiveProjects retrieves only active projects, through the cache
+func (s *ProjectService) GetActiveProjects() ([]domain.Project, error) {
+	var projects []domain.Project
+	err := s.cache.Fetch(cache.KeyActiveProjects, &projects, func() (err error) {
+		projects, err = queryProjects(s.db, "SELECT "+projectColumns+" FROM projects WHERE status = 'active' ORDER BY \"order\" ASC")
+		return err
+	})
+	return projects, err
+}
+
+// GetListedProjects retrieves the projects shown on the projects page:
+// active ones and those under maintenance
+func (s *ProjectService) GetListedProjects() ([]domain.Project, error) {
+	return queryProjects(s.db, "SELECT "+projectColumns+" FROM projects WHERE status IN ($1, $2) ORDER BY \"order\" ASC", domain.ProjectActive, domain.ProjectMaintenance)
+}
+
+// GetProjectByID retrieves a project by ID
+func (s *ProjectService) GetProjectByID(id int) (*domain.Project, error) {
+	return getProjectByID(s.db, id)
+}
+
+// GetProjectBySlug retrieves a project by slug
+func (s *ProjectService) GetProjectBySlug(slug string) (*domain.Project, error) {
+	p, err := scanProject(s.db.QueryRow("SELECT "+projectColumns+" FROM projects WHERE slug = $1", slug))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrProjectNotFound
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
-	defer rows.Close()
 
-	var projects []domain.Project
-	for rows.Next() {
-		var p domain.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("scan error: %w", err)
+	return p, nil
+}
+
+// CreateProject creates a new project, generating its slug from the name
+// when none is given
+func (s *ProjectService) CreateProject(actor domain.Actor, req *domain.CreateProjectRequest) (int, error) {
+	if req.Slug != "" {
+		if err := validateSlug(req.Slug); err != nil {
+			return 0, err
 		}
-		projects = append(projects, p)
 	}
 
-	return projects, nil
+	var id int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		id, err = s.createProject(tx, actor, req)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
 }
 
-// GetActiveProjects retrieves only active projects
-func (s *ProjectService) GetActiveProjects() ([]domain.Project, error) {
-	rows, err := s.db.Query(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at, updated_at FROM projects WHERE status = 'active' ORDER BY \"order\" ASC",
+// UpdateProject updates a project. A changed slug is kept in the slug
+// history so links using the old one can be redirected.
+func (s *ProjectService) UpdateProject(actor domain.Actor, id int, req *domain.UpdateProjectRequest) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return s.updateProject(tx, actor, id, req)
+	})
+}
+
+// PatchProject applies a patch to the editable fields of a project. The
+// patch function runs inside the transaction on the stored state and its
+// errors are returned unchanged.
+func (s *ProjectService) PatchProject(actor domain.Actor, id int, patch func(doc *domain.ProjectDocument) error) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := lockProject(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.editable(before); err != nil {
+			return err
+		}
+
+		doc := projectDocument(before)
+		if err := patch(&doc); err != nil {
+			return err
+		}
+
+		return s.saveProject(tx, actor, before, &doc)
+	})
+}
+
+// createProject inserts a project inside tx, recording the audit entry and
+// the project.created event
+func (s *ProjectService) createProject(tx *sql.Tx, actor domain.Actor, req *domain.CreateProjectRequest) (int, error) {
+	slug := req.Slug
+	if slug == "" {
+		generated, err := generateSlug(tx, req.Name)
+		if err != nil {
+			return 0, err
+		}
+		slug = generated
+	}
+
+	// Old slugs keep redirecting to their project, so they stay taken
+	if err := claimSlug(tx, slug, 0); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := tx.QueryRow(
+		"INSERT INTO projects (name, slug, description, url, icon_url, status, tags) VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, '{}'::text[])) RETURNING id",
+		req.Name, slug, req.Description, req.URL, req.IconURL, req.Status, pq.Array(req.Tags),
+	).Scan(&id)
+	if isUniqueViolation(err, "projects_slug_key") {
+		return 0, ErrSlugTaken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	after, err := getProjectByID(tx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.audit.Record(tx, actor, domain.AuditActionProjectCreate, domain.AuditResourceProject, id, diffDetails(nil, after)); err != nil {
+		return 0, err
+	}
+
+	if err := s.invalidate(tx, after, nil); err != nil {
+		return 0, err
+	}
+
+	if err := s.webhooks.Publish(tx, domain.EventProjectCreated, domain.ProjectEventData{Project: after}); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// updateProject updates a project inside tx, recording the audit entry and
+// the change events
+func (s *ProjectService) updateProject(tx *sql.Tx, actor domain.Actor, id int, req *domain.UpdateProjectRequest) error {
+	before, err := lockProject(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.editable(before); err != nil {
+		return err
+	}
+
+	doc := projectDocument(before)
+	overwrite(&doc.Name, req.Name)
+	overwrite(&doc.Slug, req.Slug)
+	overwrite(&doc.Description, req.Description)
+	overwrite(&doc.URL, req.URL)
+	overwrite(&doc.IconURL, req.IconURL)
+	overwrite(&doc.Status, req.Status)
+	if req.Tags != nil {
+		doc.Tags = req.Tags
+	}
+
+	return s.saveProject(tx, actor, before, &doc)
+}
+
+// saveProject writes doc over the editable fields of before inside tx,
+// recording the audit entry and the change events. A changed slug is
+// validated and kept in the slug history.
+func (s *ProjectService) saveProject(tx *sql.Tx, actor domain.Actor, before *domain.Project, doc *domain.ProjectDocument) error {
+	id := before.ID
+
+	if doc.Slug != before.Slug {
+		if err := validateSlug(doc.Slug); err != nil {
+			return err
+		}
+		if err := claimSlug(tx, doc.Slug, id); err != nil {
+			return err
+		}
+		if err := recordSlugChange(tx, id, before.Slug); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(
+		"UPDATE projects SET name = $1, slug = $2, description = NULLIF($3, ''), url = $4, icon_url = NULLIF($5, ''), status = $6, tags = COALESCE($7, '{}'::text[]), updated_at = CURRENT_TIMESTAMP WHERE id = $8",
+		doc.Name, doc.Slug, doc.Description, doc.URL, doc.IconURL, doc.Status, pq.Array(doc.Tags), id,
 	)
+	if isUniqueViolation(err, "projects_slug_key") {
+		return ErrSlugTaken
+	}
 	if err != nil {
-		return nil, fmt.Errorf("database error: %w", err)
+		return fmt.Errorf("database error: %w", err)
 	}
-	defer rows.Close()
 
-	var projects []domain.Project
-	for rows.Next() {
-		var p domain.Project
-		if err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("scan error: %w", err)
+	after, err := getProjectByID(tx, id)
+	if err != nil {
+		return err
+	}
+
+	details := diffDetails(before, after)
+	if err := s.audit.Record(tx, actor, domain.AuditActionProjectUpdate, domain.AuditResourceProject, id, details); err != nil {
+		return err
+	}
+
+	return s.publishUpdate(tx, after, details.Changes)
+}
+
+// DeleteProject deletes a project
+func (s *ProjectService) DeleteProject(actor domain.Actor, id int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getProjectByID(tx, id)
+		if err != nil {
+			return err
 		}
-		projects = append(projects, p)
+
+		if err := s.editable(before); err != nil {
+			return err
+		}
+
+		return s.deleteProject(tx, actor, before)
+	})
+}
+
+// deleteProject deletes a project inside tx, recording the audit entry and
+// the project.deleted event
+func (s *ProjectService) deleteProject(tx *sql.Tx, actor domain.Actor, before *domain.Project) error {
+	if _, err := tx.Exec("DELETE FROM projects WHERE id = $1", before.ID); err != nil {
+		return fmt.Errorf("database error: %w", err)
 	}
 
-	return projects, nil
+	if err := s.audit.Record(tx, actor, domain.AuditActionProjectDelete, domain.AuditResourceProject, before.ID, diffDetails(before, nil)); err != nil {
+		return err
+	}
+
+	if err := s.invalidate(tx, before, nil); err != nil {
+		return err
+	}
+
+	return s.webhooks.Publish(tx, domain.EventProjectDeleted, domain.ProjectEventData{Project: before})
 }
 
-// GetProjectByID retrieves a project by ID
-func (s *ProjectService) GetProjectByID(id int) (*domain.Project, error) {
-	var p domain.Project
-	err := s.db.QueryRow(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at, updated_at FROM projects WHERE id = $1",
-		id,
-	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, &p.CreatedAt, &p.UpdatedAt)
+// editable rejects changes outside sync to a managed project when the
+// managed policy is block
+func (s *ProjectService) editable(p *domain.Project) error {
+	if p.Managed && s.cfg.ManagedPolicy == domain.ManagedPolicyBlock {
+		return ErrProjectManaged
+	}
+	return nil
+}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("project not found")
+// publishUpdate invalidates the cached views of a changed project and
+// publishes project.updated, and project.status_changed as well when its
+// status moved
+func (s *ProjectService) publishUpdate(tx *sql.Tx, after *domain.Project, changes map[string]domain.AuditChange) error {
+	if len(changes) == 0 {
+		return nil
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("database error: %w", err)
+	if err := s.invalidate(tx, after, changes); err != nil {
+		return err
 	}
 
-	return &p, nil
+	if err := s.webhooks.Publish(tx, domain.EventProjectUpdated, domain.ProjectEventData{Project: after, Changes: changes}); err != nil {
+		return err
+	}
+
+	if change, ok := changes["status"]; ok {
+		return s.webhooks.Publish(tx, domain.EventProjectStatusChanged, domain.ProjectEventData{
+			Project: after,
+			Changes: map[string]domain.AuditChange{"status": change},
+		})
+	}
+
+	return nil
 }
 
-// GetProjectBySlug retrieves a project by slug
-func (s *ProjectService) GetProjectBySlug(slug string) (*domain.Project, error) {
-	var p domain.Project
-	err := s.db.QueryRow(
-		"SELECT id, name, slug, description, url, icon_url, status, \"order\", created_at, updated_at FROM projects WHERE slug = $1",
-		slug,
-	).Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, &p.CreatedAt, &p.UpdatedAt)
+// getProjectByID retrieves a project by ID using the given querier
+func getProjectByID(q querier, id int) (*domain.Project, error) {
+	p, err := scanProject(q.QueryRow("SELECT "+projectColumns+" FROM projects WHERE id = $1", id))
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("project not found")
+		return nil, ErrProjectNotFound
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
-	return &p, nil
+	return p, nil
 }
 
-// CreateProject creates a new project
-func (s *ProjectService) CreateProject(req *domain.CreateProjectRequest) (int, error) {
-	var id int
-	err := s.db.QueryRow(
-		"INSERT INTO projects (name, slug, description, url, icon_url, status) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		req.Name, req.Slug, req.Description, req.URL, req.IconURL, req.Status,
-	).Scan(&id)
+// lockProject retrieves a project inside tx, locking it until tx ends
+func lockProject(tx *sql.Tx, id int) (*domain.Project, error) {
+	p, err := scanProject(tx.QueryRow("SELECT "+projectColumns+" FROM projects WHERE id = $1 FOR UPDATE", id))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrProjectNotFound
+	}
 
 	if err != nil {
-		return 0, fmt.Errorf("database error: %w", err)
+		return nil, fmt.Errorf("database error: %w", err)
 	}
 
-	return id, nil
+	return p, nil
 }
 
-// UpdateProject updates a project
-func (s *ProjectService) UpdateProject(id int, req *domain.UpdateProjectRequest) error {
-	_, err := s.db.Exec(
-		"UPDATE projects SET name = COALESCE(NULLIF($1, ''), name), slug = COALESCE(NULLIF($2, ''), slug), description = COALESCE(NULLIF($3, ''), description), url = COALESCE(NULLIF($4, ''), url), icon_url = COALESCE(NULLIF($5, ''), icon_url), status = COALESCE(NULLIF($6, ''), status), updated_at = CURRENT_TIMESTAMP WHERE id = $7",
-		req.Name, req.Slug, req.Description, req.URL, req.IconURL, req.Status, id,
-	)
+// projectDocument returns the editable fields of p
+func projectDocument(p *domain.Project) domain.ProjectDocument {
+	return domain.ProjectDocument{
+		Name:        p.Name,
+		Slug:        p.Slug,
+		Description: p.Description,
+		URL:         p.URL,
+		IconURL:     p.IconURL,
+		Status:      p.Status,
+		Tags:        p.Tags,
+	}
+}
+
+// overwrite sets dst to value unless value is empty, the PUT convention for
+// leaving a field unchanged
+func overwrite(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
 
+// queryProjects runs a query selecting projectColumns and scans every row
+func queryProjects(q querier, query string, args ...interface{}) ([]domain.Project, error) {
+	rows, err := q.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("database error: %w", err)
+		return nil, fmt.Errorf("database error: %w", err)
 	}
+	defer rows.Close()
 
-	return nil
+	projects := []domain.Project{}
+	for rows.Next() {
+		p, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		projects = append(projects, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return projects, nil
 }
 
-// DeleteProject deletes a project
-func (s *ProjectService) DeleteProject(id int) error {
-	result, err := s.db.Exec("DELETE FROM projects WHERE id = $1", id)
+// scanProject scans a row selected with projectColumns
+func scanProject(row rowScanner) (*domain.Project, error) {
+	var p domain.Project
+	err := row.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, pq.Array(&p.Tags), &p.Managed, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("database error: %w", err)
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		return fmt.Errorf("project not found")
+	if p.Tags == nil {
+		p.Tags = []string{}
 	}
 
-	return nil
+	return &p, nil
+}
+
+// invalidate drops the cached lists and the page of p, under its previous
+// slug too when changes renamed it
+func (s *ProjectService) invalidate(tx *sql.Tx, p *domain.Project, changes map[string]domain.AuditChange) error {
+	keys := []string{cache.KeyActiveProjects, cache.KeyProjectsPage, cache.ProjectPageKey(p.Slug)}
+	if change, ok := changes["slug"]; ok {
+		if old, ok := change.From.(string); ok {
+			keys = append(keys, cache.ProjectPageKey(old))
+		}
+	}
+
+	return s.cache.Invalidate(tx, keys...)
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// ListTags returns every tag with its project count, most used first. When
+// activeOnly is set only active projects are counted.
+func (s *ProjectService) ListTags(activeOnly bool) ([]domain.TagCount, error) {
+	where := ""
+	if activeOnly {
+		where = " WHERE status = 'active'"
+	}
+
+	rows, err := s.db.Query("SELECT tag, COUNT(*) FROM projects, unnest(tags) AS tag" + where + " GROUP BY tag ORDER BY COUNT(*) DESC, tag ASC")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []domain.TagCount{}
+	for rows.Next() {
+		var t domain.TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		tags = append(tags, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return tags, nil
+}
+
+// StatusHistory returns the status transitions of the given projects, oldest
+// first, keyed by project ID. It reads the status diffs the audit log keeps
+// for project writes, so all projects are loaded in a single query.
+func (s *ProjectService) StatusHistory(projectIDs []int) (map[int][]domain.ProjectStatusChange, error) {
+	history := map[int][]domain.ProjectStatusChange{}
+	if len(projectIDs) == 0 {
+		return history, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT resource_id, user_id, created_at,
+			COALESCE(details::jsonb #>> '{changes,status,from}', ''),
+			COALESCE(details::jsonb #>> '{changes,status,to}', '')
+		FROM audit_logs
+		WHERE resource = $1 AND resource_id = ANY($2) AND details::jsonb -> 'changes' ? 'status'
+		ORDER BY id ASC`,
+		domain.AuditResourceProject, pq.Array(projectIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			change domain.ProjectStatusChange
+			userID sql.NullInt64
+		)
+		if err := rows.Scan(&change.ProjectID, &userID, &change.ChangedAt, &change.From, &change.To); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		if userID.Valid {
+			id := int(userID.Int64)
+			change.UserID = &id
+		}
+		history[change.ProjectID] = append(history[change.ProjectID], change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return history, nil
+}
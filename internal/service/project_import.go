@@ -0,0 +1,218 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// ImportProjects plans and, unless dryRun is set, applies an import of
+// decoded rows. A row without a slug uses the slug derived from its name.
+// In create mode every slug must be unused; in upsert mode a row whose slug
+// belongs to a project updates it, leaving empty fields unchanged. Rows
+// that already carry errors are reported as invalid. The whole import is
+// applied in one transaction, and only when every row is valid; otherwise
+// the plan is returned with ErrImportInvalid.
+func (s *ProjectService) ImportProjects(actor domain.Actor, rows []domain.ProjectImportRow, mode string, dryRun bool) (*domain.ProjectImportResult, error) {
+	if mode == "" {
+		mode = domain.ImportModeCreate
+	}
+
+	if dryRun {
+		return s.planImport(s.db, rows, mode)
+	}
+
+	var result *domain.ProjectImportResult
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		if result, err = s.planImport(tx, rows, mode); err != nil {
+			return err
+		}
+		if result.Invalid > 0 {
+			return ErrImportInvalid
+		}
+
+		for _, row := range result.Rows {
+			switch row.Action {
+			case domain.ImportActionCreate:
+				if _, err := s.createProject(tx, actor, &row.Project); err != nil {
+					return fmt.Errorf("row %d: %w", row.Row, err)
+				}
+			case domain.ImportActionUpdate:
+				if err := s.updateProject(tx, actor, row.ProjectID, importUpdate(&row.Project)); err != nil {
+					return fmt.Errorf("row %d: %w", row.Row, err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err == ErrImportInvalid {
+		return result, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+// planImport decides the action of every row against the projects and slug
+// history visible to q
+func (s *ProjectService) planImport(q querier, rows []domain.ProjectImportRow, mode string) (*domain.ProjectImportResult, error) {
+	projects, err := queryProjects(q, "SELECT "+projectColumns+" FROM projects")
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]*domain.Project, len(projects))
+	for i := range projects {
+		bySlug[projects[i].Slug] = &projects[i]
+	}
+
+	history, err := slugHistory(q)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ProjectImportResult{Mode: mode, Rows: make([]domain.ProjectImportRow, 0, len(rows))}
+	seen := map[string]int{}
+
+	for _, row := range rows {
+		if row.Project.Slug == "" {
+			row.Project.Slug = Slugify(row.Project.Name)
+		}
+		row.Slug = row.Project.Slug
+
+		if row.Errors == nil {
+			row.Errors = planSlug(&row, mode, seen, bySlug, history)
+		}
+
+		if len(row.Errors) > 0 {
+			row.Action = domain.ImportActionInvalid
+			result.Invalid++
+		} else if existing := bySlug[row.Slug]; existing != nil && s.editable(existing) != nil {
+			row.Errors = []domain.FieldError{{Field: "slug", Rule: "managed", Message: ErrProjectManaged.Error()}}
+			row.Action = domain.ImportActionInvalid
+			result.Invalid++
+		} else if existing != nil {
+			row.ProjectID = existing.ID
+			row.Action = domain.ImportActionUpdate
+			if !importChanges(existing, &row.Project) {
+				row.Action = domain.ImportActionUnchanged
+			}
+		} else {
+			row.Action = domain.ImportActionCreate
+		}
+
+		switch row.Action {
+		case domain.ImportActionCreate:
+			result.Created++
+		case domain.ImportActionUpdate:
+			result.Updated++
+		case domain.ImportActionUnchanged:
+			result.Unchanged++
+		}
+
+		if row.Slug != "" {
+			if _, ok := seen[row.Slug]; !ok {
+				seen[row.Slug] = row.Row
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+// planSlug checks the slug of a valid row
+func planSlug(row *domain.ProjectImportRow, mode string, seen map[string]int, bySlug map[string]*domain.Project, history map[string]int) []domain.FieldError {
+	slugError := func(rule, message string) []domain.FieldError {
+		return []domain.FieldError{{Field: "slug", Rule: rule, Message: message}}
+	}
+
+	if err := validateSlug(row.Slug); err != nil {
+		return slugError("slug", err.Error())
+	}
+
+	if first, ok := seen[row.Slug]; ok {
+		return slugError("unique", fmt.Sprintf("duplicates the slug of row %d", first))
+	}
+
+	existing := bySlug[row.Slug]
+	if existing != nil && mode == domain.ImportModeCreate {
+		return slugError("unique", ErrSlugTaken.Error())
+	}
+
+	if owner, ok := history[row.Slug]; ok && existing == nil {
+		return slugError("unique", fmt.Sprintf("%s by a former slug of project %d", ErrSlugTaken, owner))
+	}
+
+	return nil
+}
+
+// slugHistory maps every former slug to its project
+func slugHistory(q querier) (map[string]int, error) {
+	rows, err := q.Query("SELECT slug, project_id FROM project_slug_history")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	history := map[string]int{}
+	for rows.Next() {
+		var (
+			slug string
+			id   int
+		)
+		if err := rows.Scan(&slug, &id); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		history[slug] = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return history, nil
+}
+
+// importUpdate converts an imported record into an update of the project
+// already holding its slug
+func importUpdate(req *domain.CreateProjectRequest) *domain.UpdateProjectRequest {
+	return &domain.UpdateProjectRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		IconURL:     req.IconURL,
+		Status:      req.Status,
+		Tags:        req.Tags,
+	}
+}
+
+// importChanges reports whether applying req as an update would change p
+func importChanges(p *domain.Project, req *domain.CreateProjectRequest) bool {
+	differs := func(value, current string) bool {
+		return value != "" && value != current
+	}
+
+	if differs(req.Name, p.Name) || differs(req.Description, p.Description) || differs(req.URL, p.URL) ||
+		differs(req.IconURL, p.IconURL) || differs(req.Status, p.Status) {
+		return true
+	}
+
+	if req.Tags == nil {
+		return false
+	}
+	if len(req.Tags) != len(p.Tags) {
+		return true
+	}
+	for i := range req.Tags {
+		if req.Tags[i] != p.Tags[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// Project list page size limits
+const (
+	defaultProjectPageSize = 20
+	maxProjectPageSize     = 100
+)
+
+// projectSortColumns maps sort keys to their column and the SQL type used
+// to compare cursor values
+var projectSortColumns = map[string]struct {
+	column   string
+	castType string
+}{
+	"name":       {`name`, "text"},
+	"order":      {`"order"`, "integer"},
+	"created_at": {`created_at`, "timestamp"},
+	"updated_at": {`updated_at`, "timestamp"},
+}
+
+// projectCursor is the keyset position encoded in project list cursors
+type projectCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// ListProjects returns a page of projects matching the filter. When
+// activeOnly is set only active projects are considered, regardless of the
+// status filter.
+func (s *ProjectService) ListProjects(filter *domain.ProjectFilter, activeOnly bool) (*domain.PaginatedResponse, error) {
+	sortKey := filter.Sort
+	if sortKey == "" {
+		sortKey = "order"
+	}
+
+	desc := strings.HasPrefix(sortKey, "-")
+	sortCol, ok := projectSortColumns[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+
+	qf := projectListFilter(filter, activeOnly)
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM projects"+qf.where(), qf.args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	pageSize := clampPageSize(filter.PageSize, defaultProjectPageSize, maxProjectPageSize)
+
+	direction, cmp := "ASC", ">"
+	if desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	page := filter.Page
+	if filter.Cursor != "" {
+		cursor, err := decodeProjectCursor(filter.Cursor)
+		if err != nil || cursor.Sort != sortKey {
+			return nil, ErrInvalidCursor
+		}
+		qf.add(fmt.Sprintf("(%s, id) %s (?::%s, ?)", sortCol.column, cmp, sortCol.castType), cursor.Value, cursor.ID)
+		page = 0
+	} else if page == 0 {
+		page = 1
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM projects%s ORDER BY %s %s, id %s LIMIT %s",
+		projectColumns, qf.where(), sortCol.column, direction, direction, qf.next(pageSize+1),
+	)
+	if page > 1 {
+		query += " OFFSET " + qf.next((page-1)*pageSize)
+	}
+
+	projects, err := queryProjects(s.db, query, qf.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := domain.NewPaginatedResponse(projects, total, page, pageSize)
+
+	// One extra row was fetched to find out whether another page exists
+	if len(projects) > pageSize {
+		projects = projects[:pageSize]
+		resp.Data = projects
+		resp.NextCursor = encodeProjectCursor(sortKey, &projects[pageSize-1])
+	}
+
+	return resp, nil
+}
+
+// ListVersion returns the version of the projects table and the time it
+// last changed. Every insert, update and delete of any project bumps both,
+// so a project leaving a filtered list (e.g. no longer active) changes the
+// list's validators too. Handlers derive cache validators from it without
+// loading the page itself.
+func (s *ProjectService) ListVersion() (int64, time.Time, error) {
+	var (
+		version   int64
+		changedAt time.Time
+	)
+	err := s.db.QueryRow("SELECT version, changed_at FROM project_changes WHERE id = 1").Scan(&version, &changedAt)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("database error: %w", err)
+	}
+
+	return version, changedAt, nil
+}
+
+// projectListFilter builds the WHERE conditions of ListProjects
+func projectListFilter(filter *domain.ProjectFilter, activeOnly bool) *queryFilter {
+	qf := &queryFilter{}
+	if activeOnly {
+		qf.add("status = 'active'")
+	} else if filter.Status != "" {
+		qf.add("status = ?", filter.Status)
+	}
+	if len(filter.Tags) > 0 {
+		qf.add("tags @> ?", pq.Array(filter.Tags))
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + escapeLike(q) + "%"
+		qf.add("(name ILIKE ? OR slug ILIKE ? OR description ILIKE ?)", pattern, pattern, pattern)
+	}
+
+	return qf
+}
+
+// encodeProjectCursor encodes the keyset position after p
+func encodeProjectCursor(sortKey string, p *domain.Project) string {
+	cursor := projectCursor{Sort: sortKey, ID: p.ID}
+
+	switch strings.TrimPrefix(sortKey, "-") {
+	case "name":
+		cursor.Value = p.Name
+	case "order":
+		cursor.Value = fmt.Sprint(p.Order)
+	case "created_at":
+		cursor.Value = p.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = p.UpdatedAt.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeProjectCursor decodes a cursor produced by encodeProjectCursor
+func decodeProjectCursor(s string) (*projectCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor projectCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
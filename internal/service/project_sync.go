@@ -0,0 +1,280 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// syncLockKey serializes concurrent syncs, e.g. watchers on several hosts
+const syncLockKey = "project_sync"
+
+// ManifestError lists the invalid entries of a manifest that cannot be
+// synced
+type ManifestError struct {
+	Rows []domain.ProjectImportRow
+}
+
+func (e *ManifestError) Error() string {
+	var lines []string
+	for _, row := range e.Rows {
+		for _, fe := range row.Errors {
+			field := fe.Field
+			if field == "" {
+				field = "project"
+			}
+			lines = append(lines, fmt.Sprintf("project %d: %s %s", row.Row, field, fe.Message))
+		}
+	}
+	return "invalid manifest:\n  " + strings.Join(lines, "\n  ")
+}
+
+// PlanSync compares a manifest with the projects table. Manifest entries
+// are matched to projects by slug, deriving it from the name when absent;
+// their position in the manifest becomes their order. Every project in the
+// manifest becomes managed, and managed projects missing from it are
+// deleted. Projects never listed in a manifest are left alone. A manifest
+// without entries, e.g. one truncated while being rewritten, would delete
+// every managed project; it is refused with ErrSyncEmptyManifest unless
+// allowEmpty is set.
+func (s *ProjectService) PlanSync(rows []domain.ProjectImportRow, allowEmpty bool) (*domain.ProjectSyncPlan, error) {
+	plan, _, err := planSync(s.db, rows, allowEmpty)
+	return plan, err
+}
+
+// ApplySync plans a sync and applies it in a single transaction, with the
+// usual audit entries and events for every change
+func (s *ProjectService) ApplySync(actor domain.Actor, rows []domain.ProjectImportRow, allowEmpty bool) (*domain.ProjectSyncPlan, error) {
+	var plan *domain.ProjectSyncPlan
+
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", syncLockKey); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		var (
+			targets map[string]*domain.Project
+			err     error
+		)
+		if plan, targets, err = planSync(tx, rows, allowEmpty); err != nil {
+			return err
+		}
+
+		for _, change := range plan.Changes {
+			if err := s.applySyncChange(tx, actor, change, targets[change.Slug]); err != nil {
+				return fmt.Errorf("%s %s: %w", change.Action, change.Slug, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Applied = true
+	return plan, nil
+}
+
+// planSync builds the plan together with the target state of every created,
+// updated or deleted project, keyed by slug
+func planSync(q querier, rows []domain.ProjectImportRow, allowEmpty bool) (*domain.ProjectSyncPlan, map[string]*domain.Project, error) {
+	if len(rows) == 0 && !allowEmpty {
+		return nil, nil, ErrSyncEmptyManifest
+	}
+
+	projects, err := queryProjects(q, "SELECT "+projectColumns+" FROM projects ORDER BY \"order\" ASC, id ASC")
+	if err != nil {
+		return nil, nil, err
+	}
+	bySlug := make(map[string]*domain.Project, len(projects))
+	for i := range projects {
+		bySlug[projects[i].Slug] = &projects[i]
+	}
+
+	history, err := slugHistory(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Validate every entry first so that no plan is shown for a broken file
+	var invalid []domain.ProjectImportRow
+	seen := map[string]int{}
+	for i := range rows {
+		row := &rows[i]
+		if row.Project.Slug == "" {
+			row.Project.Slug = Slugify(row.Project.Name)
+		}
+		row.Slug = row.Project.Slug
+
+		if row.Errors == nil {
+			row.Errors = planSlug(row, domain.ImportModeUpsert, seen, bySlug, history)
+		}
+		if len(row.Errors) > 0 {
+			invalid = append(invalid, *row)
+		}
+		if _, ok := seen[row.Slug]; !ok {
+			seen[row.Slug] = row.Row
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, nil, &ManifestError{Rows: invalid}
+	}
+
+	plan := &domain.ProjectSyncPlan{Changes: []domain.ProjectSyncChange{}}
+	targets := map[string]*domain.Project{}
+
+	for i, row := range rows {
+		req := row.Project
+		target := &domain.Project{
+			Name:        req.Name,
+			Slug:        req.Slug,
+			Description: req.Description,
+			URL:         req.URL,
+			IconURL:     req.IconURL,
+			Status:      req.Status,
+			Order:       i + 1,
+			Tags:        req.Tags,
+			Managed:     true,
+		}
+		if target.Tags == nil {
+			target.Tags = []string{}
+		}
+
+		existing := bySlug[req.Slug]
+		if existing == nil {
+			targets[req.Slug] = target
+			plan.Changes = append(plan.Changes, domain.ProjectSyncChange{
+				Action:  domain.SyncActionCreate,
+				Slug:    req.Slug,
+				Changes: diffDetails(nil, syncFields(target)).Changes,
+			})
+			continue
+		}
+
+		target.ID = existing.ID
+		target.CreatedAt = existing.CreatedAt
+		target.UpdatedAt = existing.UpdatedAt
+
+		changes := diffDetails(existing, target).Changes
+		if len(changes) == 0 {
+			plan.Unchanged++
+			continue
+		}
+
+		action := domain.SyncActionUpdate
+		if _, ok := changes["order"]; ok && len(changes) == 1 {
+			action = domain.SyncActionReorder
+		}
+
+		targets[req.Slug] = target
+		plan.Changes = append(plan.Changes, domain.ProjectSyncChange{
+			Action:    action,
+			Slug:      req.Slug,
+			ProjectID: existing.ID,
+			Changes:   changes,
+		})
+	}
+
+	for i := range projects {
+		p := &projects[i]
+		if !p.Managed || seen[p.Slug] != 0 {
+			continue
+		}
+
+		targets[p.Slug] = p
+		plan.Changes = append(plan.Changes, domain.ProjectSyncChange{
+			Action:    domain.SyncActionDelete,
+			Slug:      p.Slug,
+			ProjectID: p.ID,
+		})
+	}
+
+	return plan, targets, nil
+}
+
+// applySyncChange writes one planned change towards target
+func (s *ProjectService) applySyncChange(tx *sql.Tx, actor domain.Actor, change domain.ProjectSyncChange, target *domain.Project) error {
+	switch change.Action {
+	case domain.SyncActionCreate:
+		if err := claimSlug(tx, target.Slug, 0); err != nil {
+			return err
+		}
+
+		var id int
+		err := tx.QueryRow(
+			`INSERT INTO projects (name, slug, description, url, icon_url, status, "order", tags, managed) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE) RETURNING id`,
+			target.Name, target.Slug, target.Description, target.URL, target.IconURL, target.Status, target.Order, pq.Array(target.Tags),
+		).Scan(&id)
+		if isUniqueViolation(err, "projects_slug_key") {
+			return ErrSlugTaken
+		}
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getProjectByID(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit.Record(tx, actor, domain.AuditActionProjectCreate, domain.AuditResourceProject, id, diffDetails(nil, after)); err != nil {
+			return err
+		}
+
+		if err := s.invalidate(tx, after, nil); err != nil {
+			return err
+		}
+
+		return s.webhooks.Publish(tx, domain.EventProjectCreated, domain.ProjectEventData{Project: after})
+
+	case domain.SyncActionUpdate, domain.SyncActionReorder:
+		before, err := getProjectByID(tx, target.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`UPDATE projects SET name = $1, description = $2, url = $3, icon_url = $4, status = $5, "order" = $6, tags = $7, managed = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $8`,
+			target.Name, target.Description, target.URL, target.IconURL, target.Status, target.Order, pq.Array(target.Tags), target.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getProjectByID(tx, target.ID)
+		if err != nil {
+			return err
+		}
+
+		details := diffDetails(before, after)
+		details.Reason = "sync"
+		if err := s.audit.Record(tx, actor, domain.AuditActionProjectUpdate, domain.AuditResourceProject, target.ID, details); err != nil {
+			return err
+		}
+
+		return s.publishUpdate(tx, after, details.Changes)
+
+	case domain.SyncActionDelete:
+		return s.deleteProject(tx, actor, target)
+	}
+
+	return fmt.Errorf("unknown sync action %q", change.Action)
+}
+
+// syncFields is the part of a project a manifest defines, used to describe
+// planned creations
+func syncFields(p *domain.Project) interface{} {
+	return struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		URL         string   `json:"url"`
+		IconURL     string   `json:"icon_url"`
+		Status      string   `json:"status"`
+		Order       int      `json:"order"`
+		Tags        []string `json:"tags"`
+	}{p.Name, p.Description, p.URL, p.IconURL, p.Status, p.Order, p.Tags}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// queryFilter accumulates SQL WHERE conditions together with their
+// positional arguments
+type queryFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition; every "?" in it is replaced by the next
+// positional placeholder bound to the given arguments in order
+func (f *queryFilter) add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		f.args = append(f.args, arg)
+		condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", len(f.args)), 1)
+	}
+	f.conditions = append(f.conditions, condition)
+}
+
+// where returns the WHERE clause, or an empty string if there are no conditions
+func (f *queryFilter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// next returns the positional placeholder for an extra argument appended
+// after the filter arguments
+func (f *queryFilter) next(arg interface{}) string {
+	f.args = append(f.args, arg)
+	return fmt.Sprintf("$%d", len(f.args))
+}
+
+// encodeCursor encodes a row ID into an opaque pagination cursor
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodeCursor decodes a pagination cursor produced by encodeCursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(string(data))
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return id, nil
+}
+
+// clampPageSize applies the default page size and caps it at max
+func clampPageSize(size, def, max int) int {
+	if size <= 0 {
+		return def
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
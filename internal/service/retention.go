@@ -0,0 +1,331 @@
+package service
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+)
+
+// Retention metrics, published through expvar
+var (
+	retentionRowsDeleted  = expvar.NewMap("retention_rows_deleted")
+	retentionRowsArchived = expvar.NewMap("retention_rows_archived")
+	retentionRuns         = expvar.NewMap("retention_runs")
+	retentionFailures     = expvar.NewMap("retention_failures")
+)
+
+// identifierPattern restricts table and column names taken from config
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// RetentionService deletes or archives rows that are older than the
+// configured per-table policies
+type RetentionService struct {
+	db  *sql.DB
+	cfg config.RetentionConfig
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(db *sql.DB, cfg config.RetentionConfig) *RetentionService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	if cfg.ArchiveDir == "" {
+		cfg.ArchiveDir = "archive"
+	}
+
+	return &RetentionService{db: db, cfg: cfg}
+}
+
+// Start applies the retention policies every configured interval until
+// stop is closed
+func (s *RetentionService) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunAll()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RunAll applies every configured policy and returns the run summaries
+func (s *RetentionService) RunAll() []domain.RetentionRun {
+	runs := make([]domain.RetentionRun, 0, len(s.cfg.Policies))
+
+	for _, policy := range s.cfg.Policies {
+		run := s.Run(policy)
+		if run.Error != "" {
+			log.Printf("Retention for %s failed: %s", run.Table, run.Error)
+		} else {
+			log.Printf("Retention for %s: %d deleted, %d archived", run.Table, run.RowsDeleted, run.RowsArchived)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+// Run applies a single policy in batches and records its summary
+func (s *RetentionService) Run(policy config.RetentionPolicy) domain.RetentionRun {
+	run := domain.RetentionRun{
+		Table:     policy.Table,
+		Cutoff:    time.Now().UTC().Add(-policy.MaxAge),
+		StartedAt: time.Now().UTC(),
+	}
+
+	if err := s.apply(policy, &run); err != nil {
+		run.Error = err.Error()
+		retentionFailures.Add(policy.Table, 1)
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	retentionRuns.Add(policy.Table, 1)
+	retentionRowsDeleted.Add(policy.Table, int64(run.RowsDeleted))
+	retentionRowsArchived.Add(policy.Table, int64(run.RowsArchived))
+
+	if err := s.recordRun(&run); err != nil {
+		log.Printf("Failed to record retention run for %s: %v", policy.Table, err)
+	}
+
+	return run
+}
+
+// ListRuns returns the most recent retention run summaries
+func (s *RetentionService) ListRuns(limit int) ([]domain.RetentionRun, error) {
+	rows, err := s.db.Query(
+		"SELECT id, table_name, cutoff, rows_deleted, rows_archived, archive_file, error, started_at, finished_at FROM retention_runs ORDER BY id DESC LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []domain.RetentionRun{}
+	for rows.Next() {
+		var (
+			run         domain.RetentionRun
+			archiveFile sql.NullString
+			runErr      sql.NullString
+		)
+		if err := rows.Scan(&run.ID, &run.Table, &run.Cutoff, &run.RowsDeleted, &run.RowsArchived, &archiveFile, &runErr, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		run.ArchiveFile = archiveFile.String
+		run.Error = runErr.String
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return runs, nil
+}
+
+// apply deletes expired rows batch by batch, writing them to a compressed
+// NDJSON archive first when the policy asks for it
+func (s *RetentionService) apply(policy config.RetentionPolicy, run *domain.RetentionRun) error {
+	column := policy.Column
+	if column == "" {
+		column = "created_at"
+	}
+
+	if !identifierPattern.MatchString(policy.Table) || !identifierPattern.MatchString(column) {
+		return fmt.Errorf("invalid table or column name")
+	}
+	if policy.MaxAge <= 0 {
+		return fmt.Errorf("max_age must be positive")
+	}
+
+	qf := &queryFilter{}
+	qf.add(column+" < ?", run.Cutoff)
+
+	if policy.Table == "audit_logs" {
+		bound, err := s.auditPruneBound(run.Cutoff)
+		if err != nil {
+			return err
+		}
+		// Only cut the hash chain at a signed checkpoint so that the
+		// remaining entries can still be verified
+		qf.add("(hash IS NULL OR id <= ?)", bound)
+	}
+
+	var archive *retentionArchive
+	if policy.Archive {
+		var err error
+		archive, err = newRetentionArchive(s.cfg.ArchiveDir, policy.Table, run.StartedAt)
+		if err != nil {
+			return err
+		}
+		run.ArchiveFile = archive.path
+	}
+
+	query := fmt.Sprintf(
+		"DELETE FROM %[1]s t WHERE id IN (SELECT id FROM %[1]s%[2]s ORDER BY id LIMIT %[3]s) RETURNING row_to_json(t)::text",
+		policy.Table, qf.where(), qf.next(s.cfg.BatchSize),
+	)
+
+	var applyErr error
+	for {
+		n, err := s.deleteBatch(query, qf.args, archive)
+		if err != nil {
+			applyErr = err
+			break
+		}
+
+		run.RowsDeleted += n
+		if archive != nil {
+			run.RowsArchived += n
+		}
+
+		if n < s.cfg.BatchSize {
+			break
+		}
+	}
+
+	if archive != nil {
+		if err := archive.close(); err != nil && applyErr == nil {
+			applyErr = err
+		}
+		if run.RowsArchived == 0 {
+			os.Remove(archive.path)
+			run.ArchiveFile = ""
+		}
+	}
+
+	return applyErr
+}
+
+// deleteBatch deletes one batch in its own short transaction. Archived rows
+// are flushed to disk before the delete is committed.
+func (s *RetentionService) deleteBatch(query string, args []interface{}, archive *retentionArchive) (int, error) {
+	n := 0
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row string
+			if err := rows.Scan(&row); err != nil {
+				return fmt.Errorf("scan error: %w", err)
+			}
+			if archive != nil {
+				if err := archive.write(row); err != nil {
+					return err
+				}
+			}
+			n++
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		if archive != nil {
+			return archive.flush()
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// auditPruneBound returns the newest checkpointed audit entry that is older
+// than cutoff, or zero if there is none
+func (s *RetentionService) auditPruneBound(cutoff time.Time) (int, error) {
+	var bound int
+	err := s.db.QueryRow(
+		"SELECT COALESCE(MAX(c.last_log_id), 0) FROM audit_checkpoints c JOIN audit_logs l ON l.id = c.last_log_id WHERE l.created_at < $1",
+		cutoff,
+	).Scan(&bound)
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	return bound, nil
+}
+
+// recordRun stores a run summary in retention_runs
+func (s *RetentionService) recordRun(run *domain.RetentionRun) error {
+	return s.db.QueryRow(
+		"INSERT INTO retention_runs (table_name, cutoff, rows_deleted, rows_archived, archive_file, error, started_at, finished_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		run.Table, run.Cutoff, run.RowsDeleted, run.RowsArchived,
+		sql.NullString{String: run.ArchiveFile, Valid: run.ArchiveFile != ""},
+		sql.NullString{String: run.Error, Valid: run.Error != ""},
+		run.StartedAt, run.FinishedAt,
+	).Scan(&run.ID)
+}
+
+// retentionArchive is a gzip-compressed NDJSON file of deleted rows
+type retentionArchive struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// newRetentionArchive creates <dir>/<table>/<table>-<timestamp>.ndjson.gz
+func newRetentionArchive(dir, table string, startedAt time.Time) (*retentionArchive, error) {
+	tableDir := filepath.Join(dir, table)
+	if err := os.MkdirAll(tableDir, 0o750); err != nil {
+		return nil, fmt.Errorf("archive error: %w", err)
+	}
+
+	path := filepath.Join(tableDir, fmt.Sprintf("%s-%s.ndjson.gz", table, startedAt.Format("20060102T150405Z")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("archive error: %w", err)
+	}
+
+	return &retentionArchive{path: path, file: file, gz: gzip.NewWriter(file)}, nil
+}
+
+// write appends one JSON row
+func (a *retentionArchive) write(row string) error {
+	if _, err := a.gz.Write(append([]byte(row), '\n')); err != nil {
+		return fmt.Errorf("archive error: %w", err)
+	}
+	return nil
+}
+
+// flush pushes buffered rows to disk
+func (a *retentionArchive) flush() error {
+	if err := a.gz.Flush(); err != nil {
+		return fmt.Errorf("archive error: %w", err)
+	}
+	if err := a.file.Sync(); err != nil {
+		return fmt.Errorf("archive error: %w", err)
+	}
+	return nil
+}
+
+// close finishes the gzip stream and closes the file
+func (a *retentionArchive) close() error {
+	if err := a.gz.Close(); err != nil {
+		a.file.Close()
+		return fmt.Errorf("archive error: %w", err)
+	}
+	if err := a.file.Close(); err != nil {
+		return fmt.Errorf("archive error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// Search result limits
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+// Highlight delimiters used before HTML escaping; they cannot appear in
+// escaped text and are swapped for <mark> tags afterwards
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// ProjectSearcher finds active projects matching a text query
+type ProjectSearcher interface {
+	Search(query string, limit int) ([]domain.ProjectSearchResult, error)
+}
+
+// NewProjectSearcher returns the searcher for the configured backend:
+// "postgres" uses the full-text index, anything else the basic fallback
+func NewProjectSearcher(backend string, db *sql.DB, projectService *ProjectService) ProjectSearcher {
+	if backend == "" || backend == "postgres" {
+		return &PostgresSearcher{db: db}
+	}
+	return &BasicSearcher{projectService: projectService}
+}
+
+// PostgresSearcher ranks projects using the projects.search_vector column
+type PostgresSearcher struct {
+	db *sql.DB
+}
+
+// Search returns active projects matching every term of query as a prefix,
+// ordered by rank
+func (s *PostgresSearcher) Search(query string, limit int) ([]domain.ProjectSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []domain.ProjectSearchResult{}, nil
+	}
+
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+
+	headlineOpts := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=30, MinWords=10, HighlightAll=%%s", highlightStart, highlightStop)
+
+	rows, err := s.db.Query(
+		"SELECT "+projectColumns+`, ts_rank(search_vector, q) AS rank,
+			ts_headline('simple', name, q, $2),
+			ts_headline('simple', COALESCE(description, ''), q, $3)
+		FROM projects, to_tsquery('simple', $1) q
+		WHERE status = 'active' AND search_vector @@ q
+		ORDER BY rank DESC, "order" ASC, id ASC
+		LIMIT $4`,
+		strings.Join(prefixes, " & "),
+		fmt.Sprintf(headlineOpts, "true"),
+		fmt.Sprintf(headlineOpts, "false"),
+		clampPageSize(limit, defaultSearchLimit, maxSearchLimit),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	results := []domain.ProjectSearchResult{}
+	for rows.Next() {
+		var (
+			p                      domain.Project
+			r                      domain.ProjectSearchResult
+			nameHeadline, headline string
+		)
+		err := rows.Scan(&p.ID, &p.Name, &p.Slug, &p.Description, &p.URL, &p.IconURL, &p.Status, &p.Order, pq.Array(&p.Tags), &p.Managed, &p.CreatedAt, &p.UpdatedAt, &r.Rank, &nameHeadline, &headline)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		if p.Tags == nil {
+			p.Tags = []string{}
+		}
+
+		r.Project = p
+		r.NameHighlight = markHighlights(nameHeadline)
+		r.Snippet = markHighlights(headline)
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return results, nil
+}
+
+// BasicSearcher scores active projects in memory for backends without
+// full-text search support
+type BasicSearcher struct {
+	projectService *ProjectService
+}
+
+// Search returns active projects whose words start with every term of
+// query, with name matches ranked above description matches
+func (s *BasicSearcher) Search(query string, limit int) ([]domain.ProjectSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []domain.ProjectSearchResult{}, nil
+	}
+
+	projects, err := s.projectService.GetActiveProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []domain.ProjectSearchResult{}
+	for _, p := range projects {
+		rank := 0.0
+		matched := true
+		for _, term := range terms {
+			switch {
+			case hasWordPrefix(p.Name, term):
+				rank += 1.0
+			case hasWordPrefix(strings.Join(p.Tags, " "), term):
+				rank += 0.4
+			case hasWordPrefix(p.Description, term):
+				rank += 0.2
+			default:
+				matched = false
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		results = append(results, domain.ProjectSearchResult{
+			Project:       p,
+			Rank:          rank,
+			NameHighlight: markHighlights(highlightWords(p.Name, terms)),
+			Snippet:       markHighlights(highlightWords(p.Description, terms)),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+
+	if limit = clampPageSize(limit, defaultSearchLimit, maxSearchLimit); len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// searchTerms splits a query into lower-case words made of letters and digits
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// hasWordPrefix reports whether any word of text starts with term
+func hasWordPrefix(text, term string) bool {
+	for _, word := range searchTerms(text) {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightWords wraps every word of text that starts with one of the terms
+// in highlight delimiters
+func highlightWords(text string, terms []string) string {
+	var b strings.Builder
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+
+		word := string(runes[i:j])
+		lower := strings.ToLower(word)
+		matched := false
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) {
+				matched = true
+				break
+			}
+		}
+
+		if matched {
+			b.WriteString(highlightStart + word + highlightStop)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+
+	return b.String()
+}
+
+// markHighlights HTML-escapes text and turns highlight delimiters into
+// <mark> tags
+func markHighlights(text string) string {
+	escaped := html.EscapeString(text)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(escaped)
+}
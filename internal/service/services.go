@@ -0,0 +1,51 @@
+package service
+
+import (
+	"database/sql"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/cache"
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/database"
+)
+
+// Services are the services of a server process. They are built once and
+// shared by the HTTP router and the background workers, so that every
+// write goes through the same cache and webhook outbox.
+type Services struct {
+	Cache       *cache.Loader // nil when caching is disabled
+	Audit       *AuditService
+	Auth        *AuthService
+	Webhooks    *WebhookService
+	Projects    *ProjectService
+	Portal      *PortalService
+	Retention   *RetentionService
+	Uptime      *UptimeService
+	Incidents   *IncidentService
+	Maintenance *MaintenanceService
+	Deployments *DeploymentService
+	Search      ProjectSearcher
+	Idempotency *IdempotencyService
+	Events      *EventBroker
+}
+
+// NewServices builds the services of a server process on db, caching
+// through loader unless it is nil
+func NewServices(cfg *config.Config, db *sql.DB, loader *cache.Loader) *Services {
+	s := &Services{Cache: loader}
+
+	s.Audit = NewAuditService(db, cfg.Audit.CheckpointSecret, cfg.Audit.CheckpointInterval)
+	s.Auth = NewAuthService(db, s.Audit, cfg.JWT.Secret, cfg.JWT.Expiry)
+	s.Webhooks = NewWebhookService(db, s.Audit, cfg.Webhooks)
+	s.Projects = NewProjectService(db, s.Audit, s.Webhooks, loader, cfg.Sync)
+	s.Portal = NewPortalService(db, s.Audit, s.Webhooks, loader)
+	s.Retention = NewRetentionService(db, cfg.Retention)
+	s.Uptime = NewUptimeService(db, s.Audit, cfg.Uptime)
+	s.Incidents = NewIncidentService(db, s.Audit)
+	s.Maintenance = NewMaintenanceService(db, s.Audit, s.Projects, cfg.Maintenance)
+	s.Deployments = NewDeploymentService(db, s.Projects, cfg.Deployments)
+	s.Search = NewProjectSearcher(cfg.Search.Backend, db, s.Projects)
+	s.Idempotency = NewIdempotencyService(db, cfg.Idempotency)
+	s.Events = NewEventBroker(db, database.DSN(cfg.Database), cfg.Events)
+
+	return s
+}
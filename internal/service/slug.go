@@ -0,0 +1,168 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ReservedSlugs cannot be used as project slugs because they collide with
+// routes or would be confusing in URLs
+var ReservedSlugs = []string{
+	"admin", "api", "static", "projects", "new", "edit", "search",
+	"login", "logout", "docs", "errors", "health", "status", "graphql",
+}
+
+// maxSlugLength matches projects.slug
+const maxSlugLength = 255
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// Slugify derives a URL slug from name: accents are stripped, runs of other
+// characters become single hyphens, and the result is lower case
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength-10 {
+		slug = strings.TrimRight(slug[:maxSlugLength-10], "-")
+	}
+
+	return slug
+}
+
+// validateSlug checks a client-supplied slug against the format and the
+// reserved list
+func validateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) || len(slug) > maxSlugLength {
+		return ErrSlugInvalid
+	}
+
+	if isReservedSlug(slug) {
+		return ErrSlugReserved
+	}
+
+	return nil
+}
+
+func isReservedSlug(slug string) bool {
+	for _, reserved := range ReservedSlugs {
+		if slug == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// generateSlug returns an unused slug derived from name, appending -2, -3,
+// ... when the plain form is taken, reserved or held by the slug history
+func generateSlug(q querier, name string) (string, error) {
+	base := Slugify(name)
+	if base == "" {
+		base = "project"
+	}
+
+	for n := 1; ; n++ {
+		candidate := base
+		if n > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		if isReservedSlug(candidate) {
+			continue
+		}
+
+		var used bool
+		err := q.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM projects WHERE slug = $1)
+				OR EXISTS (SELECT 1 FROM project_slug_history WHERE slug = $1)
+		`, candidate).Scan(&used)
+		if err != nil {
+			return "", fmt.Errorf("database error: %w", err)
+		}
+
+		if !used {
+			return candidate, nil
+		}
+	}
+}
+
+// claimSlug makes sure slug is not an old slug of another project, whose
+// redirect it would otherwise break. An old slug of projectID itself is
+// released so the project can move back to it.
+func claimSlug(tx *sql.Tx, slug string, projectID int) error {
+	var owner int
+	err := tx.QueryRow("SELECT project_id FROM project_slug_history WHERE slug = $1", slug).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if owner != projectID {
+		return ErrSlugTaken
+	}
+
+	if _, err := tx.Exec("DELETE FROM project_slug_history WHERE slug = $1", slug); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// recordSlugChange keeps oldSlug redirecting to projectID
+func recordSlugChange(tx *sql.Tx, projectID int, oldSlug string) error {
+	_, err := tx.Exec(`
+		INSERT INTO project_slug_history (project_id, slug) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET project_id = EXCLUDED.project_id, created_at = CURRENT_TIMESTAMP
+	`, projectID, oldSlug)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// CurrentSlug returns the slug a project formerly known as oldSlug has now,
+// or ErrProjectNotFound when oldSlug was never used
+func (s *ProjectService) CurrentSlug(oldSlug string) (string, error) {
+	var slug string
+	err := s.db.QueryRow(`
+		SELECT p.slug
+		FROM project_slug_history h
+		JOIN projects p ON p.id = h.project_id
+		WHERE h.slug = $1
+	`, oldSlug).Scan(&slug)
+
+	if err == sql.ErrNoRows {
+		return "", ErrProjectNotFound
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	return slug, nil
+}
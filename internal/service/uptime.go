@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/internal/uptime"
+)
+
+// Uptime check metrics, published through expvar
+var uptimeChecks = expvar.NewMap("uptime_checks")
+
+// defaultCheckLimit is the number of checks returned when no limit is given
+const defaultCheckLimit = 100
+
+// checkColumns is the column list scanned by scanCheck
+const checkColumns = `id, project_id, up, status_code, latency_ms, COALESCE(error, ''), checked_at`
+
+// monitorColumns is the column list scanned by getMonitor; $1 and $2 are
+// the default timeout and interval in seconds
+const monitorColumns = `project_id, enabled, method, expected_status, COALESCE(timeout_seconds, $1), COALESCE(interval_seconds, $2), next_check_at, updated_at`
+
+// UptimeService probes project URLs and keeps their check history
+type UptimeService struct {
+	db     *sql.DB
+	audit  *AuditService
+	cfg    config.UptimeConfig
+	client *http.Client
+}
+
+// NewUptimeService creates a new uptime service
+func NewUptimeService(db *sql.DB, audit *AuditService, cfg config.UptimeConfig) *UptimeService {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &UptimeService{db: db, audit: audit, cfg: cfg, client: &http.Client{}}
+}
+
+// Start checks due projects every poll interval until stop is closed
+func (s *UptimeService) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.CheckDue()
+			if err != nil {
+				log.Printf("Uptime checks failed: %v", err)
+			}
+			// Keep going while full batches are due
+			if err != nil || n < s.cfg.Concurrency {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// dueCheck is a claimed monitor together with its project's URL
+type dueCheck struct {
+	projectID int
+	target    uptime.Target
+}
+
+// CheckDue probes one batch of projects whose next check is due and
+// records the results. Monitors are claimed with SKIP LOCKED and moved to
+// their next interval first, so several replicas can run the prober
+// without checking a project twice.
+func (s *UptimeService) CheckDue() (int, error) {
+	// Projects created since the last round get a monitor with the defaults
+	if _, err := s.db.Exec("INSERT INTO project_monitors (project_id) SELECT id FROM projects ON CONFLICT (project_id) DO NOTHING"); err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		WITH due AS (
+			SELECT m.project_id FROM project_monitors m
+			JOIN projects p ON p.id = m.project_id
+			WHERE m.enabled AND p.status <> 'inactive' AND m.next_check_at <= CURRENT_TIMESTAMP
+			ORDER BY m.next_check_at
+			LIMIT $1
+			FOR UPDATE OF m SKIP LOCKED
+		), claimed AS (
+			UPDATE project_monitors m
+			SET next_check_at = CURRENT_TIMESTAMP + make_interval(secs => COALESCE(m.interval_seconds, $2))
+			FROM due WHERE m.project_id = due.project_id
+			RETURNING m.project_id, m.method, m.expected_status, COALESCE(m.timeout_seconds, $3)
+		)
+		SELECT c.*, p.url FROM claimed c JOIN projects p ON p.id = c.project_id
+	`, s.cfg.Concurrency, int(s.cfg.Interval.Seconds()), int(s.cfg.Timeout.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	var batch []dueCheck
+	for rows.Next() {
+		var (
+			d       dueCheck
+			timeout int
+		)
+		if err := rows.Scan(&d.projectID, &d.target.Method, &d.target.ExpectedStatus, &timeout, &d.target.URL); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan error: %w", err)
+		}
+		d.target.Timeout = time.Duration(timeout) * time.Second
+		batch = append(batch, d)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range batch {
+		wg.Add(1)
+		go func(d dueCheck) {
+			defer wg.Done()
+
+			result := uptime.Probe(context.Background(), s.client, d.target)
+			if err := s.record(d.projectID, result); err != nil {
+				log.Printf("Failed to record uptime check of project %d: %v", d.projectID, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	return len(batch), nil
+}
+
+// record stores the result of a check
+func (s *UptimeService) record(projectID int, result uptime.Result) error {
+	if result.Up {
+		uptimeChecks.Add(domain.HealthUp, 1)
+	} else {
+		uptimeChecks.Add(domain.HealthDown, 1)
+	}
+
+	var status sql.NullInt64
+	if result.StatusCode != 0 {
+		status = sql.NullInt64{Int64: int64(result.StatusCode), Valid: true}
+	}
+
+	_, err := s.db.Exec(
+		"INSERT INTO project_checks (project_id, up, status_code, latency_ms, error) VALUES ($1, $2, $3, $4, NULLIF($5, ''))",
+		projectID, result.Up, status, result.Latency.Milliseconds(), result.Error,
+	)
+	return err
+}
+
+// GetMonitor returns the uptime check configuration of a project
+func (s *UptimeService) GetMonitor(projectID int) (*domain.ProjectMonitor, error) {
+	if _, err := getProjectByID(s.db, projectID); err != nil {
+		return nil, err
+	}
+
+	// Projects created since the prober last ran have no monitor yet
+	if _, err := s.db.Exec("INSERT INTO project_monitors (project_id) VALUES ($1) ON CONFLICT (project_id) DO NOTHING", projectID); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return s.getMonitor(s.db, projectID)
+}
+
+// UpdateMonitor changes the uptime check configuration of a project. The
+// next check is brought forward so that the change takes effect at once.
+func (s *UptimeService) UpdateMonitor(actor domain.Actor, projectID int, req *domain.UpdateProjectMonitorRequest) (*domain.ProjectMonitor, error) {
+	var monitor *domain.ProjectMonitor
+
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		if _, err := getProjectByID(tx, projectID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("INSERT INTO project_monitors (project_id) VALUES ($1) ON CONFLICT (project_id) DO NOTHING", projectID); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		before, err := s.getMonitor(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE project_monitors SET
+				enabled = COALESCE($1, enabled),
+				method = COALESCE(NULLIF($2, ''), method),
+				expected_status = COALESCE($3, expected_status),
+				timeout_seconds = CASE WHEN $4::int IS NULL THEN timeout_seconds ELSE NULLIF($4, 0) END,
+				interval_seconds = CASE WHEN $5::int IS NULL THEN interval_seconds ELSE NULLIF($5, 0) END,
+				next_check_at = CURRENT_TIMESTAMP,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE project_id = $6
+		`, req.Enabled, req.Method, req.ExpectedStatus, req.TimeoutSeconds, req.IntervalSeconds, projectID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		monitor, err = s.getMonitor(tx, projectID)
+		if err != nil {
+			return err
+		}
+
+		// Scheduling is not configuration
+		details := diffDetails(before, monitor)
+		delete(details.Changes, "next_check_at")
+		delete(details.Changes, "updated_at")
+		if len(details.Changes) == 0 {
+			return nil
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionMonitorUpdate, domain.AuditResourceProject, projectID, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return monitor, nil
+}
+
+func (s *UptimeService) getMonitor(q querier, projectID int) (*domain.ProjectMonitor, error) {
+	var (
+		m    domain.ProjectMonitor
+		next sql.NullTime
+	)
+
+	err := q.QueryRow(
+		"SELECT "+monitorColumns+" FROM project_monitors WHERE project_id = $3",
+		int(s.cfg.Timeout.Seconds()), int(s.cfg.Interval.Seconds()), projectID,
+	).Scan(&m.ProjectID, &m.Enabled, &m.Method, &m.ExpectedStatus, &m.TimeoutSeconds, &m.IntervalSeconds, &next, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if next.Valid {
+		m.NextCheckAt = &next.Time
+	}
+
+	return &m, nil
+}
+
+// GetHealth returns the current uptime state of a project from its latest
+// check, with availability and latency over the last 24 hours
+func (s *UptimeService) GetHealth(projectID int) (*domain.ProjectHealth, error) {
+	if _, err := getProjectByID(s.db, projectID); err != nil {
+		return nil, err
+	}
+
+	health := &domain.ProjectHealth{ProjectID: projectID, Status: domain.HealthUnknown}
+
+	last, err := scanCheck(s.db.QueryRow("SELECT "+checkColumns+" FROM project_checks WHERE project_id = $1 ORDER BY checked_at DESC LIMIT 1", projectID))
+	if err == sql.ErrNoRows {
+		return health, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	health.LastCheck = last
+	health.Status = domain.HealthDown
+	if last.Up {
+		health.Status = domain.HealthUp
+	}
+
+	var (
+		up      int
+		latency sql.NullFloat64
+	)
+	err = s.db.QueryRow(
+		"SELECT COUNT(*), COUNT(*) FILTER (WHERE up), AVG(latency_ms) FROM project_checks WHERE project_id = $1 AND checked_at > CURRENT_TIMESTAMP - INTERVAL '24 hours'",
+		projectID,
+	).Scan(&health.Checks24h, &up, &latency)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if health.Checks24h > 0 {
+		ratio := float64(up) * 100 / float64(health.Checks24h)
+		health.Uptime24h = &ratio
+	}
+	if latency.Valid {
+		avg := int(latency.Float64)
+		health.AvgLatency24h = &avg
+	}
+
+	return health, nil
+}
+
+// ListChecks returns a project's checks in the filter's time range, newest
+// first
+func (s *UptimeService) ListChecks(projectID int, filter domain.ProjectCheckFilter) ([]domain.ProjectCheck, error) {
+	if _, err := getProjectByID(s.db, projectID); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultCheckLimit
+	}
+
+	qf := queryFilter{}
+	qf.add("project_id = ?", projectID)
+	if !filter.From.IsZero() {
+		qf.add("checked_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		qf.add("checked_at < ?", filter.To)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+checkColumns+" FROM project_checks"+qf.where()+" ORDER BY checked_at DESC LIMIT "+qf.next(filter.Limit),
+		qf.args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	checks := []domain.ProjectCheck{}
+	for rows.Next() {
+		check, err := scanCheck(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		checks = append(checks, *check)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return checks, nil
+}
+
+// scanCheck scans a row selected with checkColumns
+func scanCheck(row rowScanner) (*domain.ProjectCheck, error) {
+	var (
+		c      domain.ProjectCheck
+		status sql.NullInt64
+	)
+
+	if err := row.Scan(&c.ID, &c.ProjectID, &c.Up, &status, &c.LatencyMS, &c.Error, &c.CheckedAt); err != nil {
+		return nil, err
+	}
+
+	if status.Valid {
+		code := int(status.Int64)
+		c.StatusCode = &code
+	}
+
+	return &c, nil
+}
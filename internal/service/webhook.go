@@ -0,0 +1,255 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/config"
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/lib/pq"
+)
+
+// webhookColumns is the column list scanned by scanWebhook
+const webhookColumns = `id, url, COALESCE(description, ''), events, active, secret, created_at, updated_at`
+
+// WebhookService manages webhook subscriptions, records change events in
+// the outbox and delivers them
+type WebhookService struct {
+	db     *sql.DB
+	audit  *AuditService
+	cfg    config.WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db *sql.DB, audit *AuditService, cfg config.WebhookConfig) *WebhookService {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = 30 * time.Second
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = 6 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+
+	return &WebhookService{
+		db:     db,
+		audit:  audit,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Publish records an event in the outbox inside the given transaction and
+// queues a delivery for every active webhook subscribed to it, so that
+// events are sent if and only if the change they describe is committed.
+// Listeners on EventChannel are notified of the event ID on commit.
+func (s *WebhookService) Publish(tx *sql.Tx, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("event encoding failed: %w", err)
+	}
+
+	var eventID int
+	err = tx.QueryRow(
+		"INSERT INTO webhook_events (type, payload) VALUES ($1, $2) RETURNING id",
+		eventType, string(payload),
+	).Scan(&eventID)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at)
+		SELECT id, $1, CURRENT_TIMESTAMP FROM webhooks
+		WHERE active AND ($2 = ANY(events) OR $3 = ANY(events))
+	`, eventID, eventType, domain.EventAll)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if _, err := tx.Exec("SELECT pg_notify($1, $2)", EventChannel, strconv.Itoa(eventID)); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhooks retrieves all webhooks
+func (s *WebhookService) ListWebhooks() ([]domain.Webhook, error) {
+	rows, err := s.db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id ASC")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []domain.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		webhooks = append(webhooks, *w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (s *WebhookService) GetWebhook(id int) (*domain.Webhook, error) {
+	return getWebhook(s.db, id)
+}
+
+// CreateWebhook creates a webhook and returns its signing secret, which is
+// generated when the request does not set one
+func (s *WebhookService) CreateWebhook(actor domain.Actor, req *domain.CreateWebhookRequest) (*domain.WebhookSecret, error) {
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	var id int
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			"INSERT INTO webhooks (url, description, events, secret, active) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			req.URL, req.Description, pq.Array(req.Events), secret, active,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getWebhook(tx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionWebhookCreate, domain.AuditResourceWebhook, id, diffDetails(nil, after))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WebhookSecret{ID: id, Secret: secret}, nil
+}
+
+// UpdateWebhook updates a webhook; a new secret takes effect for the next
+// delivery attempt
+func (s *WebhookService) UpdateWebhook(actor domain.Actor, id int, req *domain.UpdateWebhookRequest) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getWebhook(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var events interface{}
+		if len(req.Events) > 0 {
+			events = pq.Array(req.Events)
+		}
+
+		_, err = tx.Exec(
+			"UPDATE webhooks SET url = COALESCE(NULLIF($1, ''), url), description = COALESCE(NULLIF($2, ''), description), events = COALESCE($3, events), secret = COALESCE(NULLIF($4, ''), secret), active = COALESCE($5, active), updated_at = CURRENT_TIMESTAMP WHERE id = $6",
+			req.URL, req.Description, events, req.Secret, req.Active, id,
+		)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		after, err := getWebhook(tx, id)
+		if err != nil {
+			return err
+		}
+
+		details := diffDetails(before, after)
+		if req.Secret != "" && req.Secret != before.Secret {
+			// The secret itself is never written to the audit log
+			details.Reason = "secret rotated"
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionWebhookUpdate, domain.AuditResourceWebhook, id, details)
+	})
+}
+
+// DeleteWebhook deletes a webhook together with its deliveries
+func (s *WebhookService) DeleteWebhook(actor domain.Actor, id int) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		before, err := getWebhook(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM webhooks WHERE id = $1", id); err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionWebhookDelete, domain.AuditResourceWebhook, id, diffDetails(before, nil))
+	})
+}
+
+// getWebhook retrieves a webhook by ID using the given querier
+func getWebhook(q querier, id int) (*domain.Webhook, error) {
+	w, err := scanWebhook(q.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return w, nil
+}
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var w domain.Webhook
+	err := row.Scan(&w.ID, &w.URL, &w.Description, pq.Array(&w.Events), &w.Active, &w.Secret, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+
+	return &w, nil
+}
+
+// generateWebhookSecret returns a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("secret generation failed: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
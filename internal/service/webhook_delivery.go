@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kanyaarss/kanyaars-portal/internal/domain"
+	"github.com/kanyaarss/kanyaars-portal/pkg/webhook"
+)
+
+// Webhook delivery metrics, published through expvar
+var webhookDeliveries = expvar.NewMap("webhook_deliveries")
+
+// maxResponseBody limits how much of a receiver's response is logged
+const maxResponseBody = 2048
+
+// deliveryColumns is the column list scanned by scanDelivery
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.response_status, COALESCE(d.response_body, ''), COALESCE(d.error, ''), d.next_attempt_at, d.delivered_at, d.created_at, d.updated_at`
+
+// pendingDelivery is a claimed delivery together with what is needed to
+// send it
+type pendingDelivery struct {
+	id       int
+	attempts int
+	url      string
+	secret   string
+	active   bool
+	event    domain.Event
+}
+
+// Start delivers due webhooks every poll interval until stop is closed
+func (s *WebhookService) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.DeliverDue()
+			if err != nil {
+				log.Printf("Webhook delivery failed: %v", err)
+			}
+			// Keep draining while full batches are found
+			if err != nil || n < s.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns its size.
+// Deliveries are claimed with SKIP LOCKED and leased for twice the request
+// timeout, so several replicas can run the worker without sending an event
+// twice while it is in flight.
+func (s *WebhookService) DeliverDue() (int, error) {
+	rows, err := s.db.Query(`
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due WHERE d.id = due.id
+			RETURNING d.id, d.webhook_id, d.event_id, d.attempts
+		)
+		SELECT c.id, c.attempts, w.url, w.secret, w.active, e.id, e.type, e.payload, e.created_at
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		JOIN webhook_events e ON e.id = c.event_id
+	`, s.cfg.BatchSize, (2 * s.cfg.Timeout).Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	var batch []pendingDelivery
+	for rows.Next() {
+		var (
+			d       pendingDelivery
+			payload string
+		)
+		if err := rows.Scan(&d.id, &d.attempts, &d.url, &d.secret, &d.active, &d.event.ID, &d.event.Type, &payload, &d.event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan error: %w", err)
+		}
+		d.event.Data = json.RawMessage(payload)
+		batch = append(batch, d)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	for _, d := range batch {
+		if err := s.attempt(d); err != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", d.id, err)
+		}
+	}
+
+	return len(batch), nil
+}
+
+// attempt sends a single delivery and records the outcome, scheduling a
+// retry with exponential backoff until MaxAttempts is reached
+func (s *WebhookService) attempt(d pendingDelivery) error {
+	if !d.active {
+		_, err := s.db.Exec(
+			"UPDATE webhook_deliveries SET status = $1, error = $2, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+			domain.DeliveryFailed, "webhook is inactive", d.id,
+		)
+		return err
+	}
+
+	status, body, sendErr := s.send(d)
+	attempts := d.attempts + 1
+
+	var responseStatus sql.NullInt64
+	if status != 0 {
+		responseStatus = sql.NullInt64{Int64: int64(status), Valid: true}
+	}
+
+	if sendErr == nil {
+		webhookDeliveries.Add(domain.DeliverySucceeded, 1)
+		_, err := s.db.Exec(
+			"UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3, response_body = $4, error = NULL, next_attempt_at = NULL, delivered_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $5",
+			domain.DeliverySucceeded, attempts, responseStatus, body, d.id,
+		)
+		return err
+	}
+
+	if attempts >= s.cfg.MaxAttempts {
+		webhookDeliveries.Add(domain.DeliveryFailed, 1)
+		_, err := s.db.Exec(
+			"UPDATE webhook_deliveries SET status = $1, attempts = $2, response_status = $3, response_body = $4, error = $5, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $6",
+			domain.DeliveryFailed, attempts, responseStatus, body, sendErr.Error(), d.id,
+		)
+		return err
+	}
+
+	webhookDeliveries.Add("retried", 1)
+	_, err := s.db.Exec(
+		"UPDATE webhook_deliveries SET attempts = $1, response_status = $2, response_body = $3, error = $4, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $5), updated_at = CURRENT_TIMESTAMP WHERE id = $6",
+		attempts, responseStatus, body, sendErr.Error(), s.backoff(attempts).Seconds(), d.id,
+	)
+	return err
+}
+
+// send POSTs the signed event and returns the response status and body.
+// Any non-2xx status is an error.
+func (s *WebhookService) send(d pendingDelivery) (int, string, error) {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		return 0, "", fmt.Errorf("event encoding failed: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kanyaars-Portal-Webhook/1.0")
+	req.Header.Set("X-Portal-Event", d.event.Type)
+	req.Header.Set("X-Portal-Delivery", strconv.Itoa(d.id))
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(d.secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, string(respBody), nil
+}
+
+// backoff returns the delay before the next attempt: BackoffBase doubled
+// for every failed attempt, capped at BackoffMax
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.cfg.BackoffBase
+	for i := 1; i < attempts && delay < s.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > s.cfg.BackoffMax {
+		delay = s.cfg.BackoffMax
+	}
+
+	return delay
+}
+
+// ListDeliveries returns the most recent deliveries of a webhook
+func (s *WebhookService) ListDeliveries(webhookID, limit int) ([]domain.WebhookDelivery, error) {
+	if _, err := getWebhook(s.db, webhookID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id WHERE d.webhook_id = $1 ORDER BY d.id DESC LIMIT $2",
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues the event of an earlier delivery again as a new
+// delivery, leaving the original in the log
+func (s *WebhookService) Redeliver(actor domain.Actor, webhookID, deliveryID int) (*domain.WebhookDelivery, error) {
+	var d *domain.WebhookDelivery
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		var id int
+		err := tx.QueryRow(`
+			INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at)
+			SELECT webhook_id, event_id, CURRENT_TIMESTAMP FROM webhook_deliveries
+			WHERE id = $1 AND webhook_id = $2
+			RETURNING id
+		`, deliveryID, webhookID).Scan(&id)
+
+		if err == sql.ErrNoRows {
+			return ErrDeliveryNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		d, err = scanDelivery(tx.QueryRow("SELECT "+deliveryColumns+" FROM webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id WHERE d.id = $1", id))
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		return s.audit.Record(tx, actor, domain.AuditActionWebhookRedeliver, domain.AuditResourceWebhook, webhookID, diffDetails(nil, d))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// scanDelivery scans a row selected with deliveryColumns
+func scanDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	var (
+		d              domain.WebhookDelivery
+		responseStatus sql.NullInt64
+		nextAttemptAt  sql.NullTime
+		deliveredAt    sql.NullTime
+	)
+
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &responseStatus, &d.ResponseBody, &d.Error, &nextAttemptAt, &deliveredAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if responseStatus.Valid {
+		status := int(responseStatus.Int64)
+		d.ResponseStatus = &status
+	}
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+
+	return &d, nil
+}
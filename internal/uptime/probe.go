@@ -0,0 +1,92 @@
+// Package uptime checks whether a project's URL responds as expected.
+package uptime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// userAgent identifies probe requests in the projects' access logs
+const userAgent = "Kanyaars-Portal-Uptime/1.0"
+
+// maxBody is how much of a response body is read before the connection is
+// released
+const maxBody = 64 << 10
+
+// Target describes one check of a URL
+type Target struct {
+	URL    string
+	Method string // GET or HEAD
+	// ExpectedStatus is the status the URL must answer with; 0 accepts any
+	// 2xx. Redirects are followed unless a 3xx status is expected.
+	ExpectedStatus int
+	Timeout        time.Duration
+}
+
+// Result is the outcome of a check. StatusCode is 0 when no response was
+// received.
+type Result struct {
+	Up         bool
+	StatusCode int
+	Latency    time.Duration
+	Error      string
+}
+
+// Probe requests t's URL with client and reports whether it responded with
+// the expected status within the timeout
+func Probe(ctx context.Context, client *http.Client, t Target) Result {
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	method := t.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.URL, nil)
+	if err != nil {
+		return Result{Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if t.ExpectedStatus >= 300 && t.ExpectedStatus < 400 {
+		// Report the redirect itself rather than where it leads
+		noRedirect := *client
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		client = &noRedirect
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return Result{Latency: latency, Error: fmt.Sprintf("no response within %s", t.Timeout)}
+		}
+		return Result{Latency: latency, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBody))
+
+	result := Result{StatusCode: resp.StatusCode, Latency: latency}
+
+	switch {
+	case t.ExpectedStatus != 0 && resp.StatusCode != t.ExpectedStatus:
+		result.Error = fmt.Sprintf("expected status %d, got %d", t.ExpectedStatus, resp.StatusCode)
+	case t.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299):
+		result.Error = fmt.Sprintf("expected a 2xx status, got %d", resp.StatusCode)
+	default:
+		result.Up = true
+	}
+
+	return result
+}
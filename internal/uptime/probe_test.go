@@ -0,0 +1,89 @@
+package uptime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/head-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		target    Target
+		up        bool
+		status    int
+		errSubstr string
+	}{
+		{name: "2xx", target: Target{URL: server.URL + "/ok"}, up: true, status: 200},
+		{name: "5xx", target: Target{URL: server.URL + "/down"}, status: 503, errSubstr: "expected a 2xx status, got 503"},
+		{name: "expected status", target: Target{URL: server.URL + "/down", ExpectedStatus: 503}, up: true, status: 503},
+		{name: "redirect followed", target: Target{URL: server.URL + "/moved"}, up: true, status: 200},
+		{name: "redirect expected", target: Target{URL: server.URL + "/moved", ExpectedStatus: 301}, up: true, status: 301},
+		{name: "wrong status", target: Target{URL: server.URL + "/ok", ExpectedStatus: 204}, status: 200, errSubstr: "expected status 204, got 200"},
+		{name: "head", target: Target{URL: server.URL + "/head-only", Method: http.MethodHead}, up: true, status: 200},
+		{name: "get where head is required", target: Target{URL: server.URL + "/head-only"}, status: 405, errSubstr: "got 405"},
+		{name: "timeout", target: Target{URL: server.URL + "/slow", Timeout: 50 * time.Millisecond}, errSubstr: "no response within 50ms"},
+		{name: "invalid url", target: Target{URL: "://nope"}, errSubstr: "missing protocol scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Probe(context.Background(), server.Client(), tt.target)
+
+			if result.Up != tt.up {
+				t.Errorf("Up = %v, want %v (error %q)", result.Up, tt.up, result.Error)
+			}
+			if result.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", result.StatusCode, tt.status)
+			}
+			if tt.errSubstr == "" && result.Error != "" {
+				t.Errorf("Error = %q, want none", result.Error)
+			}
+			if !strings.Contains(result.Error, tt.errSubstr) {
+				t.Errorf("Error = %q, want it to contain %q", result.Error, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestProbeConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	result := Probe(context.Background(), http.DefaultClient, Target{URL: url, Timeout: time.Second})
+	if result.Up || result.StatusCode != 0 || result.Error == "" {
+		t.Errorf("Probe of a closed server = %+v, want down without a status", result)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrTokenExpired is returned by ValidateToken for tokens past their expiry
+var ErrTokenExpired = jwt.ErrTokenExpired
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID int    `json:"user_id"`
@@ -10,6 +10,8 @@ document.addEventListener('DOMContentLoaded', function() {
     // Load dashboard data if on dashboard
     if (window.location.pathname === '/admin/') {
         loadDashboardData();
+        loadRecentActivity();
+        watchProjectEvents();
     }
 
     // Setup login form
@@ -43,7 +45,7 @@ async function handleLogin(e) {
             localStorage.setItem('user', JSON.stringify(data.data.user));
             window.location.href = '/admin/';
         } else {
-            errorMessage.textContent = data.error || 'Login failed';
+            errorMessage.textContent = data.title || 'Login failed';
             errorMessage.style.display = 'block';
         }
     } catch (error) {
@@ -56,7 +58,7 @@ async function handleLogin(e) {
 // Load dashboard data
 async function loadDashboardData() {
     try {
-        const response = await fetch('/api/v1/projects', {
+        const response = await fetch('/admin/projects?page_size=100&sort=-updated_at', {
             headers: {
                 'Authorization': `Bearer ${localStorage.getItem('token')}`
             }
@@ -68,7 +70,7 @@ async function loadDashboardData() {
             const projects = data.data.data;
             
             // Update stats
-            document.getElementById('total-projects').textContent = projects.length;
+            document.getElementById('total-projects').textContent = data.data.total;
             const activeCount = projects.filter(p => p.status === 'active').length;
             const inactiveCount = projects.filter(p => p.status === 'inactive').length;
             
@@ -84,10 +86,13 @@ async function loadDashboardData() {
                 row.innerHTML = `
                     <td>${project.name}</td>
                     <td>${project.slug}</td>
-                    <td><span class="badge">${project.status}</span></td>
+                    <td>
+                        <span class="badge">${project.status}</span>
+                        ${project.managed ? '<span class="badge badge-managed" title="Defined in the sync manifest; edits are overwritten by the next sync">managed</span>' : ''}
+                    </td>
                     <td>
                         <a href="/admin/projects/${project.id}" class="btn btn-secondary">Edit</a>
-                        <button onclick="deleteProject(${project.id})" class="btn btn-danger">Delete</button>
+                        <button onclick="deleteProject(${project.id}, ${project.managed})" class="btn btn-danger">Delete</button>
                     </td>
                 `;
                 tableBody.appendChild(row);
@@ -98,14 +103,74 @@ async function loadDashboardData() {
     }
 }
 
+// Refresh the dashboard when projects change, from any admin session
+function watchProjectEvents() {
+    if (!window.EventSource) return;
+
+    const source = new EventSource('/api/v1/events');
+    let refreshTimer;
+
+    const refresh = function() {
+        clearTimeout(refreshTimer);
+        refreshTimer = setTimeout(() => {
+            loadDashboardData();
+            loadRecentActivity();
+        }, 500);
+    };
+
+    ['project.created', 'project.updated', 'project.status_changed', 'project.deleted', 'reset'].forEach(type => {
+        source.addEventListener(type, refresh);
+    });
+}
+
+// Load recent audit log entries
+async function loadRecentActivity() {
+    try {
+        const response = await fetch('/admin/audit-logs?page_size=10', {
+            headers: {
+                'Authorization': `Bearer ${localStorage.getItem('token')}`
+            }
+        });
+
+        const data = await response.json();
+
+        if (data.success && data.data && data.data.data) {
+            const tableBody = document.getElementById('activity-table');
+            tableBody.innerHTML = '';
+
+            data.data.data.forEach(entry => {
+                const row = document.createElement('tr');
+                const resource = entry.resource_id ? `${entry.resource} #${entry.resource_id}` : (entry.resource || '-');
+                [
+                    new Date(entry.created_at).toLocaleString(),
+                    entry.user_id ? `#${entry.user_id}` : '-',
+                    entry.action,
+                    resource,
+                    entry.ip_address || '-'
+                ].forEach(value => {
+                    const cell = document.createElement('td');
+                    cell.textContent = value;
+                    row.appendChild(cell);
+                });
+                tableBody.appendChild(row);
+            });
+        }
+    } catch (error) {
+        console.error('Failed to load recent activity:', error);
+    }
+}
+
 // Delete project
-async function deleteProject(id) {
-    if (!confirm('Are you sure you want to delete this project?')) {
+async function deleteProject(id, managed = false) {
+    const question = managed
+        ? 'This project is defined in the sync manifest and will be recreated by the next sync unless it is removed there. Delete it anyway?'
+        : 'Are you sure you want to delete this project?';
+    if (!confirm(question)) {
         return;
     }
 
     try {
-        const response = await fetch(`/api/v1/admin/projects/${id}`, {
+        const response = await fetch(`/admin/projects/${id}`, {
             method: 'DELETE',
             headers: {
                 'Authorization': `Bearer ${localStorage.getItem('token')}`
@@ -118,7 +183,7 @@ async function deleteProject(id) {
             loadDashboardData();
             showNotification('Project deleted successfully', 'success');
         } else {
-            showNotification('Failed to delete project', 'error');
+            showNotification(data.detail || data.title || 'Failed to delete project', 'error');
         }
     } catch (error) {
         console.error('Delete error:', error);
@@ -0,0 +1,115 @@
+// API documentation viewer
+// Renders the OpenAPI document served at /api/v1/openapi.json without any
+// external dependencies
+
+document.addEventListener('DOMContentLoaded', function() {
+    const container = document.getElementById('api-docs-operations');
+    if (!container) return;
+
+    fetch(container.dataset.specUrl)
+        .then(response => response.json())
+        .then(spec => renderDocs(spec, container))
+        .catch(error => {
+            container.textContent = 'Failed to load API document: ' + error.message;
+        });
+});
+
+// ==================== RENDERING ====================
+function renderDocs(spec, container) {
+    document.getElementById('api-docs-title').textContent = spec.info.title + ' ' + spec.info.version;
+    document.getElementById('api-docs-description').textContent = spec.info.description || '';
+
+    // Group operations by their first tag
+    const groups = {};
+    Object.keys(spec.paths).sort().forEach(path => {
+        Object.entries(spec.paths[path]).forEach(([method, op]) => {
+            const tag = (op.tags && op.tags[0]) || 'Other';
+            (groups[tag] = groups[tag] || []).push({ path, method, op });
+        });
+    });
+
+    container.innerHTML = '';
+    spec.tags.forEach(({ name }) => {
+        if (!groups[name]) return;
+
+        const section = document.createElement('section');
+        section.className = 'api-docs-tag';
+        section.appendChild(element('h2', name));
+        groups[name].forEach(entry => section.appendChild(renderOperation(spec, entry)));
+        container.appendChild(section);
+    });
+}
+
+function renderOperation(spec, { path, method, op }) {
+    const details = document.createElement('details');
+    details.className = 'api-docs-operation';
+
+    const summary = document.createElement('summary');
+    summary.appendChild(element('span', method.toUpperCase(), 'api-docs-method api-docs-method-' + method));
+    summary.appendChild(element('code', path));
+    summary.appendChild(element('span', op.summary, 'api-docs-summary'));
+    if (op.security) {
+        summary.appendChild(element('span', 'auth', 'api-docs-auth'));
+    }
+    details.appendChild(summary);
+
+    if (op.parameters) {
+        details.appendChild(element('h4', 'Parameters'));
+        const list = document.createElement('ul');
+        op.parameters.forEach(p => {
+            const text = p.name + ' (' + p.in + ', ' + describeSchema(p.schema) + ')' + (p.required ? ' required' : '');
+            list.appendChild(element('li', p.description ? text + ' - ' + p.description : text));
+        });
+        details.appendChild(list);
+    }
+
+    if (op.requestBody) {
+        details.appendChild(element('h4', 'Request body'));
+        details.appendChild(renderSchema(spec, op.requestBody.content['application/json'].schema));
+    }
+
+    details.appendChild(element('h4', 'Responses'));
+    Object.entries(op.responses).forEach(([status, response]) => {
+        details.appendChild(element('p', status + ' ' + response.description, 'api-docs-status'));
+        const json = response.content && response.content['application/json'];
+        if (json && status < 400) {
+            details.appendChild(renderSchema(spec, json.schema));
+        }
+    });
+
+    return details;
+}
+
+// renderSchema prints a schema with its component references resolved
+function renderSchema(spec, schema) {
+    return element('pre', JSON.stringify(resolve(spec, schema, 0), null, 2));
+}
+
+function resolve(spec, schema, depth) {
+    if (!schema || typeof schema !== 'object' || depth > 6) return schema;
+
+    if (schema.$ref) {
+        const name = schema.$ref.split('/').pop();
+        return resolve(spec, spec.components.schemas[name], depth + 1);
+    }
+
+    const resolved = Array.isArray(schema) ? [] : {};
+    Object.entries(schema).forEach(([key, value]) => {
+        resolved[key] = resolve(spec, value, depth + 1);
+    });
+    return resolved;
+}
+
+function describeSchema(schema) {
+    if (!schema) return 'any';
+    if (schema.type === 'array') return describeSchema(schema.items) + '[]';
+    if (schema.enum) return schema.enum.join(' | ');
+    return schema.format ? schema.type + ':' + schema.format : String(schema.type);
+}
+
+function element(tag, text, className) {
+    const el = document.createElement(tag);
+    el.textContent = text;
+    if (className) el.className = className;
+    return el;
+}
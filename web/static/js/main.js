@@ -12,6 +12,8 @@ document.addEventListener('DOMContentLoaded', function() {
     initRoomDimensions();
     initKeyboardNavigation();
     initMobileTitlePosition();
+    initProjectSearch();
+    initProjectEvents();
 });
 
 // ==================== LOADER/SPLASH SCREEN ====================
@@ -524,6 +526,172 @@ function initMobileTitlePosition() {
     });
 }
 
+// ==================== PROJECT SEARCH ====================
+// Live search on the projects page backed by /api/v1/search
+function initProjectSearch() {
+    const input = document.getElementById('project-search-input');
+    const grid = document.getElementById('projects-grid');
+    const empty = document.getElementById('project-search-empty');
+    if (!input || !grid) return;
+
+    // Cards as rendered before searching; live updates pause while a
+    // search is shown
+    let originalCards = null;
+    let debounceTimer;
+    let requestId = 0;
+
+    input.addEventListener('input', function() {
+        clearTimeout(debounceTimer);
+        debounceTimer = setTimeout(async () => {
+            const query = input.value.trim();
+            const current = ++requestId;
+
+            if (!query) {
+                if (originalCards !== null) {
+                    grid.innerHTML = originalCards;
+                    originalCards = null;
+                    delete grid.dataset.searching;
+                }
+                empty.hidden = true;
+                return;
+            }
+
+            if (originalCards === null) {
+                originalCards = grid.innerHTML;
+                grid.dataset.searching = 'true';
+            }
+
+            try {
+                const response = await fetch(`/api/v1/search?q=${encodeURIComponent(query)}&limit=50`);
+                const data = await response.json();
+                if (current !== requestId) return;
+
+                const results = (data.success && data.data && data.data.data) || [];
+                renderSearchResults(grid, results);
+                empty.hidden = results.length > 0;
+            } catch (error) {
+                console.error('Project search failed:', error);
+            }
+        }, 250);
+    });
+}
+
+// Render search hits as project cards. Highlights are HTML-escaped by the
+// server, everything else is inserted as text.
+function renderSearchResults(grid, results) {
+    grid.innerHTML = '';
+
+    results.forEach(result => {
+        const project = result.project;
+        const card = document.createElement('div');
+        card.className = 'project-card';
+
+        if (project.icon_url) {
+            const icon = document.createElement('img');
+            icon.className = 'project-icon';
+            icon.src = project.icon_url;
+            icon.alt = project.name;
+            card.appendChild(icon);
+        }
+
+        const title = document.createElement('h3');
+        title.innerHTML = result.name_highlight;
+        card.appendChild(title);
+
+        const snippet = document.createElement('p');
+        snippet.innerHTML = result.snippet;
+        card.appendChild(snippet);
+
+        const link = document.createElement('a');
+        link.className = 'btn btn-secondary';
+        link.href = `/projects/${encodeURIComponent(project.slug)}`;
+        link.textContent = 'View Project';
+        card.appendChild(link);
+
+        grid.appendChild(card);
+    });
+}
+
+// ==================== LIVE PROJECT UPDATES ====================
+// Keep the projects grid current with the /api/v1/events stream
+function initProjectEvents() {
+    const grid = document.getElementById('projects-grid');
+    if (!grid || !window.EventSource) return;
+
+    const source = new EventSource('/api/v1/events');
+
+    ['project.created', 'project.updated', 'project.status_changed', 'project.deleted'].forEach(type => {
+        source.addEventListener(type, function(e) {
+            if (grid.dataset.searching) return;
+
+            const event = JSON.parse(e.data);
+            const project = event.data.project;
+            const existing = grid.querySelector(`[data-project-id="${project.id}"]`);
+
+            if (type === 'project.deleted' || (project.status !== 'active' && project.status !== 'maintenance')) {
+                if (existing) existing.remove();
+                return;
+            }
+
+            const card = renderProjectCard(project);
+            // Events do not carry maintenance windows, so keep a scheduled one
+            const scheduled = existing && existing.querySelector('.maintenance-banner[data-state="scheduled"]');
+            if (scheduled && project.status === 'active') {
+                card.querySelector('h3').after(scheduled);
+            }
+            if (existing) {
+                existing.replaceWith(card);
+            } else {
+                grid.appendChild(card);
+            }
+        });
+    });
+
+    // Events were missed while disconnected, so start over
+    source.addEventListener('reset', function() {
+        window.location.reload();
+    });
+}
+
+// Build a project card like the server-rendered ones
+function renderProjectCard(project) {
+    const card = document.createElement('div');
+    card.className = 'project-card';
+    card.dataset.projectId = project.id;
+
+    if (project.icon_url) {
+        const icon = document.createElement('img');
+        icon.className = 'project-icon';
+        icon.src = project.icon_url;
+        icon.alt = project.name;
+        card.appendChild(icon);
+    }
+
+    const title = document.createElement('h3');
+    title.textContent = project.name;
+    card.appendChild(title);
+
+    if (project.status === 'maintenance') {
+        const banner = document.createElement('p');
+        banner.className = 'maintenance-banner maintenance-banner-in_progress';
+        banner.dataset.state = 'in_progress';
+        banner.textContent = 'Under maintenance';
+        card.appendChild(banner);
+    }
+
+    const description = document.createElement('p');
+    description.textContent = project.description;
+    card.appendChild(description);
+
+    const link = document.createElement('a');
+    link.className = 'btn btn-secondary';
+    link.href = `/projects/${encodeURIComponent(project.slug)}`;
+    link.textContent = 'View Project';
+    card.appendChild(link);
+
+    return card;
+}
+
 // ==================== UTILITY FUNCTIONS ====================
 
 // Utility function to make API calls